@@ -1,45 +1,318 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"log"
+	"parking-lot/internal/gate"
 	"parking-lot/internal/models"
 	"parking-lot/internal/parking"
+	"parking-lot/internal/payment"
+	"parking-lot/internal/reservation"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 func main() {
-	// initialize 3 levels, 5 spots of each type per level
-	lot := parking.GetParkingLot(3, 5)
+	dir := flag.String("layouts", "layouts", "directory of lot layout files")
+	flag.Parse()
+
+	// every layout file becomes an independently managed lot
+	lots := parking.NewRegistry()
+	files, _ := filepath.Glob(filepath.Join(*dir, "*"))
+	for _, f := range files {
+		if _, err := lots.Load(f); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if _, err := lots.Open(parking.UniformLayout("overflow", 2, 1)); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Lots:", lots.Names())
+
+	downtown, ok := lots.Lot("downtown")
+	if !ok {
+		log.Fatalf("no downtown layout in %s", *dir)
+	}
+	airport, _ := lots.Lot("airport")
+
+	demoLayouts(downtown, airport)
+	demoAllocation()
+	demoReservations()
+	demoCheckout(downtown)
+	demoGates()
+}
+
+// demoLayouts shows spot sizes and lots working independently
+func demoLayouts(downtown, airport *parking.ParkingLot) {
+	fmt.Println("\n=== Layouts ===")
+	park(downtown, &models.Car{License: "EV-001", Electric: true})
+	park(downtown, &models.Car{License: "BADGE-001", Permit: true})
+	park(downtown, &models.Truck{License: "TRUCK-001"})
+	park(downtown, &models.Truck{License: "TRUCK-002"}) // only one large spot downtown
+	t := park(airport, &models.Truck{License: "TRUCK-002"})
+	fmt.Println("Downtown:", downtown.GetAvailability())
+	fmt.Println("Airport: ", airport.GetAvailability())
+
+	// a ticket only works in the lot that issued it
+	if _, err := downtown.UnparkVehicle(t.ID, t.Vehicle.LicensePlate(), nil); err != nil {
+		fmt.Println("Downtown exit with airport ticket refused:", err)
+	}
+}
+
+// demoAllocation parks the same arrivals under each allocation strategy
+func demoAllocation() {
+	fmt.Println("\n=== Allocation strategies ===")
+	// the entrance is on level 2, listed first; level 1 is a basement
+	layout := parking.Layout{Name: "demo", Levels: []parking.LevelLayout{
+		{Number: 2, Spots: map[models.SpotSize]int{models.RegularSpot: 6}, Elevator: 2},
+		{Number: 1, Spots: map[models.SpotSize]int{models.CompactSpot: 1, models.RegularSpot: 6}, Elevator: 5},
+		{Number: 3, Spots: map[models.SpotSize]int{models.RegularSpot: 6, models.LargeSpot: 1}, Elevator: 0},
+	}}
+	arrivals := []models.Vehicle{
+		&models.Car{License: "A"}, &models.Car{License: "B"}, &models.Car{License: "C"},
+		&models.Motorcycle{License: "M1"}, &models.Motorcycle{License: "M2"},
+	}
+	for _, s := range []parking.Strategy{
+		parking.LowestLevelFirst,
+		parking.NearestEntrance,
+		parking.NearestElevator,
+		parking.SpreadLoad,
+		parking.AllowUpsizing(parking.SpreadLoad),
+	} {
+		lot, err := parking.NewParkingLot(layout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		lot.SetStrategy(s)
+		fmt.Printf("%-24s", s.Name()+":")
+		for _, v := range arrivals {
+			if t, err := lot.ParkVehicle(v); err != nil {
+				fmt.Printf(" %s→full", v.LicensePlate())
+			} else {
+				fmt.Printf(" %s→%s", v.LicensePlate(), t.SpotID)
+			}
+		}
+		fmt.Println()
+	}
+}
+
+// demoReservations books a two-spot lot against walk-in traffic
+func demoReservations() {
+	fmt.Println("\n=== Reservations ===")
+	lot, err := parking.NewParkingLot(parking.Layout{Name: "clinic", Levels: []parking.LevelLayout{
+		{Number: 1, Spots: map[models.SpotSize]int{models.RegularSpot: 2}},
+	}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	now := time.Date(2025, time.June, 9, 8, 0, 0, 0, time.Local) // Monday
+	lot.SetClock(func() time.Time { return now })
+	at := func(h, m int) time.Time { return time.Date(2025, time.June, 9, h, m, 0, 0, time.Local) }
+	bookings := reservation.NewService(lot, reservation.DefaultOptions())
+
+	reserve := func(license string, start, end time.Time) reservation.Reservation {
+		r, err := bookings.Reserve(license, models.RegularSpot, start, end)
+		if err != nil {
+			fmt.Printf("Reserve for %s refused: %v\n", license, err)
+		} else {
+			fmt.Printf("Reserved %s for %s %s–%s\n", r.ID, license, r.Start.Format("15:04"), r.End.Format("15:04"))
+		}
+		return r
+	}
+	alice := reserve("ALICE", at(9, 0), at(11, 0))
+	bob := reserve("BOB", at(9, 0), at(12, 0))
+	reserve("CAROL", at(10, 0), at(11, 0)) // both spots promised
+	dave := reserve("DAVE", at(11, 0), at(13, 0))
+
+	park(lot, &models.Car{License: "WALK-1"}) // spots are held from booking
+
+	arrive := func(r reservation.Reservation, v models.Vehicle) {
+		if t, err := bookings.Arrive(r.ID, v); err != nil {
+			fmt.Printf("%s arrival on %s refused: %v\n", v.LicensePlate(), r.ID, err)
+		} else {
+			fmt.Printf("%s arrived on %s @ %s (Ticket %s)\n", v.LicensePlate(), r.ID, t.SpotID, t.ID)
+		}
+	}
+	now = at(8, 50)
+	arrive(alice, &models.Car{License: "ALICE"})
+	now = at(9, 10)
+	arrive(dave, &models.Car{License: "DAVE"})
+
+	now = at(9, 20)
+	for _, r := range bookings.ExpireNoShows() {
+		fmt.Printf("Expired %s for %s\n", r.ID, r.License)
+	}
+	reserve("ERIN", at(10, 0), at(11, 0))
+	reserve("FRANK", at(10, 30), at(11, 0)) // ALICE's booking runs until 11:00
+	park(lot, &models.Car{License: "WALK-2"})
+	arrive(bob, &models.Car{License: "BOB"})
+	fmt.Println(gate.NewBoard(lot).Render())
+}
+
+func demoCheckout(lot *parking.ParkingLot) {
+	fmt.Println("\n=== Checkout ===")
+	// simulated clock so the demo can fast-forward: Friday 18:00
+	now := time.Date(2025, time.June, 6, 18, 0, 0, 0, time.Local)
+	lot.SetClock(func() time.Time { return now })
 
 	vehicles := []models.Vehicle{
 		&models.Car{License: "CAR-001"},
 		&models.Car{License: "CAR-002"},
 		&models.Car{License: "CAR-003"},
 		&models.Motorcycle{License: "BIKE-001"},
-		&models.Truck{License: "TRUCK-001"},
-		&models.Truck{License: "TRUCK-002"},
+		&models.Car{License: "CAR-004"},
 	}
 
 	var tickets []*models.Ticket
 	for _, v := range vehicles {
-		t, err := lot.ParkVehicle(v)
-		if err != nil {
-			fmt.Println("Park error:", err)
-			continue
+		if t := park(lot, v); t != nil {
+			tickets = append(tickets, t)
 		}
-		fmt.Printf("Parked %s [%s] @ L%d/%s (Ticket %s)\n",
-			v.Type(), v.LicensePlate(), t.Level, t.SpotID, t.ID)
-		tickets = append(tickets, t)
 	}
 
 	fmt.Println("Availability:", lot.GetAvailability())
 
-	// unpark first vehicle
-	if len(tickets) > 0 {
-		if err := lot.UnparkVehicle(tickets[0]); err != nil {
-			fmt.Println("Unpark error:", err)
-		} else {
-			fmt.Println("Unparked:", tickets[0].Vehicle.LicensePlate())
-		}
-		fmt.Println("After unpark:", lot.GetAvailability())
+	// leave within the grace period: free
+	now = now.Add(10 * time.Minute)
+	exit(lot, tickets[0], nil)
+	fmt.Println("After unpark:", lot.GetAvailability())
+
+	// weekday evening running into the night rate, paid by card
+	now = now.Add(5*time.Hour + 20*time.Minute)
+	exit(lot, tickets[1], &payment.Card{Number: "4111 1111 1111 1111", Limit: models.Dollars(500)})
+
+	// a wallet without enough balance is refused and the car stays parked
+	now = now.Add(2 * time.Hour)
+	wallet := payment.NewWallet("W-42", models.Dollars(5))
+	exit(lot, tickets[2], wallet)
+	exit(lot, tickets[2], &payment.Cash{Tendered: models.Dollars(20)})
+	exit(lot, tickets[2], &payment.Cash{Tendered: models.Dollars(20)}) // ticket reuse
+
+	// weekend stay over two days hits the daily cap
+	now = now.Add(40 * time.Hour)
+	exit(lot, tickets[4], payment.NewWallet("FLEET-1", models.Dollars(200)))
+
+	// motorcycle rider lost the ticket
+	if r, err := lot.UnparkLostTicket("BIKE-001", &payment.Cash{Tendered: models.Dollars(50)}); err != nil {
+		fmt.Println("Lost ticket exit failed:", err)
+	} else {
+		fmt.Printf("Unparked %s without ticket, paid %s\n%s\n", r.License, r.Quote.Total, r.Quote)
+	}
+	fmt.Println("Final availability:", lot.GetAvailability())
+}
+
+// demoGates runs several entry and exit gates at once against one lot
+func demoGates() {
+	fmt.Println("\n=== Gates ===")
+	lot, err := parking.NewParkingLot(parking.Layout{Name: "mall", Strategy: "spread-load", Levels: []parking.LevelLayout{
+		{Number: 1, Spots: map[models.SpotSize]int{models.CompactSpot: 2, models.RegularSpot: 4, models.HandicappedSpot: 1}},
+		{Number: 2, Spots: map[models.SpotSize]int{models.RegularSpot: 4, models.EVSpot: 2}},
+	}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	board := gate.NewBoard(lot)
+	fmt.Println(board.Render())
+
+	entries := []*gate.Gate{gate.NewEntryGate("N", lot, nil), gate.NewEntryGate("S", lot, nil)}
+	exits := []*gate.Gate{gate.NewExitGate("E1", lot), gate.NewExitGate("E2", lot)}
+
+	// twelve cars queue at two entry gates at once; only ten fit
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var tickets []*models.Ticket
+	for i := range 12 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v := &models.Car{License: fmt.Sprintf("MALL-%02d", i), Electric: i%4 == 0}
+			t, err := entries[i%2].Enter(v)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			tickets = append(tickets, t)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	for _, g := range entries {
+		passed, refused := g.Stats()
+		fmt.Printf("Entry gate %s: %d in, %d refused\n", g.ID, passed, refused)
+	}
+	fmt.Println(board.Render())
+
+	// the same ticket presented at both exits at once opens only one
+	t := tickets[0]
+	var ok atomic.Int32
+	for _, g := range exits {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := g.Exit(t.ID, t.Vehicle.LicensePlate(), nil); err != nil {
+				fmt.Println(err)
+			} else {
+				ok.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("Ticket %s opened %d barrier(s)\n", t.ID, ok.Load())
+
+	// forged ticket, wrong car, entry gate used as exit
+	if _, err := exits[0].Exit("mall-L1-Regular-1-999", "FAKE-1", nil); err != nil {
+		fmt.Println(err)
+	}
+	if _, err := exits[0].Exit(tickets[1].ID, "MALL-99", nil); err != nil {
+		fmt.Println(err)
+	}
+	if _, err := entries[0].Exit(tickets[1].ID, tickets[1].Vehicle.LicensePlate(), nil); err != nil {
+		fmt.Println(err)
+	}
+	// a vehicle already inside cannot be issued a second ticket
+	if _, err := entries[1].Enter(tickets[2].Vehicle); err != nil {
+		fmt.Println(err)
+	}
+
+	// a lost ticket closes the original, which is then refused
+	lost := tickets[3]
+	if r, err := exits[1].ExitLost(lost.Vehicle.LicensePlate(), &payment.Cash{Tendered: models.Dollars(100)}); err == nil {
+		fmt.Printf("%s left without ticket, paid %s\n", r.License, r.Quote.Total)
+	}
+	if _, err := exits[0].Exit(lost.ID, lost.Vehicle.LicensePlate(), nil); err != nil {
+		fmt.Println(err)
+	}
+	if stored, ok := lot.Tickets().Get(lost.ID); ok {
+		fmt.Printf("Ticket %s closed at %s, fee %s\n", stored.ID, stored.ExitTime.Format("15:04"), stored.Fee)
+	}
+	fmt.Println(board.Render())
+}
+
+// park parks a vehicle and reports where it went
+func park(lot *parking.ParkingLot, v models.Vehicle) *models.Ticket {
+	t, err := lot.ParkVehicle(v)
+	if err != nil {
+		fmt.Printf("Park %s [%s] in %s: %v\n", v.Type(), v.LicensePlate(), lot.Name, err)
+		return nil
+	}
+	fmt.Printf("Parked %s [%s] @ %s L%d/%s (Ticket %s)\n",
+		v.Type(), v.LicensePlate(), lot.Name, t.Level, t.SpotID, t.ID)
+	return t
+}
+
+// exit pays for a ticket and reports the outcome
+func exit(lot *parking.ParkingLot, t *models.Ticket, pm payment.Method) {
+	r, err := lot.UnparkVehicle(t.ID, t.Vehicle.LicensePlate(), pm)
+	if err != nil {
+		fmt.Printf("Unpark %s refused: %v\n", t.Vehicle.LicensePlate(), err)
+		return
+	}
+	fmt.Printf("Unparked %s, paid %s by %s (txn %s)\n%s\n",
+		r.License, r.Quote.Total, r.Method, r.TransactionID, r.Quote)
+	if cash, ok := pm.(*payment.Cash); ok {
+		fmt.Println("  change:", cash.Change)
 	}
 }
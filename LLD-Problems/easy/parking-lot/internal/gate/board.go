@@ -0,0 +1,124 @@
+package gate
+
+import (
+	"fmt"
+	"parking-lot/internal/models"
+	"parking-lot/internal/parking"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Board is the display at the lot entrance showing live free spots per
+// level and spot size. It follows the lot as an observer; only Render
+// asks the lot's capacity hold how many free spots walk-ins may not take
+type Board struct {
+	Lot string
+
+	lot  *parking.ParkingLot
+	mu   sync.RWMutex
+	free map[int]map[models.SpotSize]int
+}
+
+// NewBoard creates a board and subscribes it to the lot (Factory Pattern)
+func NewBoard(lot *parking.ParkingLot) *Board {
+	b := &Board{Lot: lot.Name, lot: lot, free: map[int]map[models.SpotSize]int{}}
+	lot.AddObserver(b)
+	return b
+}
+
+// AvailabilityChanged implements parking.AvailabilityObserver
+func (b *Board) AvailabilityChanged(lot string, level int, size models.SpotSize, free int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.free[level] == nil {
+		b.free[level] = map[models.SpotSize]int{}
+	}
+	b.free[level][size] = free
+}
+
+// Free returns the free spots of a size on a level
+func (b *Board) Free(level int, size models.SpotSize) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.free[level][size]
+}
+
+// Held returns how many free spots of a size are held back from walk-ins
+func (b *Board) Held(size models.SpotSize) int {
+	h := b.lot.Hold()
+	if h == nil {
+		return 0
+	}
+	return h.Held(size, b.lot.Now())
+}
+
+// Render draws the board: one row per level, one column per spot size
+// the lot has, FULL where nothing is left. When spots are held for
+// reservations a Reserved row shows how many, and the Total row counts
+// only the spots a walk-in can take
+func (b *Board) Render() string {
+	held := map[models.SpotSize]int{}
+	anyHeld := false
+	for _, size := range models.SpotSizes {
+		held[size] = b.Held(size) // before b.mu: the hold may lock the lot
+		anyHeld = anyHeld || held[size] > 0
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var levels []int
+	present := map[models.SpotSize]bool{}
+	for lvl, counts := range b.free {
+		levels = append(levels, lvl)
+		for size := range counts {
+			present[size] = true
+		}
+	}
+	sort.Ints(levels)
+	var sizes []models.SpotSize
+	for _, size := range models.SpotSizes {
+		if present[size] {
+			sizes = append(sizes, size)
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%-8s", strings.ToUpper(b.Lot))
+	for _, size := range sizes {
+		fmt.Fprintf(&sb, " %11s", size)
+	}
+	totals := map[models.SpotSize]int{}
+	for _, lvl := range levels {
+		fmt.Fprintf(&sb, "\nLevel %-2d", lvl)
+		for _, size := range sizes {
+			n, ok := b.free[lvl][size]
+			totals[size] += n
+			sb.WriteString(" " + cell(n, ok))
+		}
+	}
+	if anyHeld {
+		fmt.Fprintf(&sb, "\n%-8s", "Reserved")
+		for _, size := range sizes {
+			held[size] = min(held[size], totals[size])
+			fmt.Fprintf(&sb, " %11d", held[size])
+		}
+	}
+	fmt.Fprintf(&sb, "\n%-8s", "Total")
+	for _, size := range sizes {
+		sb.WriteString(" " + cell(totals[size]-held[size], true))
+	}
+	return sb.String()
+}
+
+func cell(n int, ok bool) string {
+	switch {
+	case !ok:
+		return fmt.Sprintf("%11s", "-")
+	case n == 0:
+		return fmt.Sprintf("%11s", "FULL")
+	default:
+		return fmt.Sprintf("%11d", n)
+	}
+}
@@ -0,0 +1,112 @@
+package gate
+
+import (
+	"errors"
+	"fmt"
+	"parking-lot/internal/models"
+	"parking-lot/internal/parking"
+	"parking-lot/internal/payment"
+	"parking-lot/internal/reservation"
+	"sync/atomic"
+)
+
+var ErrWrongGate = errors.New("wrong gate for this action")
+
+// Kind enum
+type Kind int
+
+const (
+	Entry Kind = iota
+	Exit
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Entry:
+		return "Entry"
+	case Exit:
+		return "Exit"
+	default:
+		return "Unknown"
+	}
+}
+
+// Gate is one barrier of a lot. Any number of gates may run
+// concurrently; they share the lot's ticket store, so a ticket is
+// accepted by exactly one exit
+type Gate struct {
+	ID   string
+	Kind Kind
+
+	lot      *parking.ParkingLot
+	bookings *reservation.Service // entry gates only; may be nil
+
+	passed  atomic.Uint64
+	refused atomic.Uint64
+}
+
+// NewEntryGate creates an entry barrier; bookings may be nil if the lot
+// takes no reservations (Factory Pattern)
+func NewEntryGate(id string, lot *parking.ParkingLot, bookings *reservation.Service) *Gate {
+	return &Gate{ID: id, Kind: Entry, lot: lot, bookings: bookings}
+}
+
+// NewExitGate creates an exit barrier (Factory Pattern)
+func NewExitGate(id string, lot *parking.ParkingLot) *Gate {
+	return &Gate{ID: id, Kind: Exit, lot: lot}
+}
+
+// Enter issues a ticket to a walk-in and lifts the barrier
+func (g *Gate) Enter(v models.Vehicle) (*models.Ticket, error) {
+	if g.Kind != Entry {
+		return nil, g.refuse(ErrWrongGate)
+	}
+	t, err := g.lot.ParkVehicle(v)
+	return t, g.result(err)
+}
+
+// EnterReserved admits a vehicle on its reservation
+func (g *Gate) EnterReserved(reservationID string, v models.Vehicle) (*models.Ticket, error) {
+	if g.Kind != Entry || g.bookings == nil {
+		return nil, g.refuse(ErrWrongGate)
+	}
+	t, err := g.bookings.Arrive(reservationID, v)
+	return t, g.result(err)
+}
+
+// Exit takes the scanned ticket ID and the plate read by the gate's
+// camera; the barrier lifts only if they match and payment succeeds
+func (g *Gate) Exit(ticketID, plate string, pm payment.Method) (*parking.Receipt, error) {
+	if g.Kind != Exit {
+		return nil, g.refuse(ErrWrongGate)
+	}
+	r, err := g.lot.UnparkVehicle(ticketID, plate, pm)
+	return r, g.result(err)
+}
+
+// ExitLost lets a vehicle out without its ticket after the lost-ticket fee
+func (g *Gate) ExitLost(plate string, pm payment.Method) (*parking.Receipt, error) {
+	if g.Kind != Exit {
+		return nil, g.refuse(ErrWrongGate)
+	}
+	r, err := g.lot.UnparkLostTicket(plate, pm)
+	return r, g.result(err)
+}
+
+// Stats returns how many vehicles this gate let through and refused
+func (g *Gate) Stats() (passed, refused uint64) {
+	return g.passed.Load(), g.refused.Load()
+}
+
+func (g *Gate) result(err error) error {
+	if err != nil {
+		return g.refuse(err)
+	}
+	g.passed.Add(1)
+	return nil
+}
+
+func (g *Gate) refuse(err error) error {
+	g.refused.Add(1)
+	return fmt.Errorf("%s gate %s: %w", g.Kind, g.ID, err)
+}
@@ -0,0 +1,17 @@
+package models
+
+import "fmt"
+
+// Money is an amount in cents, so fees never suffer float rounding
+type Money int64
+
+// Dollars converts whole dollars to Money
+func Dollars(d int64) Money { return Money(d * 100) }
+
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign, m = "-", -m
+	}
+	return fmt.Sprintf("%s$%d.%02d", sign, m/100, m%100)
+}
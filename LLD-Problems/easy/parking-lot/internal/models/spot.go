@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SpotSize enum
+type SpotSize int
+
+const (
+	CompactSpot SpotSize = iota
+	RegularSpot
+	LargeSpot
+	EVSpot          // regular-sized with a charger
+	HandicappedSpot // regular-sized, wider, near the exits
+)
+
+// SpotSizes lists every size in display order
+var SpotSizes = []SpotSize{CompactSpot, RegularSpot, LargeSpot, EVSpot, HandicappedSpot}
+
+func (s SpotSize) String() string {
+	switch s {
+	case CompactSpot:
+		return "Compact"
+	case RegularSpot:
+		return "Regular"
+	case LargeSpot:
+		return "Large"
+	case EVSpot:
+		return "EV"
+	case HandicappedSpot:
+		return "Handicapped"
+	default:
+		return "Unknown"
+	}
+}
+
+// ParseSpotSize accepts a size name in any case
+func ParseSpotSize(name string) (SpotSize, error) {
+	for _, s := range SpotSizes {
+		if strings.EqualFold(name, s.String()) {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown spot size %q", name)
+}
+
+// MarshalText lets sizes be used as JSON/YAML values and map keys
+func (s SpotSize) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(s.String())), nil
+}
+
+func (s *SpotSize) UnmarshalText(b []byte) error {
+	size, err := ParseSpotSize(string(b))
+	if err != nil {
+		return err
+	}
+	*s = size
+	return nil
+}
+
+// SizesFor returns the spot sizes a vehicle may use, most suitable first:
+// EV and handicapped spots are kept for the vehicles that need them
+func SizesFor(v Vehicle) []SpotSize {
+	switch v.Type() {
+	case MotorcycleType:
+		return []SpotSize{CompactSpot}
+	case TruckType:
+		return []SpotSize{LargeSpot}
+	}
+	var sizes []SpotSize
+	if HasPermit(v) {
+		sizes = append(sizes, HandicappedSpot)
+	}
+	if IsElectric(v) {
+		sizes = append(sizes, EVSpot)
+	}
+	return append(sizes, RegularSpot)
+}
+
+// LargerSizes returns the general-purpose sizes bigger than a vehicle
+// needs, smallest first, for lots that let small vehicles upsize
+func LargerSizes(v Vehicle) []SpotSize {
+	switch v.Type() {
+	case MotorcycleType:
+		return []SpotSize{RegularSpot, LargeSpot}
+	case CarType:
+		return []SpotSize{LargeSpot}
+	}
+	return nil
+}
+
+// Fits reports whether a vehicle may use a spot of this size, either
+// one meant for it or a larger general-purpose one
+func (s SpotSize) Fits(v Vehicle) bool {
+	for _, size := range append(SizesFor(v), LargerSizes(v)...) {
+		if size == s {
+			return true
+		}
+	}
+	return false
+}
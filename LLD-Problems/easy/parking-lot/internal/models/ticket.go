@@ -5,8 +5,18 @@ import "time"
 // Ticket holds parking info
 type Ticket struct {
 	ID        string
+	Lot       string
 	Vehicle   Vehicle
 	Level     int
 	SpotID    string
 	EntryTime time.Time
+
+	ReservationID string // set when the vehicle arrived on a booking
+
+	// set on exit
+	ExitTime time.Time
+	Fee      Money
 }
+
+// Closed reports whether the ticket has been paid and used to exit
+func (t *Ticket) Closed() bool { return !t.ExitTime.IsZero() }
@@ -29,10 +29,16 @@ type Vehicle interface {
 }
 
 // Car, Motorcycle, Truck concrete types
-type Car struct{ License string }
+type Car struct {
+	License  string
+	Electric bool // may use EV spots
+	Permit   bool // disabled badge: may use handicapped spots
+}
 
 func (c *Car) Type() VehicleType    { return CarType }
 func (c *Car) LicensePlate() string { return c.License }
+func (c *Car) IsElectric() bool     { return c.Electric }
+func (c *Car) HasPermit() bool      { return c.Permit }
 
 type Motorcycle struct{ License string }
 
@@ -43,3 +49,15 @@ type Truck struct{ License string }
 
 func (t *Truck) Type() VehicleType    { return TruckType }
 func (t *Truck) LicensePlate() string { return t.License }
+
+// IsElectric reports whether a vehicle needs charging
+func IsElectric(v Vehicle) bool {
+	e, ok := v.(interface{ IsElectric() bool })
+	return ok && e.IsElectric()
+}
+
+// HasPermit reports whether a vehicle displays a disabled badge
+func HasPermit(v Vehicle) bool {
+	p, ok := v.(interface{ HasPermit() bool })
+	return ok && p.HasPermit()
+}
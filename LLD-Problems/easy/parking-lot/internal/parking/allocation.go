@@ -0,0 +1,128 @@
+package parking
+
+import (
+	"fmt"
+	"parking-lot/internal/models"
+	"strings"
+)
+
+// LevelState is what a strategy sees of a level when ranking levels
+type LevelState struct {
+	Level *Level
+	Best  *ParkingSpot // its best free spot of the wanted size
+	Free  int          // its free spots of the wanted size
+}
+
+// Strategy decides which free spot a vehicle gets (Strategy Pattern).
+// LevelLess may only look at Best, Free and the level itself: the index
+// re-ranks a level when those change, not otherwise
+type Strategy interface {
+	Name() string
+	// Sizes lists the spot sizes to try for a vehicle, in order
+	Sizes(v models.Vehicle) []models.SpotSize
+	// SpotLess orders the free spots of one size on one level
+	SpotLess(a, b *ParkingSpot) bool
+	// LevelLess orders the levels that have a free spot of that size
+	LevelLess(a, b LevelState) bool
+}
+
+// Built-in strategies
+var (
+	NearestEntrance  Strategy = nearestEntrance{}
+	NearestElevator  Strategy = nearestElevator{}
+	LowestLevelFirst Strategy = lowestLevelFirst{}
+	SpreadLoad       Strategy = spreadLoad{}
+)
+
+// exactSize gives every vehicle the sizes meant for it
+type exactSize struct{}
+
+func (exactSize) Sizes(v models.Vehicle) []models.SpotSize { return models.SizesFor(v) }
+
+// nearestEntrance minimises the distance driven from the lot entrance
+type nearestEntrance struct{ exactSize }
+
+func (nearestEntrance) Name() string { return "nearest-entrance" }
+
+func (nearestEntrance) SpotLess(a, b *ParkingSpot) bool {
+	return a.EntranceDistance < b.EntranceDistance
+}
+
+func (nearestEntrance) LevelLess(a, b LevelState) bool {
+	if a.Best.EntranceDistance != b.Best.EntranceDistance {
+		return a.Best.EntranceDistance < b.Best.EntranceDistance
+	}
+	return a.Level.rank < b.Level.rank
+}
+
+// nearestElevator minimises the walk to the lift lobby
+type nearestElevator struct{ exactSize }
+
+func (nearestElevator) Name() string { return "nearest-elevator" }
+
+func (nearestElevator) SpotLess(a, b *ParkingSpot) bool {
+	if a.ElevatorDistance != b.ElevatorDistance {
+		return a.ElevatorDistance < b.ElevatorDistance
+	}
+	return a.Position < b.Position
+}
+
+func (nearestElevator) LevelLess(a, b LevelState) bool {
+	if a.Best.ElevatorDistance != b.Best.ElevatorDistance {
+		return a.Best.ElevatorDistance < b.Best.ElevatorDistance
+	}
+	return a.Level.rank < b.Level.rank
+}
+
+// lowestLevelFirst fills levels by number, each from the ramp inwards
+type lowestLevelFirst struct{ exactSize }
+
+func (lowestLevelFirst) Name() string { return "lowest-level-first" }
+
+func (lowestLevelFirst) SpotLess(a, b *ParkingSpot) bool { return a.Position < b.Position }
+
+func (lowestLevelFirst) LevelLess(a, b LevelState) bool { return a.Level.Number < b.Level.Number }
+
+// spreadLoad sends each vehicle to the level with the most free spots
+// of its size, evening out traffic on the ramps
+type spreadLoad struct{ exactSize }
+
+func (spreadLoad) Name() string { return "spread-load" }
+
+func (spreadLoad) SpotLess(a, b *ParkingSpot) bool { return a.Position < b.Position }
+
+func (spreadLoad) LevelLess(a, b LevelState) bool {
+	if a.Free != b.Free {
+		return a.Free > b.Free
+	}
+	return a.Level.rank < b.Level.rank
+}
+
+// upsizing lets vehicles take larger general-purpose spots once the
+// sizes meant for them are full
+type upsizing struct{ Strategy }
+
+// AllowUpsizing wraps a strategy so a motorcycle may take a regular or
+// large spot, and a car a large one, when its own sizes are full
+func AllowUpsizing(s Strategy) Strategy { return upsizing{s} }
+
+func (u upsizing) Name() string { return u.Strategy.Name() + "+upsize" }
+
+func (u upsizing) Sizes(v models.Vehicle) []models.SpotSize {
+	return append(u.Strategy.Sizes(v), models.LargerSizes(v)...)
+}
+
+// StrategyByName returns a built-in strategy; a "+upsize" suffix wraps
+// it with AllowUpsizing
+func StrategyByName(name string) (Strategy, error) {
+	base, upsize := strings.CutSuffix(name, "+upsize")
+	for _, s := range []Strategy{NearestEntrance, NearestElevator, LowestLevelFirst, SpreadLoad} {
+		if s.Name() == base {
+			if upsize {
+				return AllowUpsizing(s), nil
+			}
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown allocation strategy %q", name)
+}
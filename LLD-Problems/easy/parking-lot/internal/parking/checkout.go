@@ -0,0 +1,122 @@
+package parking
+
+import (
+	"errors"
+	"fmt"
+	"parking-lot/internal/models"
+	"parking-lot/internal/payment"
+	"parking-lot/internal/pricing"
+	"time"
+)
+
+// Receipt is the proof of payment handed out on exit
+type Receipt struct {
+	TicketID      string
+	License       string
+	Quote         pricing.Quote
+	Method        string
+	TransactionID string
+	PaidAt        time.Time
+}
+
+// SetPricing replaces the pricing strategy (DefaultTariff by default)
+func (pl *ParkingLot) SetPricing(s pricing.Strategy) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.pricing = s
+}
+
+// SetClock replaces time.Now, e.g. to simulate long stays
+func (pl *ParkingLot) SetClock(now func() time.Time) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.now = now
+}
+
+// Quote prices the stay on a ticket as if the vehicle left now
+func (pl *ParkingLot) Quote(ticketID string) (pricing.Quote, error) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	t, ok := pl.tickets.lookup(ticketID)
+	if !ok {
+		return pricing.Quote{}, ErrUnknownTicket
+	}
+	return pl.pricing.Quote(t.Vehicle.Type(), t.EntryTime, pl.now()), nil
+}
+
+// UnparkVehicle checks the ticket against the store and the plate read
+// at the exit, prices the stay, takes payment and only then releases
+// the spot and closes the ticket. If payment fails the vehicle stays
+// parked and the ticket stays open.
+func (pl *ParkingLot) UnparkVehicle(ticketID, license string, pm payment.Method) (*Receipt, error) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	t, err := pl.tickets.validate(ticketID, license)
+	if err != nil {
+		return nil, err
+	}
+	lvl, err := pl.level(t.Level)
+	if err != nil {
+		return nil, err
+	}
+	if v, err := lvl.occupant(t.SpotID); err != nil {
+		return nil, err
+	} else if v != t.Vehicle {
+		return nil, errors.New("vehicle not in ticketed spot")
+	}
+
+	exit := pl.now()
+	quote := pl.pricing.Quote(t.Vehicle.Type(), t.EntryTime, exit)
+	r, err := pl.charge(t.ID, t.Vehicle, quote, pm, exit)
+	if err != nil {
+		return nil, err
+	}
+	if err := pl.release(lvl, t.SpotID); err != nil {
+		return nil, err
+	}
+	pl.tickets.close(t, exit, quote.Total)
+	return r, nil
+}
+
+// UnparkLostTicket lets a vehicle out without its ticket after paying
+// the lost-ticket fee; the lost ticket is closed so it cannot be used
+func (pl *ParkingLot) UnparkLostTicket(license string, pm payment.Method) (*Receipt, error) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	t, ok := pl.tickets.openFor(license)
+	if !ok {
+		return nil, fmt.Errorf("no vehicle %s in the lot", license)
+	}
+	lvl, err := pl.level(t.Level)
+	if err != nil {
+		return nil, err
+	}
+	exit := pl.now()
+	quote := pl.pricing.LostTicket(t.Vehicle.Type(), t.EntryTime, exit)
+	r, err := pl.charge("LOST-"+t.ID, t.Vehicle, quote, pm, exit)
+	if err != nil {
+		return nil, err
+	}
+	if err := pl.release(lvl, t.SpotID); err != nil {
+		return nil, err
+	}
+	pl.tickets.close(t, exit, quote.Total)
+	return r, nil
+}
+
+// charge takes payment for a quote; nothing is charged for a free stay
+func (pl *ParkingLot) charge(ref string, v models.Vehicle, q pricing.Quote, pm payment.Method, at time.Time) (*Receipt, error) {
+	r := &Receipt{TicketID: ref, License: v.LicensePlate(), Quote: q, Method: "none", PaidAt: at}
+	if q.Total == 0 {
+		return r, nil
+	}
+	if pm == nil {
+		return nil, errors.New("payment required")
+	}
+	txn, err := pm.Pay(q.Total, ref)
+	if err != nil {
+		return nil, fmt.Errorf("payment of %s by %s failed: %w", q.Total, pm.Name(), err)
+	}
+	r.Method, r.TransactionID = pm.Name(), txn
+	return r, nil
+}
@@ -0,0 +1,168 @@
+package parking
+
+import (
+	"container/heap"
+	"parking-lot/internal/models"
+)
+
+// spotIndex keeps the free spots ordered for a strategy so allocation
+// and release are O(log n): per size, a heap of free spots on each
+// level, and a heap of those levels ranked by the strategy
+type spotIndex struct {
+	strategy Strategy
+	sizes    map[models.SpotSize]*levelHeap
+}
+
+func newSpotIndex(s Strategy, levels []*Level) *spotIndex {
+	idx := &spotIndex{strategy: s, sizes: map[models.SpotSize]*levelHeap{}}
+	for _, lvl := range levels {
+		for _, spot := range lvl.spots {
+			spot.heapIdx = -1
+			if spot.IsAvailable() {
+				idx.add(spot)
+			}
+		}
+	}
+	return idx
+}
+
+// best returns the spot the strategy picks among free spots of one
+// size, or nil if none is free
+func (idx *spotIndex) best(size models.SpotSize) *ParkingSpot {
+	if lh := idx.sizes[size]; lh != nil && len(lh.levels) > 0 {
+		return lh.levels[0].spots[0]
+	}
+	return nil
+}
+
+// count returns how many spots of a size are free
+func (idx *spotIndex) count(size models.SpotSize) int {
+	if lh := idx.sizes[size]; lh != nil {
+		return lh.free
+	}
+	return 0
+}
+
+// add marks a spot free
+func (idx *spotIndex) add(s *ParkingSpot) {
+	lh := idx.sizes[s.Size]
+	if lh == nil {
+		lh = &levelHeap{strategy: idx.strategy, byLevel: map[*Level]*freeSpots{}}
+		idx.sizes[s.Size] = lh
+	}
+	fs := lh.byLevel[s.Level]
+	if fs == nil {
+		fs = &freeSpots{level: s.Level, less: idx.strategy.SpotLess, heapIdx: -1}
+		lh.byLevel[s.Level] = fs
+	}
+	heap.Push(fs, s)
+	lh.free++
+	lh.update(fs)
+}
+
+// remove marks a spot taken
+func (idx *spotIndex) remove(s *ParkingSpot) {
+	if s.heapIdx < 0 {
+		return
+	}
+	lh := idx.sizes[s.Size]
+	fs := lh.byLevel[s.Level]
+	heap.Remove(fs, s.heapIdx)
+	s.heapIdx = -1
+	lh.free--
+	lh.update(fs)
+}
+
+// freeSpots is a heap of one level's free spots of one size
+type freeSpots struct {
+	level   *Level
+	spots   []*ParkingSpot
+	less    func(a, b *ParkingSpot) bool
+	heapIdx int // position in the levelHeap; -1 when the level has none free
+}
+
+func (fs *freeSpots) Len() int { return len(fs.spots) }
+
+func (fs *freeSpots) Less(i, j int) bool {
+	if fs.less(fs.spots[i], fs.spots[j]) {
+		return true
+	}
+	if fs.less(fs.spots[j], fs.spots[i]) {
+		return false
+	}
+	return fs.spots[i].Position < fs.spots[j].Position
+}
+
+func (fs *freeSpots) Swap(i, j int) {
+	fs.spots[i], fs.spots[j] = fs.spots[j], fs.spots[i]
+	fs.spots[i].heapIdx = i
+	fs.spots[j].heapIdx = j
+}
+
+func (fs *freeSpots) Push(x interface{}) {
+	s := x.(*ParkingSpot)
+	s.heapIdx = len(fs.spots)
+	fs.spots = append(fs.spots, s)
+}
+
+func (fs *freeSpots) Pop() interface{} {
+	s := fs.spots[len(fs.spots)-1]
+	fs.spots = fs.spots[:len(fs.spots)-1]
+	return s
+}
+
+func (fs *freeSpots) state() LevelState {
+	return LevelState{Level: fs.level, Best: fs.spots[0], Free: len(fs.spots)}
+}
+
+// levelHeap ranks the levels that have a free spot of one size
+type levelHeap struct {
+	strategy Strategy
+	byLevel  map[*Level]*freeSpots
+	levels   []*freeSpots
+	free     int // free spots on all levels
+}
+
+// update re-ranks a level after its free spots changed
+func (lh *levelHeap) update(fs *freeSpots) {
+	switch {
+	case len(fs.spots) == 0 && fs.heapIdx >= 0:
+		heap.Remove(lh, fs.heapIdx)
+		fs.heapIdx = -1
+	case len(fs.spots) > 0 && fs.heapIdx < 0:
+		heap.Push(lh, fs)
+	case len(fs.spots) > 0:
+		heap.Fix(lh, fs.heapIdx)
+	}
+}
+
+func (lh *levelHeap) Len() int { return len(lh.levels) }
+
+func (lh *levelHeap) Less(i, j int) bool {
+	a, b := lh.levels[i].state(), lh.levels[j].state()
+	if lh.strategy.LevelLess(a, b) {
+		return true
+	}
+	if lh.strategy.LevelLess(b, a) {
+		return false
+	}
+	return a.Level.rank < b.Level.rank
+}
+
+func (lh *levelHeap) Swap(i, j int) {
+	lh.levels[i], lh.levels[j] = lh.levels[j], lh.levels[i]
+	lh.levels[i].heapIdx = i
+	lh.levels[j].heapIdx = j
+}
+
+func (lh *levelHeap) Push(x interface{}) {
+	fs := x.(*freeSpots)
+	fs.heapIdx = len(lh.levels)
+	lh.levels = append(lh.levels, fs)
+}
+
+func (lh *levelHeap) Pop() interface{} {
+	fs := lh.levels[len(lh.levels)-1]
+	lh.levels = lh.levels[:len(lh.levels)-1]
+	return fs
+}
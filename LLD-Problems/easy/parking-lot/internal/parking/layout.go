@@ -0,0 +1,126 @@
+package parking
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"parking-lot/internal/models"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Layout describes a lot: its levels and how many spots of each size
+// they have
+type Layout struct {
+	Name   string        `json:"name" yaml:"name"`
+	Levels []LevelLayout `json:"levels" yaml:"levels"`
+	// RampLength is the distance between levels in spot widths;
+	// DefaultRampLength if 0. The entrance is on the first level listed
+	RampLength int `json:"ramp_length,omitempty" yaml:"ramp_length,omitempty"`
+	// Strategy names the allocation strategy, see StrategyByName;
+	// lowest-level-first if empty
+	Strategy string `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+}
+
+// LevelLayout describes one level
+type LevelLayout struct {
+	Number int                     `json:"number" yaml:"number"`
+	Spots  map[models.SpotSize]int `json:"spots" yaml:"spots"`
+	// Elevator is the aisle position of the lift lobby, counted in
+	// spots from the ramp
+	Elevator int `json:"elevator,omitempty" yaml:"elevator,omitempty"`
+}
+
+// DefaultRampLength is the distance between levels when a layout sets none
+const DefaultRampLength = 10
+
+// UniformLayout builds numLevels identical levels with perSize spots of
+// every size
+func UniformLayout(name string, numLevels, perSize int) Layout {
+	l := Layout{Name: name}
+	for i := 1; i <= numLevels; i++ {
+		spots := map[models.SpotSize]int{}
+		for _, s := range models.SpotSizes {
+			spots[s] = perSize
+		}
+		l.Levels = append(l.Levels, LevelLayout{Number: i, Spots: spots})
+	}
+	return l
+}
+
+// ParseLayout decodes a layout; format is "json" or "yaml"
+func ParseLayout(data []byte, format string) (Layout, error) {
+	var l Layout
+	var err error
+	switch strings.ToLower(format) {
+	case "json":
+		dec := json.NewDecoder(strings.NewReader(string(data)))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&l)
+	case "yaml", "yml":
+		dec := yaml.NewDecoder(strings.NewReader(string(data)))
+		dec.KnownFields(true)
+		err = dec.Decode(&l)
+	default:
+		return l, fmt.Errorf("unknown layout format %q", format)
+	}
+	if err != nil {
+		return l, fmt.Errorf("parse layout: %w", err)
+	}
+	return l, l.Validate()
+}
+
+// LoadLayout reads a .json, .yaml or .yml layout file
+func LoadLayout(path string) (Layout, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Layout{}, err
+	}
+	l, err := ParseLayout(data, strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return l, fmt.Errorf("%s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Validate checks the layout can be built
+func (l Layout) Validate() error {
+	if l.Name == "" {
+		return errors.New("layout has no name")
+	}
+	if len(l.Levels) == 0 {
+		return fmt.Errorf("lot %s has no levels", l.Name)
+	}
+	if l.RampLength < 0 {
+		return fmt.Errorf("lot %s: negative ramp length", l.Name)
+	}
+	if l.Strategy != "" {
+		if _, err := StrategyByName(l.Strategy); err != nil {
+			return fmt.Errorf("lot %s: %w", l.Name, err)
+		}
+	}
+	seen := map[int]bool{}
+	for _, lvl := range l.Levels {
+		if seen[lvl.Number] {
+			return fmt.Errorf("lot %s: duplicate level %d", l.Name, lvl.Number)
+		}
+		seen[lvl.Number] = true
+		total := 0
+		for size, n := range lvl.Spots {
+			if n < 0 {
+				return fmt.Errorf("lot %s level %d: negative %s spot count", l.Name, lvl.Number, size)
+			}
+			total += n
+		}
+		if total == 0 {
+			return fmt.Errorf("lot %s level %d has no spots", l.Name, lvl.Number)
+		}
+		if lvl.Elevator < 0 || lvl.Elevator >= total {
+			return fmt.Errorf("lot %s level %d: elevator outside the aisle", l.Name, lvl.Number)
+		}
+	}
+	return nil
+}
@@ -14,64 +14,101 @@ var ticketCount uint64
 // Level holds spots for one floor
 type Level struct {
 	Number    int
+	lot       string
+	rank      int // 0 for the level with the lot entrance, then in layout order
 	spots     []*ParkingSpot
+	byID      map[string]*ParkingSpot
 	mu        sync.Mutex
-	available map[models.VehicleType]int
+	available map[models.SpotSize]int
 }
 
-func NewLevel(levelNumber, spotsPerType int) *Level {
+// NewLevel creates a level from its layout. Spots are numbered along the
+// aisle from the ramp, size by size; entranceOffset is the distance from
+// the lot entrance to this level's ramp
+func NewLevel(lot string, rank, entranceOffset int, ll LevelLayout) *Level {
 	l := &Level{
-		Number:    levelNumber,
-		available: map[models.VehicleType]int{},
+		Number:    ll.Number,
+		lot:       lot,
+		rank:      rank,
+		byID:      map[string]*ParkingSpot{},
+		available: map[models.SpotSize]int{},
 	}
-	types := []models.VehicleType{
-		models.CarType,
-		models.MotorcycleType,
-		models.TruckType,
-	}
-	for _, t := range types {
-		for i := 0; i < spotsPerType; i++ {
-			id := fmt.Sprintf("L%d-%s-%d", levelNumber, t.String(), i+1)
-			l.spots = append(l.spots, &ParkingSpot{ID: id, SpotType: t})
+	for _, size := range models.SpotSizes {
+		n := ll.Spots[size]
+		for i := 0; i < n; i++ {
+			pos := len(l.spots)
+			s := &ParkingSpot{
+				ID:               fmt.Sprintf("L%d-%s-%d", ll.Number, size, i+1),
+				Size:             size,
+				Level:            l,
+				Position:         pos,
+				EntranceDistance: entranceOffset + pos,
+				ElevatorDistance: abs(pos - ll.Elevator),
+				heapIdx:          -1,
+			}
+			l.spots = append(l.spots, s)
+			l.byID[s.ID] = s
+		}
+		if n > 0 {
+			l.available[size] = n
 		}
-		l.available[t] = spotsPerType
 	}
 	return l
 }
 
-func (l *Level) ParkVehicle(v models.Vehicle) (*models.Ticket, error) {
+// park puts a vehicle in a spot the allocator picked and issues a ticket
+func (l *Level) park(s *ParkingSpot, v models.Vehicle, at time.Time) (*models.Ticket, error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	for _, s := range l.spots {
-		if s.SpotType == v.Type() && s.IsAvailable() {
-			if err := s.Park(v); err != nil {
-				continue
-			}
-			l.available[v.Type()]--
-			tid := fmt.Sprintf("%s-%d", s.ID, atomic.AddUint64(&ticketCount, 1))
-			return &models.Ticket{
-				ID:        tid,
-				Vehicle:   v,
-				Level:     l.Number,
-				SpotID:    s.ID,
-				EntryTime: time.Now(),
-			}, nil
-		}
+	if err := s.Park(v); err != nil {
+		return nil, err
 	}
-	return nil, errors.New("no available spots on level")
+	l.available[s.Size]--
+	tid := fmt.Sprintf("%s-%s-%d", l.lot, s.ID, atomic.AddUint64(&ticketCount, 1))
+	return &models.Ticket{
+		ID:        tid,
+		Lot:       l.lot,
+		Vehicle:   v,
+		Level:     l.Number,
+		SpotID:    s.ID,
+		EntryTime: at,
+	}, nil
 }
 
-func (l *Level) UnparkVehicle(t *models.Ticket) error {
+// occupant returns the vehicle parked in a spot
+func (l *Level) occupant(spotID string) (models.Vehicle, error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	for _, s := range l.spots {
-		if s.ID == t.SpotID {
-			if _, err := s.Unpark(); err != nil {
-				return err
-			}
-			l.available[s.SpotType]++
-			return nil
-		}
+	s, ok := l.byID[spotID]
+	if !ok {
+		return nil, errors.New("spot not found")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.occupied {
+		return nil, errors.New("spot empty")
+	}
+	return s.vehicle, nil
+}
+
+// unpark empties a spot and returns it
+func (l *Level) unpark(spotID string) (*ParkingSpot, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s, ok := l.byID[spotID]
+	if !ok {
+		return nil, errors.New("spot not found")
+	}
+	if _, err := s.Unpark(); err != nil {
+		return nil, err
+	}
+	l.available[s.Size]++
+	return s, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
 	}
-	return errors.New("spot not found")
+	return n
 }
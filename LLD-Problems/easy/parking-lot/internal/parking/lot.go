@@ -2,67 +2,203 @@ package parking
 
 import (
 	"errors"
+	"fmt"
 	"parking-lot/internal/models"
+	"parking-lot/internal/pricing"
 	"sync"
+	"time"
 )
 
-// ParkingLot is a singleton managing all levels
+// ErrReservedFull is returned to walk-ins when the only free spots are
+// held for reservations
+var ErrReservedFull = errors.New("remaining spots are reserved")
+
+// CapacityHold holds back capacity from walk-ins, e.g. for reservations
+type CapacityHold interface {
+	// Held returns how many free spots of a size walk-ins may not take
+	Held(size models.SpotSize, at time.Time) int
+}
+
+// ParkingLot manages the levels of one lot
 type ParkingLot struct {
-	levels []*Level
-	mu     sync.Mutex
+	Name      string
+	levels    []*Level
+	mu        sync.Mutex
+	strategy  Strategy
+	free      *spotIndex
+	hold      CapacityHold
+	tickets   *TicketStore
+	observers []AvailabilityObserver
+	pricing   pricing.Strategy
+	now       func() time.Time
 }
 
-var (
-	instance *ParkingLot
-	once     sync.Once
-)
+// NewParkingLot builds a lot from its layout (Factory Pattern)
+func NewParkingLot(layout Layout) (*ParkingLot, error) {
+	if err := layout.Validate(); err != nil {
+		return nil, err
+	}
+	strategy := LowestLevelFirst
+	if layout.Strategy != "" {
+		strategy, _ = StrategyByName(layout.Strategy) // checked by Validate
+	}
+	ramp := layout.RampLength
+	if ramp == 0 {
+		ramp = DefaultRampLength
+	}
+	pl := &ParkingLot{
+		Name:     layout.Name,
+		strategy: strategy,
+		tickets:  NewTicketStore(),
+		pricing:  pricing.DefaultTariff(),
+		now:      time.Now,
+	}
+	for i, l := range layout.Levels {
+		pl.levels = append(pl.levels, NewLevel(layout.Name, i, i*ramp, l))
+	}
+	pl.free = newSpotIndex(strategy, pl.levels)
+	return pl, nil
+}
 
-// GetParkingLot initializes on first call
-func GetParkingLot(numLevels, spotsPerType int) *ParkingLot {
-	once.Do(func() {
-		pl := &ParkingLot{}
-		for i := 1; i <= numLevels; i++ {
-			pl.levels = append(pl.levels, NewLevel(i, spotsPerType))
-		}
-		instance = pl
-	})
-	return instance
+// SetStrategy switches the allocation strategy, re-indexing free spots
+func (pl *ParkingLot) SetStrategy(s Strategy) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.strategy = s
+	pl.free = newSpotIndex(s, pl.levels)
+}
+
+// SetHold installs the capacity hold walk-ins must respect
+func (pl *ParkingLot) SetHold(h CapacityHold) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.hold = h
+}
+
+// Hold returns the installed capacity hold, or nil
+func (pl *ParkingLot) Hold() CapacityHold {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	return pl.hold
+}
+
+// Now returns the lot's current time
+func (pl *ParkingLot) Now() time.Time {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	return pl.now()
+}
+
+// Tickets returns the store of tickets this lot issued
+func (pl *ParkingLot) Tickets() *TicketStore { return pl.tickets }
+
+// Strategy returns the allocation strategy in use
+func (pl *ParkingLot) Strategy() Strategy {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	return pl.strategy
 }
 
-// ParkVehicle tries each level in order
+// ParkVehicle parks a walk-in in the spot the allocation strategy
+// picks, leaving alone the spots held for reservations
 func (pl *ParkingLot) ParkVehicle(v models.Vehicle) (*models.Ticket, error) {
 	pl.mu.Lock()
 	defer pl.mu.Unlock()
+	if err := pl.tickets.checkIn(v.LicensePlate()); err != nil {
+		return nil, err
+	}
+	now := pl.now()
+	err := errors.New("parking lot full")
+	for _, size := range pl.strategy.Sizes(v) {
+		free := pl.free.count(size)
+		if free == 0 {
+			continue
+		}
+		if pl.hold != nil && free <= pl.hold.Held(size, now) {
+			err = ErrReservedFull
+			continue
+		}
+		return pl.parkIn(pl.free.best(size), v, now, "")
+	}
+	return nil, err
+}
+
+// ParkReserved parks a vehicle in a spot of the size held for its
+// reservation, ignoring holds
+func (pl *ParkingLot) ParkReserved(v models.Vehicle, size models.SpotSize, reservationID string) (*models.Ticket, error) {
+	if !size.Fits(v) {
+		return nil, fmt.Errorf("%s does not fit a %s spot", v.Type(), size)
+	}
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	if err := pl.tickets.checkIn(v.LicensePlate()); err != nil {
+		return nil, err
+	}
+	s := pl.free.best(size)
+	if s == nil {
+		return nil, fmt.Errorf("no free %s spot", size)
+	}
+	return pl.parkIn(s, v, pl.now(), reservationID)
+}
+
+// parkIn parks in a free spot, takes it out of the index and issues the
+// ticket, returning the caller's copy
+func (pl *ParkingLot) parkIn(s *ParkingSpot, v models.Vehicle, at time.Time, reservationID string) (*models.Ticket, error) {
+	t, err := s.Level.park(s, v, at)
+	if err != nil {
+		return nil, err
+	}
+	pl.free.remove(s)
+	pl.notify(s.Level, s.Size)
+	t.ReservationID = reservationID
+	return pl.tickets.issue(t), nil
+}
+
+// release empties a spot and makes it available again
+func (pl *ParkingLot) release(lvl *Level, spotID string) error {
+	s, err := lvl.unpark(spotID)
+	if err != nil {
+		return err
+	}
+	pl.free.add(s)
+	pl.notify(lvl, s.Size)
+	return nil
+}
+
+// level looks up a level by number
+func (pl *ParkingLot) level(number int) (*Level, error) {
 	for _, lvl := range pl.levels {
-		if t, err := lvl.ParkVehicle(v); err == nil {
-			return t, nil
+		if lvl.Number == number {
+			return lvl, nil
 		}
 	}
-	return nil, errors.New("parking lot full")
+	return nil, errors.New("invalid level")
 }
 
-// UnparkVehicle releases based on ticket
-func (pl *ParkingLot) UnparkVehicle(t *models.Ticket) error {
+// Capacity returns the total number of spots per size
+func (pl *ParkingLot) Capacity() map[models.SpotSize]int {
 	pl.mu.Lock()
 	defer pl.mu.Unlock()
-	idx := t.Level - 1
-	if idx < 0 || idx >= len(pl.levels) {
-		return errors.New("invalid level")
+	out := map[models.SpotSize]int{}
+	for _, lvl := range pl.levels {
+		for _, s := range lvl.spots {
+			out[s.Size]++
+		}
 	}
-	return pl.levels[idx].UnparkVehicle(t)
+	return out
 }
 
-// GetAvailability returns free‐spot counts per level/type
-func (pl *ParkingLot) GetAvailability() map[int]map[models.VehicleType]int {
+// GetAvailability returns free‐spot counts per level/size
+func (pl *ParkingLot) GetAvailability() map[int]map[models.SpotSize]int {
 	pl.mu.Lock()
 	defer pl.mu.Unlock()
 
-	out := make(map[int]map[models.VehicleType]int, len(pl.levels))
+	out := make(map[int]map[models.SpotSize]int, len(pl.levels))
 	for _, lvl := range pl.levels {
 		lvl.mu.Lock()
-		counts := make(map[models.VehicleType]int, len(lvl.available))
-		for vt, c := range lvl.available {
-			counts[vt] = c
+		counts := make(map[models.SpotSize]int, len(lvl.available))
+		for size, c := range lvl.available {
+			counts[size] = c
 		}
 		lvl.mu.Unlock()
 		out[lvl.Number] = counts
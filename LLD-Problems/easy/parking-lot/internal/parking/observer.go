@@ -0,0 +1,40 @@
+package parking
+
+import "parking-lot/internal/models"
+
+// AvailabilityObserver is told whenever the free count of a spot size on
+// a level changes (Observer Pattern). It is called with the lot locked
+// and must not call back into the lot
+type AvailabilityObserver interface {
+	AvailabilityChanged(lot string, level int, size models.SpotSize, free int)
+}
+
+// AddObserver registers an observer and sends it the current counts
+func (pl *ParkingLot) AddObserver(o AvailabilityObserver) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.observers = append(pl.observers, o)
+	for _, lvl := range pl.levels {
+		lvl.mu.Lock()
+		for _, size := range models.SpotSizes {
+			if n, ok := lvl.available[size]; ok {
+				o.AvailabilityChanged(pl.Name, lvl.Number, size, n)
+			}
+		}
+		lvl.mu.Unlock()
+	}
+}
+
+// notify tells observers the new free count of a size on a level.
+// Caller holds pl.mu
+func (pl *ParkingLot) notify(lvl *Level, size models.SpotSize) {
+	if len(pl.observers) == 0 {
+		return
+	}
+	lvl.mu.Lock()
+	n := lvl.available[size]
+	lvl.mu.Unlock()
+	for _, o := range pl.observers {
+		o.AvailabilityChanged(pl.Name, lvl.Number, size, n)
+	}
+}
@@ -0,0 +1,68 @@
+package parking
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Registry keeps several independently managed lots by name
+type Registry struct {
+	mu   sync.RWMutex
+	lots map[string]*ParkingLot
+}
+
+func NewRegistry() *Registry {
+	return &Registry{lots: map[string]*ParkingLot{}}
+}
+
+// Open builds a lot from a layout and registers it
+func (r *Registry) Open(layout Layout) (*ParkingLot, error) {
+	pl, err := NewParkingLot(layout)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.lots[pl.Name]; ok {
+		return nil, fmt.Errorf("lot %s already exists", pl.Name)
+	}
+	r.lots[pl.Name] = pl
+	return pl, nil
+}
+
+// Load reads a layout file and opens the lot it describes
+func (r *Registry) Load(path string) (*ParkingLot, error) {
+	layout, err := LoadLayout(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.Open(layout)
+}
+
+// Lot looks up a lot by name
+func (r *Registry) Lot(name string) (*ParkingLot, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	pl, ok := r.lots[name]
+	return pl, ok
+}
+
+// Close removes a lot from the registry
+func (r *Registry) Close(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.lots, name)
+}
+
+// Names lists the registered lots, sorted
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.lots))
+	for n := range r.lots {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -8,8 +8,14 @@ import (
 
 // ParkingSpot represents one spot
 type ParkingSpot struct {
-	ID       string
-	SpotType models.VehicleType
+	ID               string
+	Size             models.SpotSize
+	Level            *Level
+	Position         int // along the level's aisle, from the ramp
+	EntranceDistance int // from the lot entrance, ramps included
+	ElevatorDistance int // from the level's lift lobby
+
+	heapIdx int // position in the free-spot index; -1 while taken
 
 	mu       sync.Mutex
 	occupied bool
@@ -28,8 +34,8 @@ func (ps *ParkingSpot) Park(v models.Vehicle) error {
 	if ps.occupied {
 		return errors.New("spot occupied")
 	}
-	if v.Type() != ps.SpotType {
-		return errors.New("vehicle-type mismatch")
+	if !ps.Size.Fits(v) {
+		return errors.New("vehicle does not fit spot")
 	}
 	ps.vehicle = v
 	ps.occupied = true
@@ -0,0 +1,115 @@
+package parking
+
+import (
+	"errors"
+	"parking-lot/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownTicket = errors.New("unknown ticket")
+	ErrTicketUsed    = errors.New("ticket already used")
+	ErrPlateMismatch = errors.New("license plate does not match ticket")
+	ErrAlreadyInside = errors.New("vehicle already inside")
+)
+
+// TicketStore keeps every ticket a lot issued. Callers only ever get
+// copies, so a ticket can only be validated or closed through the store
+type TicketStore struct {
+	mu      sync.RWMutex
+	tickets map[string]*models.Ticket
+	inside  map[string]string // normalized plate → open ticket ID
+}
+
+func NewTicketStore() *TicketStore {
+	return &TicketStore{tickets: map[string]*models.Ticket{}, inside: map[string]string{}}
+}
+
+// normalizePlate makes plates read by a camera comparable to typed ones
+func normalizePlate(p string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(p))
+}
+
+// checkIn refuses a plate that already has an open ticket
+func (ts *TicketStore) checkIn(license string) error {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	if _, ok := ts.inside[normalizePlate(license)]; ok {
+		return ErrAlreadyInside
+	}
+	return nil
+}
+
+// issue records a new ticket and returns the caller's copy
+func (ts *TicketStore) issue(t *models.Ticket) *models.Ticket {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.tickets[t.ID] = t
+	ts.inside[normalizePlate(t.Vehicle.LicensePlate())] = t.ID
+	c := *t
+	return &c
+}
+
+// validate returns the open ticket with this ID if it belongs to the
+// vehicle with this plate
+func (ts *TicketStore) validate(id, license string) (*models.Ticket, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	t, ok := ts.tickets[id]
+	switch {
+	case !ok:
+		return nil, ErrUnknownTicket
+	case t.Closed():
+		return nil, ErrTicketUsed
+	case normalizePlate(t.Vehicle.LicensePlate()) != normalizePlate(license):
+		return nil, ErrPlateMismatch
+	}
+	return t, nil
+}
+
+// close marks a ticket used
+func (ts *TicketStore) close(t *models.Ticket, exit time.Time, fee models.Money) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	t.ExitTime, t.Fee = exit, fee
+	delete(ts.inside, normalizePlate(t.Vehicle.LicensePlate()))
+}
+
+// openFor returns the open ticket of a plate, if any
+func (ts *TicketStore) openFor(license string) (*models.Ticket, bool) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	id, ok := ts.inside[normalizePlate(license)]
+	if !ok {
+		return nil, false
+	}
+	return ts.tickets[id], true
+}
+
+// lookup returns the stored ticket with this ID
+func (ts *TicketStore) lookup(id string) (*models.Ticket, bool) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	t, ok := ts.tickets[id]
+	return t, ok
+}
+
+// Get returns a copy of a ticket by ID
+func (ts *TicketStore) Get(id string) (models.Ticket, bool) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	t, ok := ts.tickets[id]
+	if !ok {
+		return models.Ticket{}, false
+	}
+	return *t, true
+}
+
+// Inside returns how many vehicles hold an open ticket
+func (ts *TicketStore) Inside() int {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return len(ts.inside)
+}
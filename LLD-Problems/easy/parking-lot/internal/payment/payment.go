@@ -0,0 +1,131 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"parking-lot/internal/models"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrDeclined          = errors.New("payment declined")
+)
+
+var txnCount uint64
+
+func nextTxnID(prefix string) string {
+	return fmt.Sprintf("%s-%06d", prefix, atomic.AddUint64(&txnCount, 1))
+}
+
+// Method is a way to pay a fee (Strategy Pattern)
+type Method interface {
+	Name() string
+	// Pay charges amount for the given reference (ticket ID) and
+	// returns a transaction ID
+	Pay(amount models.Money, reference string) (string, error)
+}
+
+// Cash pays from the notes handed over and records the change due
+type Cash struct {
+	Tendered models.Money
+	Change   models.Money
+}
+
+func (c *Cash) Name() string { return "cash" }
+
+func (c *Cash) Pay(amount models.Money, reference string) (string, error) {
+	if c.Tendered < amount {
+		return "", fmt.Errorf("%w: tendered %s, due %s", ErrInsufficientFunds, c.Tendered, amount)
+	}
+	c.Change = c.Tendered - amount
+	return nextTxnID("CASH"), nil
+}
+
+// Card charges a credit card up to its remaining limit
+type Card struct {
+	Number string
+	Limit  models.Money
+
+	mu    sync.Mutex
+	spent models.Money
+}
+
+func (c *Card) Name() string { return "card " + maskCard(c.Number) }
+
+func (c *Card) Pay(amount models.Money, reference string) (string, error) {
+	if !luhnValid(c.Number) {
+		return "", fmt.Errorf("%w: invalid card number", ErrDeclined)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.spent+amount > c.Limit {
+		return "", fmt.Errorf("%w: over limit", ErrDeclined)
+	}
+	c.spent += amount
+	return nextTxnID("CARD"), nil
+}
+
+// Wallet pays from a prepaid balance
+type Wallet struct {
+	ID string
+
+	mu      sync.Mutex
+	balance models.Money
+}
+
+// NewWallet creates a wallet with an opening balance (Factory Pattern)
+func NewWallet(id string, balance models.Money) *Wallet {
+	return &Wallet{ID: id, balance: balance}
+}
+
+func (w *Wallet) Name() string { return "wallet " + w.ID }
+
+func (w *Wallet) Pay(amount models.Money, reference string) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.balance < amount {
+		return "", fmt.Errorf("%w: balance %s, due %s", ErrInsufficientFunds, w.balance, amount)
+	}
+	w.balance -= amount
+	return nextTxnID("WLT"), nil
+}
+
+// Balance returns what is left in the wallet
+func (w *Wallet) Balance() models.Money {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.balance
+}
+
+// luhnValid checks a card number's check digit
+func luhnValid(number string) bool {
+	digits := strings.ReplaceAll(number, " ", "")
+	if len(digits) < 12 {
+		return false
+	}
+	sum := 0
+	for i := range len(digits) {
+		d := int(digits[len(digits)-1-i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+func maskCard(number string) string {
+	digits := strings.ReplaceAll(number, " ", "")
+	if len(digits) < 4 {
+		return "****"
+	}
+	return "****" + digits[len(digits)-4:]
+}
@@ -0,0 +1,148 @@
+package pricing
+
+import (
+	"fmt"
+	"parking-lot/internal/models"
+	"time"
+)
+
+// Strategy prices a stay (Strategy Pattern)
+type Strategy interface {
+	// Quote prices a stay from entry to exit
+	Quote(vt models.VehicleType, entry, exit time.Time) Quote
+	// LostTicket prices an exit without a ticket for a vehicle that
+	// entered at entry
+	LostTicket(vt models.VehicleType, entry, exit time.Time) Quote
+}
+
+// Line is one item on a quote
+type Line struct {
+	Description string
+	Amount      models.Money
+}
+
+// Quote is an itemised fee
+type Quote struct {
+	Duration time.Duration
+	Lines    []Line
+	Total    models.Money
+}
+
+func (q Quote) String() string {
+	s := fmt.Sprintf("stay %s:", q.Duration.Round(time.Minute))
+	for _, l := range q.Lines {
+		s += fmt.Sprintf("\n  %-32s %8s", l.Description, l.Amount)
+	}
+	return s + fmt.Sprintf("\n  %-32s %8s", "TOTAL", q.Total)
+}
+
+// Rates are the prices for one vehicle type
+type Rates struct {
+	FirstHour  models.Money // first started hour
+	Hourly     models.Money // each later started hour on weekdays
+	Night      models.Money // hours starting in the night window
+	Weekend    models.Money // hours starting on Saturday or Sunday
+	DailyCap   models.Money // max per 24h from entry; 0 = no cap
+	LostTicket models.Money // penalty added to the stay, billed as at least one capped day
+}
+
+// Tariff is the standard hourly pricing: a free grace period, then
+// every started hour is billed at the first-hour, weekend, night or
+// weekday rate (in that order of precedence), capped per 24h block
+type Tariff struct {
+	Grace      time.Duration
+	NightStart int // hour of day the night rate starts, e.g. 22
+	NightEnd   int // hour of day it ends, e.g. 6
+	Rates      map[models.VehicleType]Rates
+}
+
+// DefaultTariff returns typical city-centre prices
+func DefaultTariff() *Tariff {
+	return &Tariff{
+		Grace:      15 * time.Minute,
+		NightStart: 22,
+		NightEnd:   6,
+		Rates: map[models.VehicleType]Rates{
+			models.MotorcycleType: {FirstHour: 150, Hourly: 100, Night: 50, Weekend: 75, DailyCap: 1000, LostTicket: 2500},
+			models.CarType:        {FirstHour: 300, Hourly: 200, Night: 100, Weekend: 150, DailyCap: 2000, LostTicket: 5000},
+			models.TruckType:      {FirstHour: 600, Hourly: 400, Night: 200, Weekend: 300, DailyCap: 4000, LostTicket: 10000},
+		},
+	}
+}
+
+// Quote implements Strategy
+func (tf *Tariff) Quote(vt models.VehicleType, entry, exit time.Time) Quote {
+	q := Quote{Duration: exit.Sub(entry)}
+	if q.Duration <= tf.Grace {
+		q.Lines = append(q.Lines, Line{fmt.Sprintf("grace period (%s)", tf.Grace), 0})
+		return q
+	}
+	r := tf.Rates[vt]
+
+	// bill each started hour, one 24h block at a time so the cap applies per day
+	counts := map[string]int{}
+	prices := map[string]models.Money{}
+	var capped models.Money
+	hour := 0
+	for dayStart := entry; dayStart.Before(exit); dayStart = dayStart.Add(24 * time.Hour) {
+		var day models.Money
+		for h := dayStart; h.Before(exit) && h.Before(dayStart.Add(24*time.Hour)); h = h.Add(time.Hour) {
+			kind, price := tf.hourRate(r, h, hour == 0)
+			counts[kind]++
+			prices[kind] = price
+			day += price
+			hour++
+		}
+		if r.DailyCap > 0 && day > r.DailyCap {
+			capped += day - r.DailyCap
+		}
+	}
+
+	for _, kind := range []string{"first hour", "weekday hours", "night hours", "weekend hours"} {
+		if n := counts[kind]; n > 0 {
+			amount := prices[kind] * models.Money(n)
+			q.Lines = append(q.Lines, Line{fmt.Sprintf("%d x %s @ %s", n, kind, prices[kind]), amount})
+			q.Total += amount
+		}
+	}
+	if capped > 0 {
+		q.Lines = append(q.Lines, Line{fmt.Sprintf("daily cap %s", r.DailyCap), -capped})
+		q.Total -= capped
+	}
+	return q
+}
+
+// hourRate picks the rate for the hour starting at h
+func (tf *Tariff) hourRate(r Rates, h time.Time, first bool) (string, models.Money) {
+	switch {
+	case first:
+		return "first hour", r.FirstHour
+	case h.Weekday() == time.Saturday || h.Weekday() == time.Sunday:
+		return "weekend hours", r.Weekend
+	case tf.isNight(h.Hour()):
+		return "night hours", r.Night
+	default:
+		return "weekday hours", r.Hourly
+	}
+}
+
+func (tf *Tariff) isNight(hour int) bool {
+	if tf.NightStart <= tf.NightEnd {
+		return hour >= tf.NightStart && hour < tf.NightEnd
+	}
+	return hour >= tf.NightStart || hour < tf.NightEnd // window wraps midnight
+}
+
+// LostTicket implements Strategy: the stay, billed as at least one
+// capped day, plus the penalty
+func (tf *Tariff) LostTicket(vt models.VehicleType, entry, exit time.Time) Quote {
+	r := tf.Rates[vt]
+	q := tf.Quote(vt, entry, exit)
+	if q.Total < r.DailyCap {
+		q.Lines = append(q.Lines, Line{"lost ticket: one full day minimum", r.DailyCap - q.Total})
+		q.Total = r.DailyCap
+	}
+	q.Lines = append(q.Lines, Line{"lost ticket penalty", r.LostTicket})
+	q.Total += r.LostTicket
+	return q
+}
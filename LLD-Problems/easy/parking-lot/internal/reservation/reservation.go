@@ -0,0 +1,334 @@
+package reservation
+
+import (
+	"errors"
+	"fmt"
+	"parking-lot/internal/models"
+	"parking-lot/internal/parking"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrNotFound    = errors.New("reservation not found")
+	ErrFullyBooked = errors.New("no capacity left for that window")
+	ErrNotPending  = errors.New("reservation is no longer pending")
+	ErrTooEarly    = errors.New("too early for this reservation")
+)
+
+var reservationCount uint64
+
+// Status of a reservation
+type Status int
+
+const (
+	Pending Status = iota
+	Arrived
+	Cancelled
+	NoShow
+)
+
+func (s Status) String() string {
+	switch s {
+	case Pending:
+		return "Pending"
+	case Arrived:
+		return "Arrived"
+	case Cancelled:
+		return "Cancelled"
+	case NoShow:
+		return "NoShow"
+	default:
+		return "Unknown"
+	}
+}
+
+// Reservation books one spot of a size for a time window
+type Reservation struct {
+	ID      string
+	License string
+	Size    models.SpotSize
+	Start   time.Time
+	End     time.Time
+	Status  Status
+	Ticket  *models.Ticket // set on arrival
+
+	arriving bool // parking in progress; still holds its spot
+}
+
+// Options tune a Service
+type Options struct {
+	// Grace is how late a driver may arrive before the booking lapses
+	Grace time.Duration
+	// HoldBefore is how long before the window starts the driver may
+	// arrive; the spot is kept from walk-ins from the moment of booking
+	HoldBefore time.Duration
+	// Quota caps how many spots of each size may be booked at once;
+	// sizes not listed may use the lot's whole capacity
+	Quota map[models.SpotSize]int
+}
+
+// DefaultOptions lets drivers arrive 30 minutes early and holds their
+// spot for 15 minutes after the booked start
+func DefaultOptions() Options {
+	return Options{Grace: 15 * time.Minute, HoldBefore: 30 * time.Minute}
+}
+
+// Service books spots in one lot and keeps walk-ins from taking the
+// capacity it has promised
+type Service struct {
+	lot  *parking.ParkingLot
+	opts Options
+
+	mu     sync.Mutex
+	byID   map[string]*Reservation
+	booked map[models.SpotSize][]*Reservation // pending, and arrived until End; by start
+}
+
+// NewService attaches a reservation service to a lot (Factory Pattern)
+func NewService(lot *parking.ParkingLot, opts Options) *Service {
+	s := &Service{
+		lot:    lot,
+		opts:   opts,
+		byID:   map[string]*Reservation{},
+		booked: map[models.SpotSize][]*Reservation{},
+	}
+	lot.SetHold(s)
+	return s
+}
+
+// Reserve books a spot of the given size for [start, end). It fails if
+// at any moment of the window every bookable spot is already promised,
+// or if the spots free now cannot cover every booking still to come
+// (walk-ins already parked may stay past the window)
+func (s *Service) Reserve(license string, size models.SpotSize, start, end time.Time) (Reservation, error) {
+	if !end.After(start) {
+		return Reservation{}, errors.New("reservation must end after it starts")
+	}
+	now := s.lot.Now()
+	if start.Add(s.opts.Grace).Before(now) {
+		return Reservation{}, errors.New("reservation window already started")
+	}
+	limit := s.lot.Capacity()[size]
+	if q, ok := s.opts.Quota[size]; ok && q < limit {
+		limit = q
+	}
+	free := 0
+	for _, counts := range s.lot.GetAvailability() {
+		free += counts[size]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(now)
+	if s.peakBooked(size, start, end) >= limit {
+		return Reservation{}, fmt.Errorf("%w: %s %s–%s", ErrFullyBooked, size,
+			start.Format("Mon 15:04"), end.Format("Mon 15:04"))
+	}
+	r := &Reservation{
+		License: license,
+		Size:    size,
+		Start:   start,
+		End:     end,
+	}
+	s.add(r)
+	if s.held(size, now) > free {
+		s.remove(r)
+		return Reservation{}, fmt.Errorf("%w: not enough %s spots free now", ErrFullyBooked, size)
+	}
+	r.ID = fmt.Sprintf("R-%04d", atomic.AddUint64(&reservationCount, 1))
+	s.byID[r.ID] = r
+	return *r, nil
+}
+
+// add inserts a reservation into its size's booked list. Caller holds s.mu
+func (s *Service) add(r *Reservation) {
+	list := append(s.booked[r.Size], r)
+	sort.Slice(list, func(i, j int) bool { return list[i].Start.Before(list[j].Start) })
+	s.booked[r.Size] = list
+}
+
+// remove drops a reservation from its size's booked list. Caller holds s.mu
+func (s *Service) remove(r *Reservation) {
+	list := s.booked[r.Size]
+	for i, p := range list {
+		if p == r {
+			s.booked[r.Size] = append(list[:i], list[i+1:]...)
+			return
+		}
+	}
+}
+
+// peakBooked returns the most pending or arrived reservations of a size
+// that overlap at any one moment of [start, end). Caller holds s.mu
+func (s *Service) peakBooked(size models.SpotSize, start, end time.Time) int {
+	type edge struct {
+		at    time.Time
+		delta int
+	}
+	var edges []edge
+	for _, r := range s.booked[size] {
+		if r.Start.Before(end) && r.End.After(start) {
+			edges = append(edges, edge{r.Start, 1}, edge{r.End, -1})
+		}
+	}
+	// at equal times process ends first: back-to-back bookings share a spot
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].at.Equal(edges[j].at) {
+			return edges[i].delta < edges[j].delta
+		}
+		return edges[i].at.Before(edges[j].at)
+	})
+	peak, n := 0, 0
+	for _, e := range edges {
+		n += e.delta
+		peak = max(peak, n)
+	}
+	return peak
+}
+
+// Cancel releases a pending reservation
+func (s *Service) Cancel(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.byID[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if r.Status != Pending || r.arriving {
+		return ErrNotPending
+	}
+	s.close(r, Cancelled)
+	return nil
+}
+
+// Arrive parks a booked vehicle in a spot of the reserved size and
+// converts the reservation into its ticket. The booking keeps its place
+// until End, so no one else is promised that spot for the rest of it
+func (s *Service) Arrive(id string, v models.Vehicle) (*models.Ticket, error) {
+	now := s.lot.Now()
+	s.mu.Lock()
+	s.expire(now)
+	r, ok := s.byID[id]
+	switch {
+	case !ok:
+		s.mu.Unlock()
+		return nil, ErrNotFound
+	case r.Status != Pending || r.arriving:
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrNotPending, r.Status)
+	case r.License != v.LicensePlate():
+		s.mu.Unlock()
+		return nil, fmt.Errorf("reservation is for %s, not %s", r.License, v.LicensePlate())
+	case now.Before(r.Start.Add(-s.opts.HoldBefore)):
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: come back after %s", ErrTooEarly, r.Start.Add(-s.opts.HoldBefore).Format("Mon 15:04"))
+	}
+	// keep holding the spot while the lot parks the vehicle; the lot
+	// calls Held, so s.mu must not be held across ParkReserved
+	r.arriving = true
+	s.mu.Unlock()
+
+	t, err := s.lot.ParkReserved(v, r.Size, r.ID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r.arriving = false
+	if err != nil {
+		return nil, err
+	}
+	r.Ticket = t
+	s.close(r, Arrived)
+	return t, nil
+}
+
+// ExpireNoShows marks reservations whose grace period has passed as
+// no-shows, releasing their spots, and returns them
+func (s *Service) ExpireNoShows() []Reservation {
+	now := s.lot.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Reservation
+	for _, r := range s.expire(now) {
+		out = append(out, *r)
+	}
+	return out
+}
+
+// expire closes pending reservations not arrived by start+grace and
+// forgets arrived ones whose window has ended. Caller holds s.mu
+func (s *Service) expire(now time.Time) []*Reservation {
+	var expired, ended []*Reservation
+	for _, list := range s.booked {
+		for _, r := range list {
+			switch {
+			case r.Status == Arrived && !r.End.After(now):
+				ended = append(ended, r)
+			case r.Status == Pending && !r.arriving && now.After(r.Start.Add(s.opts.Grace)):
+				expired = append(expired, r)
+			}
+		}
+	}
+	for _, r := range ended {
+		s.remove(r)
+	}
+	for _, r := range expired {
+		s.close(r, NoShow)
+	}
+	return expired
+}
+
+// close ends a pending reservation; an arrived one stays booked until
+// End. Caller holds s.mu
+func (s *Service) close(r *Reservation, st Status) {
+	r.Status = st
+	if st != Arrived {
+		s.remove(r)
+	}
+}
+
+// Held implements parking.CapacityHold: a reservation holds capacity
+// from the moment it is booked, since a walk-in may stay any length of
+// time. Walk-ins must leave enough spots free for the busiest moment of
+// the bookings still to come
+func (s *Service) Held(size models.SpotSize, at time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(at)
+	return s.held(size, at)
+}
+
+// held returns how many free spots the bookings from at onwards still
+// need: their peak overlap, less the arrived drivers who are parked and
+// will free their own spot when they leave. Caller holds s.mu
+func (s *Service) held(size models.SpotSize, at time.Time) int {
+	var last time.Time
+	parked := 0
+	for _, r := range s.booked[size] {
+		if r.End.After(last) {
+			last = r.End
+		}
+		if r.Status == Arrived && r.End.After(at) {
+			if t, ok := s.lot.Tickets().Get(r.Ticket.ID); ok && !t.Closed() {
+				parked++
+			}
+		}
+	}
+	if !last.After(at) {
+		return 0
+	}
+	return max(s.peakBooked(size, at, last)-parked, 0)
+}
+
+// Get looks up a reservation
+func (s *Service) Get(id string) (Reservation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.byID[id]
+	if !ok {
+		return Reservation{}, false
+	}
+	return *r, true
+}
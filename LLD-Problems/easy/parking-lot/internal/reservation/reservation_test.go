@@ -0,0 +1,71 @@
+package reservation
+
+import (
+	"errors"
+	"parking-lot/internal/models"
+	"parking-lot/internal/parking"
+	"testing"
+	"time"
+)
+
+// oneSpotLot returns a lot with a single regular spot, a booking service
+// for it and a way to set its clock
+func oneSpotLot(t *testing.T) (*parking.ParkingLot, *Service, func(h, m int) time.Time, func(time.Time)) {
+	t.Helper()
+	lot, err := parking.NewParkingLot(parking.Layout{Name: "test", Levels: []parking.LevelLayout{
+		{Number: 1, Spots: map[models.SpotSize]int{models.RegularSpot: 1}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(h, m int) time.Time { return time.Date(2025, time.June, 9, h, m, 0, 0, time.UTC) }
+	now := at(8, 0)
+	lot.SetClock(func() time.Time { return now })
+	return lot, NewService(lot, DefaultOptions()), at, func(t time.Time) { now = t }
+}
+
+func TestArrivedReservationCountsUntilEnd(t *testing.T) {
+	_, s, at, setNow := oneSpotLot(t)
+	a, err := s.Reserve("A", models.RegularSpot, at(9, 0), at(12, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setNow(at(9, 0))
+	if _, err := s.Arrive(a.ID, &models.Car{License: "A"}); err != nil {
+		t.Fatal(err)
+	}
+	setNow(at(9, 5))
+	if _, err := s.Reserve("B", models.RegularSpot, at(10, 0), at(11, 0)); !errors.Is(err, ErrFullyBooked) {
+		t.Fatalf("Reserve overlapping an arrived booking: err = %v, want ErrFullyBooked", err)
+	}
+	if _, err := s.Reserve("C", models.RegularSpot, at(12, 0), at(13, 0)); err != nil {
+		t.Fatalf("Reserve after the arrived booking ends: %v", err)
+	}
+}
+
+func TestWalkInCannotTakeBookedSpot(t *testing.T) {
+	lot, s, at, setNow := oneSpotLot(t)
+	setNow(at(7, 0))
+	r, err := s.Reserve("R", models.RegularSpot, at(10, 0), at(11, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setNow(at(8, 0))
+	if _, err := lot.ParkVehicle(&models.Car{License: "WALK"}); !errors.Is(err, parking.ErrReservedFull) {
+		t.Fatalf("walk-in before the hold window: err = %v, want ErrReservedFull", err)
+	}
+	setNow(at(10, 0))
+	if _, err := s.Arrive(r.ID, &models.Car{License: "R"}); err != nil {
+		t.Fatalf("Arrive: %v", err)
+	}
+}
+
+func TestBookingNeedsFreeSpot(t *testing.T) {
+	lot, s, at, _ := oneSpotLot(t)
+	if _, err := lot.ParkVehicle(&models.Car{License: "WALK"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Reserve("R", models.RegularSpot, at(10, 0), at(11, 0)); !errors.Is(err, ErrFullyBooked) {
+		t.Fatalf("Reserve while a walk-in holds the only spot: err = %v, want ErrFullyBooked", err)
+	}
+}
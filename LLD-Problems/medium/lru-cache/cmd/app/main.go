@@ -1,8 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"lru-cache/internal/lrucache"
@@ -71,4 +79,149 @@ func main() {
 	}()
 
 	wg.Wait()
+
+	demoReadThrough()
+	demoWeighted()
+	demoSnapshot()
+	demoStats()
+}
+
+// demoReadThrough shows GetOrLoad: deduplicated loads, negative caching
+// and stale-while-revalidate.
+func demoReadThrough() {
+	fmt.Println("\n--- Read-through loading ---")
+	cache := lrucache.NewWithOptions(lrucache.Options{
+		Capacity:    10,
+		SoftTTL:     50 * time.Millisecond,
+		NegativeTTL: time.Second,
+	})
+
+	var calls atomic.Int32
+	loader := func(ctx context.Context, key interface{}) (interface{}, error) {
+		n := calls.Add(1)
+		time.Sleep(20 * time.Millisecond) // simulate a slow backend
+		if key == "missing" {
+			return nil, errors.New("not found in backend")
+		}
+		return fmt.Sprintf("%v@v%d", key, n), nil
+	}
+	ctx := context.Background()
+
+	// 5 concurrent misses for the same key → 1 backend call
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.GetOrLoad(ctx, "user:1", loader)
+		}()
+	}
+	wg.Wait()
+	fmt.Println("Backend calls after 5 concurrent misses:", calls.Load())
+
+	// Errors are cached for NegativeTTL
+	_, err1 := cache.GetOrLoad(ctx, "missing", loader)
+	_, err2 := cache.GetOrLoad(ctx, "missing", loader)
+	fmt.Println("Negative lookups:", err1, "/", err2, "| backend calls:", calls.Load())
+
+	// After SoftTTL the stale value is served while a refresh runs
+	time.Sleep(60 * time.Millisecond)
+	v, _ := cache.GetOrLoad(ctx, "user:1", loader)
+	fmt.Println("Served while refreshing:", v)
+	time.Sleep(40 * time.Millisecond)
+	v, _ = cache.GetOrLoad(ctx, "user:1", loader)
+	fmt.Println("After refresh:", v)
+}
+
+// demoWeighted shows cost-based capacity: values are weighed by size.
+func demoWeighted() {
+	fmt.Println("\n--- Weight-based capacity ---")
+	cache := lrucache.NewWithOptions(lrucache.Options{
+		MaxWeight: 10, // bytes
+		Weigher: func(key, value interface{}) int64 {
+			return int64(len(value.(string)))
+		},
+	})
+
+	cache.Put("a", "aaaa")   // weight 4
+	cache.Put("b", "bbbb")   // weight 8
+	cache.Put("c", "cccccc") // weight 14 > 10 → evicts LRU "a"
+	_, okA := cache.Get("a")
+	fmt.Println("a cached:", okA, "| total weight:", cache.Weight())
+
+	err := cache.PutWithCost("huge", "too big", 64)
+	fmt.Println("Put huge:", err)
+}
+
+// demoSnapshot shows a warm restart: save to a file, load into a new cache.
+func demoSnapshot() {
+	fmt.Println("\n--- Snapshot & warm restart ---")
+	path := filepath.Join(os.TempDir(), "lru-demo.snapshot")
+	defer os.Remove(path)
+
+	cache := lrucache.NewWithOptions(lrucache.Options{Capacity: 3, TTL: time.Hour})
+	snapshots, err := cache.StartSnapshots(path, time.Minute)
+	if err != nil {
+		fmt.Println("Snapshot error:", err)
+		return
+	}
+	cache.Put("x", 1)
+	cache.Put("y", 2)
+	cache.Put("z", 3)
+	cache.Get("x") // recency order is now x, z, y
+
+	// Stop writes a final snapshot, as a service would on shutdown
+	if err := snapshots.Stop(); err != nil {
+		fmt.Println("Snapshot error:", err)
+		return
+	}
+
+	// "Restart": a new cache loads the snapshot with the same LRU order
+	restored := lrucache.NewWithOptions(lrucache.Options{Capacity: 3, TTL: time.Hour})
+	if err := restored.LoadFile(path); err != nil {
+		fmt.Println("Load error:", err)
+		return
+	}
+	restored.Put("w", 4) // evicts LRU "y", as the original would have
+	_, okY := restored.Get("y")
+	_, okX := restored.Get("x")
+	fmt.Println("Restored entries:", restored.Len(), "| y evicted:", !okY, "| x kept:", okX)
+}
+
+// demoStats shows cache counters and the HTTP admin endpoint.
+func demoStats() {
+	fmt.Println("\n--- Statistics & admin endpoint ---")
+	cache := lrucache.New(2)
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+	cache.Get("a")    // hit
+	cache.Get("nope") // miss
+	cache.Put("c", 3) // evicts "b"
+
+	s := cache.Stats()
+	fmt.Printf("hits=%d misses=%d evictions=%d hit ratio=%.2f\n",
+		s.Hits, s.Misses, s.Evictions, s.HitRatio())
+
+	srv := httptest.NewServer(http.StripPrefix("/admin", lrucache.NewAdminHandler(cache)))
+	defer srv.Close()
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/admin/keys/a"},
+		{http.MethodDelete, "/admin/keys/a"},
+		{http.MethodGet, "/admin/keys/a"},
+		{http.MethodGet, "/admin/stats"},
+	} {
+		r, _ := http.NewRequest(req.method, srv.URL+req.path, nil)
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			fmt.Println("Admin request error:", err)
+			return
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		fmt.Printf("%s %s → %d %s", req.method, req.path, resp.StatusCode, body)
+		if resp.StatusCode == http.StatusNoContent {
+			fmt.Println()
+		}
+	}
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"testing"
+	"text/tabwriter"
+
+	"lru-cache/internal/lrucache"
+)
+
+// candidate is one cache configuration under benchmark.
+type candidate struct {
+	name string
+	new  func(capacity int) lrucache.Policy
+}
+
+func main() {
+	capacity := flag.Int("capacity", 10000, "cache capacity in entries")
+	keys := flag.Int("keys", 20000, "size of the key space")
+	readPct := flag.Int("reads", 90, "percentage of operations that are Gets")
+	flag.Parse()
+
+	candidates := []candidate{
+		{"LRUCache (single lock)", func(n int) lrucache.Policy { return lrucache.New(n) }},
+		{"Sharded strict", func(n int) lrucache.Policy {
+			return lrucache.NewSharded(n, lrucache.ShardOptions{Recency: lrucache.RecencyStrict})
+		}},
+		{"Sharded sampled", func(n int) lrucache.Policy {
+			return lrucache.NewSharded(n, lrucache.ShardOptions{Recency: lrucache.RecencySampled})
+		}},
+		{"Sharded buffered", func(n int) lrucache.Policy {
+			return lrucache.NewSharded(n, lrucache.ShardOptions{Recency: lrucache.RecencyBuffered})
+		}},
+	}
+
+	// Pre-generate Zipf-distributed keys so the benchmark loop measures
+	// the cache, not the random number generator.
+	rng := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(rng, 1.01, 1, uint64(*keys-1))
+	workload := make([]string, 1<<16)
+	for i := range workload {
+		workload[i] = "key-" + strconv.FormatUint(zipf.Uint64(), 10)
+	}
+
+	fmt.Printf("GOMAXPROCS=%d capacity=%d keys=%d reads=%d%%\n\n",
+		runtime.GOMAXPROCS(0), *capacity, *keys, *readPct)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "cache\tns/op\tMops/s\t")
+	for _, c := range candidates {
+		res := testing.Benchmark(parallel(c.new(*capacity), workload, *readPct))
+		nsPerOp := float64(res.T.Nanoseconds()) / float64(res.N)
+		fmt.Fprintf(tw, "%s\t%.1f\t%.2f\t\n", c.name, nsPerOp, 1e3/nsPerOp)
+	}
+	tw.Flush()
+}
+
+// parallel returns a benchmark that hammers cache from every P with a
+// read/write mix drawn from workload.
+func parallel(cache lrucache.Policy, workload []string, readPct int) func(b *testing.B) {
+	return func(b *testing.B) {
+		for _, k := range workload {
+			cache.Put(k, k)
+		}
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			i := rand.Intn(len(workload))
+			for pb.Next() {
+				k := workload[i%len(workload)]
+				if i%100 < readPct {
+					cache.Get(k)
+				} else {
+					cache.Put(k, k)
+				}
+				i++
+			}
+		})
+	}
+}
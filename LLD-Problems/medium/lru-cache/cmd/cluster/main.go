@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	"lru-cache/internal/cluster"
+	"lru-cache/internal/memcache"
+)
+
+func main() {
+	// Start four in-process cache servers on ephemeral ports
+	var nodes []cluster.Node
+	for i := 0; i < 4; i++ {
+		nodes = append(nodes, cluster.Node{Addr: startServer(), Weight: 1})
+	}
+	// Give the last node twice the capacity (and share of keys)
+	nodes[3].Weight = 2
+
+	keys := make([]string, 20000)
+	for i := range keys {
+		keys[i] = "user:" + strconv.Itoa(i)
+	}
+
+	fmt.Println("--- Key distribution (weights 1,1,1,2) ---")
+	for _, p := range []struct {
+		name   string
+		picker cluster.Picker
+	}{
+		{"hash ring (160 vnodes)", cluster.NewHashRing(160, nodes...)},
+		{"hash ring (4 vnodes)", cluster.NewHashRing(4, nodes...)},
+		{"rendezvous", cluster.NewRendezvous(nodes...)},
+	} {
+		s := cluster.Skew(p.picker, keys)
+		fmt.Printf("%-24s share/expected min=%.2f max=%.2f stddev=%.3f\n", p.name, s.MinRatio, s.MaxRatio, s.StdDev)
+	}
+
+	// Route real traffic through the cluster client
+	client := cluster.NewClient(cluster.NewHashRing(160), time.Second, nodes...)
+	defer client.Close()
+	for _, k := range keys[:1000] {
+		if err := client.Set(k, &memcache.Item{Data: []byte("v-" + k)}, 0); err != nil {
+			log.Fatal(err)
+		}
+	}
+	it, err := client.Get("user:42")
+	if err != nil {
+		log.Fatal(err)
+	}
+	owner, _ := client.NodeFor("user:42")
+	fmt.Printf("\nuser:42 = %s (stored on %s)\n", it.Data, owner)
+
+	// Membership changes move only the keys of the affected node
+	fmt.Println("\n--- Membership changes ---")
+	before := cluster.Owners(client.Picker(), keys)
+	added := cluster.Node{Addr: startServer(), Weight: 1}
+	client.AddNode(added)
+	afterAdd := cluster.Owners(client.Picker(), keys)
+	fmt.Printf("Add %s: %.1f%% of keys moved (ideal %.1f%%)\n", added.Addr, 100*cluster.Moved(before, afterAdd), 100.0/6)
+
+	client.RemoveNode(nodes[0].Addr)
+	afterRemove := cluster.Owners(client.Picker(), keys)
+	fmt.Printf("Remove %s: %.1f%% of keys moved (ideal %.1f%%)\n", nodes[0].Addr, 100*cluster.Moved(afterAdd, afterRemove), 100.0/6)
+
+	misses := 0
+	for _, k := range keys[:1000] {
+		if _, err := client.Get(k); err == memcache.ErrCacheMiss {
+			misses++
+		}
+	}
+	fmt.Printf("Reads after both changes: %d/1000 misses (moved keys refill on demand)\n", misses)
+}
+
+// startServer runs a memcache server on a random local port.
+func startServer() string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	srv := memcache.NewServer(memcache.NewCache(16<<20), 0)
+	go srv.Serve(ln)
+	return ln.Addr().String()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"lru-cache/internal/memcache"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:11211", "TCP address to listen on")
+	maxMB := flag.Int64("m", 64, "cache memory limit in megabytes (key+data bytes)")
+	maxItem := flag.Int64("I", 1<<20, "maximum item size in bytes")
+	flag.Parse()
+
+	// Byte-weighted LRU cache (Factory Pattern)
+	cache := memcache.NewCache(*maxMB << 20)
+	srv := memcache.NewServer(cache, *maxItem)
+
+	// Shut down cleanly on Ctrl-C / SIGTERM
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		srv.Close()
+	}()
+
+	log.Printf("memcached-compatible LRU cache listening on %s (%d MB)", *addr, *maxMB)
+	if err := srv.ListenAndServe(*addr); err != nil {
+		log.Fatal(err)
+	}
+}
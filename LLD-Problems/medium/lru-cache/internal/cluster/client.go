@@ -0,0 +1,121 @@
+package cluster
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"lru-cache/internal/memcache"
+)
+
+// ErrNoNodes is returned when the cluster has no members.
+var ErrNoNodes = errors.New("cluster: no nodes available")
+
+// Client routes each key to one node of a cluster of memcached-protocol
+// cache servers using a Picker, keeping one connection per node.
+// (Facade Pattern)
+type Client struct {
+	picker  Picker
+	timeout time.Duration
+
+	mu      sync.RWMutex // guards clients
+	clients map[string]*memcache.Client
+}
+
+// NewClient builds a cluster client. nodes are added to picker.
+// (Factory Pattern)
+func NewClient(picker Picker, timeout time.Duration, nodes ...Node) *Client {
+	c := &Client{
+		picker:  picker,
+		timeout: timeout,
+		clients: make(map[string]*memcache.Client),
+	}
+	for _, n := range nodes {
+		c.AddNode(n)
+	}
+	return c
+}
+
+// AddNode joins n to the cluster. Only keys on the arcs it takes over
+// change owner; they will miss once and be re-filled on the new node.
+func (c *Client) AddNode(n Node) {
+	c.mu.Lock()
+	if _, ok := c.clients[n.Addr]; !ok {
+		c.clients[n.Addr] = memcache.NewClient(n.Addr, c.timeout)
+	}
+	c.mu.Unlock()
+	c.picker.Add(n)
+}
+
+// RemoveNode takes addr out of the cluster and closes its connection.
+func (c *Client) RemoveNode(addr string) {
+	c.picker.Remove(addr)
+	c.mu.Lock()
+	mc := c.clients[addr]
+	delete(c.clients, addr)
+	c.mu.Unlock()
+	if mc != nil {
+		mc.Close()
+	}
+}
+
+// NodeFor returns the address that owns key.
+func (c *Client) NodeFor(key string) (string, bool) {
+	return c.picker.Pick(key)
+}
+
+// Picker exposes the placement strategy (e.g. for Skew reports).
+func (c *Client) Picker() Picker { return c.picker }
+
+// Get fetches key from its owner node (memcache.ErrCacheMiss if absent).
+func (c *Client) Get(key string) (*memcache.Item, error) {
+	mc, err := c.clientFor(key)
+	if err != nil {
+		return nil, err
+	}
+	it, _, err := mc.Get(key)
+	return it, err
+}
+
+// Set stores key on its owner node.
+func (c *Client) Set(key string, it *memcache.Item, exptime int64) error {
+	mc, err := c.clientFor(key)
+	if err != nil {
+		return err
+	}
+	return mc.Set(key, it, exptime)
+}
+
+// Delete removes key from its owner node.
+func (c *Client) Delete(key string) error {
+	mc, err := c.clientFor(key)
+	if err != nil {
+		return err
+	}
+	return mc.Delete(key)
+}
+
+// Close closes every node connection.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for _, mc := range c.clients {
+		errs = append(errs, mc.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Client) clientFor(key string) (*memcache.Client, error) {
+	addr, ok := c.picker.Pick(key)
+	if !ok {
+		return nil, ErrNoNodes
+	}
+	c.mu.RLock()
+	mc := c.clients[addr]
+	c.mu.RUnlock()
+	if mc == nil {
+		return nil, ErrNoNodes
+	}
+	return mc, nil
+}
@@ -0,0 +1,37 @@
+package cluster
+
+import "hash/fnv"
+
+// Node is one cache server in the cluster.
+type Node struct {
+	Addr   string
+	Weight int // relative share of keys; values < 1 count as 1
+}
+
+// Picker maps keys to node addresses.
+// (Strategy Pattern: HashRing or Rendezvous)
+type Picker interface {
+	Add(n Node)
+	Remove(addr string)
+	Pick(key string) (addr string, ok bool)
+	Nodes() []Node
+}
+
+// hash64 is a stable (cross-process) 64-bit hash: FNV-1a followed by a
+// splitmix64 finalizer, since FNV alone mixes similar strings poorly.
+func hash64(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// weightOf normalizes a node weight.
+func weightOf(n Node) int {
+	return max(n.Weight, 1)
+}
@@ -0,0 +1,66 @@
+package cluster
+
+import (
+	"math"
+	"sync"
+)
+
+// Rendezvous implements weighted highest-random-weight hashing: every
+// node scores each key and the highest score wins. It needs no ring or
+// virtual nodes and moves the minimum number of keys on membership
+// changes, at the cost of O(nodes) per lookup.
+type Rendezvous struct {
+	mu    sync.RWMutex
+	nodes map[string]Node
+}
+
+// NewRendezvous builds a Rendezvous picker.
+// (Factory Pattern)
+func NewRendezvous(nodes ...Node) *Rendezvous {
+	r := &Rendezvous{nodes: make(map[string]Node)}
+	for _, n := range nodes {
+		r.Add(n)
+	}
+	return r
+}
+
+// Add adds (or re-weights) n.
+func (r *Rendezvous) Add(n Node) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nodes[n.Addr] = n
+}
+
+// Remove drops addr.
+func (r *Rendezvous) Remove(addr string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.nodes, addr)
+}
+
+// Pick returns the highest-scoring node for key. O(nodes).
+func (r *Rendezvous) Pick(key string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var (
+		best      string
+		bestScore = math.Inf(-1)
+	)
+	for addr, n := range r.nodes {
+		// Map the hash to (0,1) and use -w/ln(u): a node with twice the
+		// weight wins twice as many keys.
+		u := (float64(hash64(addr+"\x00"+key)>>11) + 0.5) / (1 << 53)
+		score := -float64(weightOf(n)) / math.Log(u)
+		if score > bestScore || (score == bestScore && addr < best) {
+			best, bestScore = addr, score
+		}
+	}
+	return best, best != ""
+}
+
+// Nodes returns the current members.
+func (r *Rendezvous) Nodes() []Node {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return collectNodes(r.nodes)
+}
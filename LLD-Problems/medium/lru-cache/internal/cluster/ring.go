@@ -0,0 +1,111 @@
+package cluster
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HashRing is a consistent hash ring with virtual nodes. Each node owns
+// replicas*weight points on the ring; a key belongs to the first point
+// clockwise from its hash. Adding or removing a node only moves the keys
+// of the arcs it gains or loses (about 1/N of them).
+type HashRing struct {
+	replicas int // virtual nodes per unit of weight
+
+	mu     sync.RWMutex
+	points []uint64          // sorted
+	owners map[uint64]string // point → node address
+	nodes  map[string]Node
+}
+
+// NewHashRing builds a ring with replicas virtual nodes per unit of
+// weight (160 is a common choice; more points means less skew).
+// (Factory Pattern)
+func NewHashRing(replicas int, nodes ...Node) *HashRing {
+	r := &HashRing{
+		replicas: max(replicas, 1),
+		owners:   make(map[uint64]string),
+		nodes:    make(map[string]Node),
+	}
+	for _, n := range nodes {
+		r.Add(n)
+	}
+	return r
+}
+
+// Add places n's virtual nodes on the ring (replacing n if present).
+func (r *HashRing) Add(n Node) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.nodes[n.Addr]; ok {
+		r.remove(n.Addr)
+	}
+	r.nodes[n.Addr] = n
+	for i := 0; i < r.replicas*weightOf(n); i++ {
+		p := hash64(n.Addr + "#" + strconv.Itoa(i))
+		if _, taken := r.owners[p]; taken {
+			continue // astronomically rare collision: first owner keeps it
+		}
+		r.owners[p] = n.Addr
+		r.points = append(r.points, p)
+	}
+	slices.Sort(r.points)
+}
+
+// Remove takes addr's virtual nodes off the ring.
+func (r *HashRing) Remove(addr string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.remove(addr)
+}
+
+func (r *HashRing) remove(addr string) {
+	if _, ok := r.nodes[addr]; !ok {
+		return
+	}
+	delete(r.nodes, addr)
+	kept := r.points[:0]
+	for _, p := range r.points {
+		if r.owners[p] == addr {
+			delete(r.owners, p)
+			continue
+		}
+		kept = append(kept, p)
+	}
+	r.points = kept
+}
+
+// Pick returns the node owning key. O(log points).
+func (r *HashRing) Pick(key string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.points) == 0 {
+		return "", false
+	}
+	h := hash64(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0 // wrap around
+	}
+	return r.owners[r.points[i]], true
+}
+
+// Nodes returns the current members.
+func (r *HashRing) Nodes() []Node {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return collectNodes(r.nodes)
+}
+
+// collectNodes returns nodes sorted by address.
+func collectNodes(m map[string]Node) []Node {
+	out := make([]Node, 0, len(m))
+	for _, n := range m {
+		out = append(out, n)
+	}
+	slices.SortFunc(out, func(a, b Node) int { return strings.Compare(a.Addr, b.Addr) })
+	return out
+}
@@ -0,0 +1,79 @@
+package cluster
+
+import "math"
+
+// Distribution counts how many of keys each node owns.
+func Distribution(p Picker, keys []string) map[string]int {
+	counts := make(map[string]int)
+	for _, n := range p.Nodes() {
+		counts[n.Addr] = 0
+	}
+	for _, k := range keys {
+		if addr, ok := p.Pick(k); ok {
+			counts[addr]++
+		}
+	}
+	return counts
+}
+
+// SkewReport compares each node's actual share of keys with the share
+// its weight entitles it to. A Ratio of 1.0 is a perfect fit.
+type SkewReport struct {
+	Keys     int
+	MinRatio float64 // lowest actual/expected across nodes
+	MaxRatio float64 // highest actual/expected across nodes
+	StdDev   float64 // standard deviation of actual/expected
+}
+
+// Skew measures how evenly p spreads keys, accounting for weights.
+func Skew(p Picker, keys []string) SkewReport {
+	nodes := p.Nodes()
+	counts := Distribution(p, keys)
+	rep := SkewReport{Keys: len(keys)}
+	if len(nodes) == 0 || len(keys) == 0 {
+		return rep
+	}
+	totalWeight := 0
+	for _, n := range nodes {
+		totalWeight += weightOf(n)
+	}
+
+	rep.MinRatio = math.Inf(1)
+	var sum, sumSq float64
+	for _, n := range nodes {
+		expected := float64(len(keys)) * float64(weightOf(n)) / float64(totalWeight)
+		ratio := float64(counts[n.Addr]) / expected
+		rep.MinRatio = min(rep.MinRatio, ratio)
+		rep.MaxRatio = max(rep.MaxRatio, ratio)
+		sum += ratio
+		sumSq += ratio * ratio
+	}
+	mean := sum / float64(len(nodes))
+	rep.StdDev = math.Sqrt(max(sumSq/float64(len(nodes))-mean*mean, 0))
+	return rep
+}
+
+// Owners records the node owning each key, for comparing before/after
+// a membership change with Moved.
+func Owners(p Picker, keys []string) map[string]string {
+	out := make(map[string]string, len(keys))
+	for _, k := range keys {
+		out[k], _ = p.Pick(k)
+	}
+	return out
+}
+
+// Moved returns the fraction of keys whose owner differs between two
+// Owners snapshots.
+func Moved(before, after map[string]string) float64 {
+	if len(before) == 0 {
+		return 0
+	}
+	moved := 0
+	for k, owner := range before {
+		if after[k] != owner {
+			moved++
+		}
+	}
+	return float64(moved) / float64(len(before))
+}
@@ -0,0 +1,86 @@
+package lrucache
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// statsResponse adds derived ratios to Stats for the admin endpoint.
+type statsResponse struct {
+	Stats
+	HitRatio           float64 `json:"hit_ratio"`
+	AverageLoadPenalty string  `json:"average_load_penalty"`
+}
+
+// keyResponse describes one cached key for the admin endpoint.
+type keyResponse struct {
+	Key       string      `json:"key"`
+	Value     interface{} `json:"value,omitempty"`
+	Error     string      `json:"error,omitempty"` // cached load failure
+	ExpiresAt *time.Time  `json:"expires_at,omitempty"`
+	RefreshAt *time.Time  `json:"refresh_at,omitempty"`
+	Cost      int64       `json:"cost"`
+}
+
+// NewAdminHandler returns an HTTP handler for operating c:
+//
+//	GET    /stats       counters as JSON
+//	GET    /keys/{key}  inspect one key (404 if absent)
+//	DELETE /keys/{key}  invalidate one key (404 if absent)
+//
+// Keys in the URL are matched as strings. Mount it under a prefix with
+// http.StripPrefix; it has no authentication of its own.
+func NewAdminHandler(c *LRUCache) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		s := c.Stats()
+		writeJSON(w, http.StatusOK, statsResponse{
+			Stats:              s,
+			HitRatio:           s.HitRatio(),
+			AverageLoadPenalty: s.AverageLoadPenalty().String(),
+		})
+	})
+
+	mux.HandleFunc("GET /keys/{key}", func(w http.ResponseWriter, r *http.Request) {
+		key := r.PathValue("key")
+		info, ok := c.Inspect(key)
+		if !ok {
+			http.Error(w, "key not found", http.StatusNotFound)
+			return
+		}
+		resp := keyResponse{Key: key, Value: info.Value, Cost: info.Cost}
+		if info.Err != nil {
+			resp.Error = info.Err.Error()
+		}
+		if !info.ExpiresAt.IsZero() {
+			resp.ExpiresAt = &info.ExpiresAt
+		}
+		if !info.RefreshAt.IsZero() {
+			resp.RefreshAt = &info.RefreshAt
+		}
+		if _, err := json.Marshal(resp.Value); err != nil {
+			resp.Value = fmt.Sprintf("%v", resp.Value) // not JSON-encodable
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+
+	mux.HandleFunc("DELETE /keys/{key}", func(w http.ResponseWriter, r *http.Request) {
+		if !c.Remove(r.PathValue("key")) {
+			http.Error(w, "key not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
+
+// writeJSON encodes v as the response body.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
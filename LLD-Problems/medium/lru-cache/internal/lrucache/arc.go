@@ -0,0 +1,155 @@
+package lrucache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// ARC list identifiers.
+const (
+	arcT1 = iota // resident, seen once recently
+	arcT2        // resident, seen at least twice
+	arcB1        // ghost keys evicted from T1
+	arcB2        // ghost keys evicted from T2
+)
+
+// arcEntry lives in exactly one of the four ARC lists.
+// Ghost entries (B1/B2) keep only the key.
+type arcEntry struct {
+	key   interface{}
+	value interface{}
+	where int
+}
+
+// ARCCache is a thread-safe Adaptive Replacement Cache (Megiddo & Modha).
+// It balances a recency list (T1) against a frequency list (T2) and uses
+// ghost lists (B1/B2) of recently evicted keys to tune the split target p,
+// which makes it resistant to one-off scans.
+type ARCCache struct {
+	capacity int
+	p        int // target size of T1
+	lists    [4]*list.List
+	items    map[interface{}]*list.Element
+	mu       sync.Mutex
+}
+
+// NewARC constructs an ARCCache with the given capacity.
+// (Factory Pattern)
+func NewARC(capacity int) *ARCCache {
+	c := &ARCCache{
+		capacity: capacity,
+		items:    make(map[interface{}]*list.Element, 2*capacity),
+	}
+	for i := range c.lists {
+		c.lists[i] = list.New()
+	}
+	return c
+}
+
+// Get returns the value for a resident key and promotes it to T2.
+// O(1) time.
+func (c *ARCCache) Get(key interface{}) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*arcEntry)
+	if e.where != arcT1 && e.where != arcT2 {
+		return nil, false // ghost hit is still a miss
+	}
+	c.move(elem, arcT2)
+	return e.value, true
+}
+
+// Put inserts or updates key, adapting p when key is a ghost.
+// O(1) time.
+func (c *ARCCache) Put(key, value interface{}) {
+	if c.capacity <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t1, t2, b1, b2 := c.lists[arcT1], c.lists[arcT2], c.lists[arcB1], c.lists[arcB2]
+
+	if elem, ok := c.items[key]; ok {
+		e := elem.Value.(*arcEntry)
+		switch e.where {
+		case arcT1, arcT2:
+			e.value = value
+			c.move(elem, arcT2)
+			return
+		case arcB1:
+			c.p = min(c.capacity, c.p+max(b2.Len()/b1.Len(), 1))
+			c.replace(false)
+		case arcB2:
+			c.p = max(0, c.p-max(b1.Len()/b2.Len(), 1))
+			c.replace(true)
+		}
+		e.value = value
+		c.move(elem, arcT2)
+		return
+	}
+
+	// Brand-new key.
+	switch l1 := t1.Len() + b1.Len(); {
+	case l1 == c.capacity:
+		if t1.Len() < c.capacity {
+			c.drop(b1.Back())
+			c.replace(false)
+		} else {
+			c.drop(t1.Back())
+		}
+	case l1 < c.capacity && l1+t2.Len()+b2.Len() >= c.capacity:
+		if l1+t2.Len()+b2.Len() >= 2*c.capacity {
+			c.drop(b2.Back())
+		}
+		c.replace(false)
+	}
+
+	c.items[key] = t1.PushFront(&arcEntry{key: key, value: value, where: arcT1})
+}
+
+// Len reports the number of resident (non-ghost) entries.
+func (c *ARCCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lists[arcT1].Len() + c.lists[arcT2].Len()
+}
+
+// replace demotes one resident entry to its ghost list, choosing T1 or
+// T2 according to the target p. inB2 reports whether the key that caused
+// the replacement was found in B2.
+func (c *ARCCache) replace(inB2 bool) {
+	t1, t2 := c.lists[arcT1], c.lists[arcT2]
+	if t1.Len() > 0 && (t1.Len() > c.p || (inB2 && t1.Len() == c.p) || t2.Len() == 0) {
+		c.move(t1.Back(), arcB1)
+	} else if t2.Len() > 0 {
+		c.move(t2.Back(), arcB2)
+	}
+}
+
+// move relocates elem to the front of list `to`, dropping the value of
+// entries that become ghosts.
+func (c *ARCCache) move(elem *list.Element, to int) {
+	e := elem.Value.(*arcEntry)
+	c.lists[e.where].Remove(elem)
+	e.where = to
+	if to == arcB1 || to == arcB2 {
+		e.value = nil
+	}
+	c.items[e.key] = c.lists[to].PushFront(e)
+}
+
+// drop forgets elem entirely.
+func (c *ARCCache) drop(elem *list.Element) {
+	if elem == nil {
+		return
+	}
+	e := elem.Value.(*arcEntry)
+	c.lists[e.where].Remove(elem)
+	delete(c.items, e.key)
+}
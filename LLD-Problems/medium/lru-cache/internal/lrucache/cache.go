@@ -2,15 +2,84 @@ package lrucache
 
 import (
 	"container/list"
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // entry holds a key/value pair for the doubly-linked list.
 type entry struct {
-	key   interface{}
-	value interface{}
+	key       interface{}
+	value     interface{}
+	err       error     // non-nil for a cached loader failure (negative entry)
+	expiresAt time.Time // hard expiry; zero means never
+	refreshAt time.Time // soft expiry for GetOrLoad; zero means never
+	cost      int64     // weight counted against Options.MaxWeight
+	version   uint64    // changes on every write; used as a CAS token
 }
 
+// expired reports whether e is past its hard TTL.
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// stale reports whether e is past its soft TTL and should be refreshed.
+func (e *entry) stale(now time.Time) bool {
+	return !e.refreshAt.IsZero() && now.After(e.refreshAt)
+}
+
+// Options configures an LRUCache built by NewWithOptions.
+type Options struct {
+	// Capacity is the maximum number of entries.
+	Capacity int
+	// TTL is the hard expiry of entries written by Put and GetOrLoad.
+	// Zero means entries never expire.
+	TTL time.Duration
+	// SoftTTL is the age after which GetOrLoad still returns the cached
+	// value but refreshes it in the background (stale-while-revalidate).
+	// Zero disables background refresh.
+	SoftTTL time.Duration
+	// NegativeTTL is how long GetOrLoad caches a loader error.
+	// Zero means errors are not cached.
+	NegativeTTL time.Duration
+	// MaxWeight enables cost-based capacity: entries are evicted until
+	// the total cost of all entries fits. When set, Capacity may be 0 to
+	// bound the cache by weight alone.
+	MaxWeight int64
+	// Weigher computes an entry's cost when none is given explicitly.
+	// Defaults to a cost of 1 per entry.
+	Weigher func(key, value interface{}) int64
+	// Codec serializes snapshots for SaveTo/LoadFrom.
+	// Defaults to GobCodec.
+	Codec Codec
+}
+
+// ErrEntryTooLarge is returned when a single entry's cost exceeds
+// Options.MaxWeight; such entries are never cached.
+var ErrEntryTooLarge = errors.New("lrucache: entry cost exceeds MaxWeight")
+
+// ErrInvalidCost is returned when Options.Weigher gives an entry a cost
+// below 1; such entries are never cached.
+var ErrInvalidCost = errors.New("lrucache: Weigher returned a non-positive cost")
+
+// RecencyMode controls how a Get hit updates LRU order.
+type RecencyMode int
+
+const (
+	// RecencyStrict moves the entry to the front on every hit.
+	// Every Get takes the exclusive lock.
+	RecencyStrict RecencyMode = iota
+	// RecencySampled looks up under the read lock and only promotes
+	// one hit in every SampleRate.
+	RecencySampled
+	// RecencyBuffered looks up under the read lock and queues hits in a
+	// bounded buffer that is applied in batches (on Put, or when full).
+	// Hits are dropped if the buffer is full and the lock is busy.
+	RecencyBuffered
+)
+
 // LRUCache is a thread-safe LRU cache.
 // Uses a hashmap + doubly-linked list to achieve O(1) Get/Put.
 // Design Patterns:
@@ -22,63 +91,360 @@ type LRUCache struct {
 	ll       *list.List                    // most-recent at Front
 	cache    map[interface{}]*list.Element // key → *list.Element
 	mu       sync.RWMutex                  // guards ll and cache
+
+	recency    RecencyMode
+	sampleRate uint64
+	hits       atomic.Uint64      // RecencySampled hit counter
+	readBuf    chan *list.Element // RecencyBuffered pending promotions
+
+	ttl         time.Duration
+	softTTL     time.Duration
+	negativeTTL time.Duration
+	maxWeight   int64
+	weight      int64  // total cost of cached entries
+	lastVersion uint64 // last version handed out by set
+	weigher     func(key, value interface{}) int64
+	codec       Codec
+	loadMu      sync.Mutex            // guards loads
+	loads       map[interface{}]*call // in-flight GetOrLoad calls
+
+	stats counters
 }
 
 // New constructs an LRUCache with the given capacity.
 // (Factory Pattern)
 func New(capacity int) *LRUCache {
+	return NewWithOptions(Options{Capacity: capacity})
+}
+
+// NewWithOptions constructs an LRUCache from opts.
+// (Factory Pattern)
+func NewWithOptions(opts Options) *LRUCache {
+	if opts.Codec == nil {
+		opts.Codec = GobCodec{}
+	}
 	return &LRUCache{
-		capacity: capacity,
-		ll:       list.New(),
-		cache:    make(map[interface{}]*list.Element, capacity),
+		capacity:    opts.Capacity,
+		ll:          list.New(),
+		cache:       make(map[interface{}]*list.Element, opts.Capacity),
+		ttl:         opts.TTL,
+		softTTL:     opts.SoftTTL,
+		negativeTTL: opts.NegativeTTL,
+		maxWeight:   opts.MaxWeight,
+		weigher:     opts.Weigher,
+		codec:       opts.Codec,
+		loads:       make(map[interface{}]*call),
 	}
 }
 
+// newWithRecency constructs an LRUCache using a relaxed recency mode.
+func newWithRecency(capacity int, mode RecencyMode, sampleRate, bufferSize int) *LRUCache {
+	c := New(capacity)
+	c.recency = mode
+	c.sampleRate = uint64(max(sampleRate, 1))
+	if mode == RecencyBuffered {
+		c.readBuf = make(chan *list.Element, max(bufferSize, 1))
+	}
+	return c
+}
+
 // Get looks up a key’s value.
 // If found, moves its element to front (MRU) and returns the value.
-// If not found, returns nil, false.
+// If not found (or expired, or a cached load failure), returns nil, false.
 // O(1) time.
 // Thread-safe via RWMutex.
 func (c *LRUCache) Get(key interface{}) (value interface{}, ok bool) {
+	if c.recency != RecencyStrict {
+		value, ok = c.getRelaxed(key)
+	} else {
+		value, _, ok = c.getStrict(key)
+	}
+	c.stats.recordAccess(ok)
+	return value, ok
+}
+
+// GetWithVersion is Get that also returns the entry's version. Every
+// write to a key gives it a new, higher version, so the version works as
+// a compare-and-swap token. It always promotes under the exclusive lock.
+func (c *LRUCache) GetWithVersion(key interface{}) (value interface{}, version uint64, ok bool) {
+	value, version, ok = c.getStrict(key)
+	c.stats.recordAccess(ok)
+	return value, version, ok
+}
+
+// getStrict serves Get under the exclusive lock, promoting every hit.
+func (c *LRUCache) getStrict(key interface{}) (interface{}, uint64, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if elem, exists := c.cache[key]; exists {
+		e := elem.Value.(*entry)
+		if e.expired(time.Now()) {
+			c.removeElement(elem)
+			c.stats.expirations.Add(1)
+			return nil, 0, false
+		}
+		if e.err != nil {
+			return nil, 0, false
+		}
 		c.ll.MoveToFront(elem) // mark as most-recent
-		return elem.Value.(*entry).value, true
+		return e.value, e.version, true
+	}
+	return nil, 0, false
+}
+
+// getRelaxed serves Get under the read lock and defers promotion
+// according to c.recency.
+func (c *LRUCache) getRelaxed(key interface{}) (interface{}, bool) {
+	c.mu.RLock()
+	elem, exists := c.cache[key]
+	var (
+		value   interface{}
+		expired bool
+	)
+	if exists {
+		e := elem.Value.(*entry)
+		value, expired = e.value, e.expired(time.Now())
+		exists = e.err == nil
+	}
+	c.mu.RUnlock()
+	if expired {
+		c.mu.Lock()
+		if c.cache[key] == elem {
+			c.removeElement(elem)
+			c.stats.expirations.Add(1)
+		}
+		c.mu.Unlock()
+		return nil, false
+	}
+	if !exists {
+		return nil, false
+	}
+
+	switch c.recency {
+	case RecencySampled:
+		if c.hits.Add(1)%c.sampleRate == 0 {
+			c.mu.Lock()
+			c.ll.MoveToFront(elem) // no-op if elem was evicted meanwhile
+			c.mu.Unlock()
+		}
+	case RecencyBuffered:
+		select {
+		case c.readBuf <- elem:
+		default:
+			// Buffer full: drain it if nobody else holds the lock,
+			// otherwise drop this hit.
+			if c.mu.TryLock() {
+				c.drainReads()
+				c.ll.MoveToFront(elem)
+				c.mu.Unlock()
+			}
+		}
+	}
+	return value, true
+}
+
+// drainReads applies buffered promotions. Caller holds c.mu.
+func (c *LRUCache) drainReads() {
+	for {
+		select {
+		case elem := <-c.readBuf:
+			c.ll.MoveToFront(elem) // no-op if elem was evicted meanwhile
+		default:
+			return
+		}
 	}
-	return nil, false
 }
 
 // Put inserts or updates a key/value.
 // If key exists, updates value and moves to front.
 // If new key and at capacity, evicts Least-Recently-Used (tail).
+// Entries heavier than MaxWeight are not cached and remove any older
+// value for the key, as memcached does; use PutWithCost to observe
+// ErrEntryTooLarge.
 // O(1) time.
 // Thread-safe via RWMutex.
 func (c *LRUCache) Put(key, value interface{}) {
+	c.PutWithTTL(key, value, c.ttl)
+}
+
+// PutWithTTL is Put with an explicit hard TTL (0 = never expire).
+// Like PutWithCost it reports ErrEntryTooLarge.
+func (c *LRUCache) PutWithTTL(key, value interface{}, ttl time.Duration) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.set(c.newEntry(key, value, ttl, 0))
+}
 
-	if elem, exists := c.cache[key]; exists {
-		// Update existing entry, move to front
-		elem.Value.(*entry).value = value
-		c.ll.MoveToFront(elem)
-		return
+// PutWithCost is Put with an explicit cost; a cost of 0 asks the
+// Weigher. Evicts least-recent entries until the total weight fits, or
+// returns ErrEntryTooLarge (removing any existing value, so a later Get
+// cannot return it) if the entry alone exceeds MaxWeight.
+func (c *LRUCache) PutWithCost(key, value interface{}, cost int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.set(c.newEntry(key, value, c.ttl, cost))
+}
+
+// Weight reports the total cost of cached entries.
+func (c *LRUCache) Weight() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.weight
+}
+
+// newEntry builds an entry whose expiries are relative to now.
+// A zero cost is computed by the Weigher (default 1).
+func (c *LRUCache) newEntry(key, value interface{}, ttl time.Duration, cost int64) *entry {
+	if cost <= 0 {
+		cost = 1
+		if c.weigher != nil {
+			cost = c.weigher(key, value)
+		}
+	}
+	e := &entry{key: key, value: value, cost: cost}
+	now := time.Now()
+	if ttl > 0 {
+		e.expiresAt = now.Add(ttl)
+	}
+	if c.softTTL > 0 {
+		e.refreshAt = now.Add(c.softTTL)
+	}
+	return e
+}
+
+// set inserts or replaces e as the most-recent entry, evicting
+// least-recent entries until it fits. An entry that cannot be cached
+// removes the key's old one. Caller holds c.mu.
+func (c *LRUCache) set(e *entry) error {
+	var err error
+	switch {
+	case e.cost <= 0:
+		err = fmt.Errorf("%w: key %v costs %d", ErrInvalidCost, e.key, e.cost)
+	case c.maxWeight > 0 && e.cost > c.maxWeight:
+		err = fmt.Errorf("%w: key %v costs %d, max is %d", ErrEntryTooLarge, e.key, e.cost, c.maxWeight)
+	}
+	if err != nil {
+		if elem, exists := c.cache[e.key]; exists {
+			c.removeElement(elem)
+		}
+		return err
+	}
+	if c.readBuf != nil {
+		c.drainReads()
 	}
 
-	// New entry
-	if c.ll.Len() >= c.capacity {
-		// Evict LRU at back
+	if elem, exists := c.cache[e.key]; exists {
+		// Replace existing entry; it is re-added at the front below
+		c.removeElement(elem)
+	}
+
+	// Evict LRU at back until the new entry fits
+	for c.full(e.cost) {
 		lru := c.ll.Back()
-		if lru != nil {
-			ent := lru.Value.(*entry)
-			delete(c.cache, ent.key)
-			c.ll.Remove(lru)
+		if lru == nil {
+			break
 		}
+		c.removeElement(lru)
+		c.stats.evictions.Add(1)
 	}
 
 	// Add to front as MRU
-	e := &entry{key: key, value: value}
+	c.lastVersion++
+	e.version = c.lastVersion
 	elem := c.ll.PushFront(e)
-	c.cache[key] = elem
+	c.cache[e.key] = elem
+	c.weight += e.cost
+	return nil
+}
+
+// full reports whether adding an entry of the given cost would exceed
+// the entry count or total weight limits. Caller holds c.mu.
+func (c *LRUCache) full(cost int64) bool {
+	if c.maxWeight > 0 {
+		if c.weight+cost > c.maxWeight {
+			return true
+		}
+		if c.capacity <= 0 {
+			return false
+		}
+	}
+	return c.ll.Len() >= c.capacity
+}
+
+// Peek returns key's value without updating recency or statistics.
+func (c *LRUCache) Peek(key interface{}) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if elem, exists := c.cache[key]; exists {
+		e := elem.Value.(*entry)
+		if e.err == nil && !e.expired(time.Now()) {
+			return e.value, true
+		}
+	}
+	return nil, false
+}
+
+// EntryInfo describes one cached entry for inspection.
+type EntryInfo struct {
+	Value     interface{}
+	Err       error     // non-nil for a cached load failure
+	ExpiresAt time.Time // zero means never
+	RefreshAt time.Time // zero means never
+	Cost      int64
+	Version   uint64 // see GetWithVersion
+}
+
+// Inspect returns everything known about key without updating recency
+// or statistics.
+func (c *LRUCache) Inspect(key interface{}) (EntryInfo, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	elem, exists := c.cache[key]
+	if !exists {
+		return EntryInfo{}, false
+	}
+	e := elem.Value.(*entry)
+	if e.expired(time.Now()) {
+		return EntryInfo{}, false
+	}
+	return EntryInfo{Value: e.value, Err: e.err, ExpiresAt: e.expiresAt, RefreshAt: e.refreshAt, Cost: e.cost, Version: e.version}, true
+}
+
+// Remove invalidates key, reporting whether it was cached.
+func (c *LRUCache) Remove(key interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, exists := c.cache[key]
+	if exists {
+		c.removeElement(elem)
+	}
+	return exists
+}
+
+// Clear removes every entry. Statistics counters are kept.
+func (c *LRUCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// A fresh list (rather than Init) makes pending buffered promotions
+	// of the old elements harmless no-ops.
+	c.ll = list.New()
+	clear(c.cache)
+	c.weight = 0
+}
+
+// removeElement drops elem from both the list and the map.
+// Caller holds c.mu.
+func (c *LRUCache) removeElement(elem *list.Element) {
+	e := elem.Value.(*entry)
+	delete(c.cache, e.key)
+	c.ll.Remove(elem)
+	c.weight -= e.cost
+}
+
+// Len reports the number of cached entries.
+func (c *LRUCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ll.Len()
 }
@@ -0,0 +1,113 @@
+package lrucache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lfuEntry is a resident entry together with its access count.
+type lfuEntry struct {
+	key   interface{}
+	value interface{}
+	freq  int
+	elem  *list.Element // position inside freqs[freq]
+}
+
+// LFUCache is a thread-safe Least-Frequently-Used cache.
+// Entries are grouped into one list per access count, so Get/Put and
+// eviction are all O(1). Ties are broken by recency (LRU within a count).
+type LFUCache struct {
+	capacity int
+	items    map[interface{}]*lfuEntry
+	freqs    map[int]*list.List // freq → entries, most-recent at Front
+	minFreq  int
+	mu       sync.Mutex
+}
+
+// NewLFU constructs an LFUCache with the given capacity.
+// (Factory Pattern)
+func NewLFU(capacity int) *LFUCache {
+	return &LFUCache{
+		capacity: capacity,
+		items:    make(map[interface{}]*lfuEntry, capacity),
+		freqs:    make(map[int]*list.List),
+	}
+}
+
+// Get returns the value for key and bumps its frequency.
+// O(1) time.
+func (c *LFUCache) Get(key interface{}) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.touch(e)
+	return e.value, true
+}
+
+// Put inserts or updates key. When full, the least frequently used
+// entry (oldest among equals) is evicted.
+// O(1) time.
+func (c *LFUCache) Put(key, value interface{}) {
+	if c.capacity <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		e.value = value
+		c.touch(e)
+		return
+	}
+
+	if len(c.items) >= c.capacity {
+		victims := c.freqs[c.minFreq]
+		lfu := victims.Back()
+		ent := lfu.Value.(*lfuEntry)
+		victims.Remove(lfu)
+		if victims.Len() == 0 {
+			delete(c.freqs, c.minFreq)
+		}
+		delete(c.items, ent.key)
+	}
+
+	e := &lfuEntry{key: key, value: value, freq: 1}
+	e.elem = c.bucket(1).PushFront(e)
+	c.items[key] = e
+	c.minFreq = 1
+}
+
+// Len reports the number of resident entries.
+func (c *LFUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// touch moves e from its current frequency list to the next one.
+func (c *LFUCache) touch(e *lfuEntry) {
+	old := c.freqs[e.freq]
+	old.Remove(e.elem)
+	if old.Len() == 0 {
+		delete(c.freqs, e.freq)
+		if c.minFreq == e.freq {
+			c.minFreq++
+		}
+	}
+	e.freq++
+	e.elem = c.bucket(e.freq).PushFront(e)
+}
+
+// bucket returns (creating if needed) the list for freq.
+func (c *LFUCache) bucket(freq int) *list.List {
+	l, ok := c.freqs[freq]
+	if !ok {
+		l = list.New()
+		c.freqs[freq] = l
+	}
+	return l
+}
@@ -0,0 +1,120 @@
+package lrucache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Loader fetches the value for key from the backing store on a miss.
+type Loader func(ctx context.Context, key interface{}) (interface{}, error)
+
+// call is one in-flight load shared by every concurrent caller of the
+// same key (singleflight).
+type call struct {
+	done  chan struct{} // closed when value/err are set
+	value interface{}
+	err   error
+}
+
+// GetOrLoad returns the cached value for key, calling loader on a miss.
+//
+//   - Concurrent misses for the same key share a single loader call.
+//   - Loader errors are cached for Options.NegativeTTL, so a failing
+//     backend is not hammered.
+//   - Once an entry is older than Options.SoftTTL its (stale) value is
+//     returned immediately while a background load refreshes it.
+//
+// ctx only bounds how long this caller waits; the shared load itself is
+// not cancelled when one waiter gives up.
+func (c *LRUCache) GetOrLoad(ctx context.Context, key interface{}, loader Loader) (interface{}, error) {
+	c.mu.Lock()
+	if elem, exists := c.cache[key]; exists {
+		e := elem.Value.(*entry)
+		now := time.Now()
+		if !e.expired(now) {
+			c.ll.MoveToFront(elem)
+			c.mu.Unlock()
+			c.stats.recordAccess(true)
+			if e.err == nil && e.stale(now) {
+				c.startLoad(ctx, key, loader, true) // stale-while-revalidate
+			}
+			return e.value, e.err
+		}
+		c.removeElement(elem)
+		c.stats.expirations.Add(1)
+	}
+	c.mu.Unlock()
+	c.stats.recordAccess(false)
+
+	cl := c.startLoad(ctx, key, loader, false)
+	select {
+	case <-cl.done:
+		return cl.value, cl.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// startLoad joins the in-flight load for key or starts a new one.
+// refresh marks a background refresh of a still-cached value: if it
+// fails, the stale value is kept rather than replaced by the error, and
+// the next refresh waits (see store).
+func (c *LRUCache) startLoad(ctx context.Context, key interface{}, loader Loader, refresh bool) *call {
+	c.loadMu.Lock()
+	if cl, ok := c.loads[key]; ok {
+		c.loadMu.Unlock()
+		return cl
+	}
+	cl := &call{done: make(chan struct{})}
+	c.loads[key] = cl
+	c.loadMu.Unlock()
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				cl.value, cl.err = nil, fmt.Errorf("lrucache: loader panicked: %v", r)
+			}
+			c.store(key, cl.value, cl.err, refresh)
+			// Store before un-registering so late callers hit the cache.
+			c.loadMu.Lock()
+			delete(c.loads, key)
+			c.loadMu.Unlock()
+			close(cl.done)
+		}()
+		start := time.Now()
+		cl.value, cl.err = loader(context.WithoutCancel(ctx), key)
+		c.stats.recordLoad(time.Since(start), cl.err)
+	}()
+	return cl
+}
+
+// store caches the outcome of a load. A failed refresh keeps serving
+// the stale value but puts off the next refresh by NegativeTTL (SoftTTL
+// if there is none), so a failing backend is not called on every read.
+func (c *LRUCache) store(key, value interface{}, err error, refresh bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err == nil {
+		c.set(c.newEntry(key, value, c.ttl, 0)) // too-large values are not cached
+		return
+	}
+	if refresh {
+		if elem, ok := c.cache[key]; ok {
+			e := elem.Value.(*entry)
+			backoff := c.negativeTTL
+			if backoff <= 0 {
+				backoff = c.softTTL
+			}
+			if e.err == nil && !e.refreshAt.IsZero() {
+				e.refreshAt = time.Now().Add(backoff)
+			}
+		}
+		return
+	}
+	if c.negativeTTL <= 0 {
+		return
+	}
+	c.set(&entry{key: key, err: err, expiresAt: time.Now().Add(c.negativeTTL), cost: 1})
+}
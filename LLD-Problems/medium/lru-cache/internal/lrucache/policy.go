@@ -0,0 +1,34 @@
+package lrucache
+
+import "hash/maphash"
+
+// Policy is the common interface shared by every eviction policy in this
+// package (LRU, LFU, ARC, 2Q, W-TinyLFU), so callers and the trace
+// replayer can swap one for another.
+// (Strategy Pattern)
+type Policy interface {
+	// Get returns the value for key and records the access.
+	Get(key interface{}) (value interface{}, ok bool)
+	// Put inserts or updates key, evicting according to the policy.
+	Put(key, value interface{})
+	// Len reports the number of resident entries.
+	Len() int
+}
+
+// Compile-time checks that every policy satisfies the interface.
+var (
+	_ Policy = (*LRUCache)(nil)
+	_ Policy = (*LFUCache)(nil)
+	_ Policy = (*ARCCache)(nil)
+	_ Policy = (*TwoQueueCache)(nil)
+	_ Policy = (*TinyLFUCache)(nil)
+	_ Policy = (*ShardedCache)(nil)
+)
+
+// hashSeed is shared by all key hashing in this process.
+var hashSeed = maphash.MakeSeed()
+
+// hashKey hashes any comparable key (the same keys a map accepts).
+func hashKey(key interface{}) uint64 {
+	return maphash.Comparable(hashSeed, key)
+}
@@ -0,0 +1,52 @@
+package lrucache
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// ReplayResult summarises one policy's run over an access trace.
+type ReplayResult struct {
+	Requests int
+	Hits     int
+}
+
+// HitRatio returns hits / requests (0 for an empty trace).
+func (r ReplayResult) HitRatio() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Hits) / float64(r.Requests)
+}
+
+// ReadTrace parses a recorded access trace: one request per line, the
+// key being the first whitespace-separated field. Blank lines and lines
+// starting with '#' are ignored.
+func ReadTrace(r io.Reader) ([]string, error) {
+	var keys []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, strings.Fields(line)[0])
+	}
+	return keys, sc.Err()
+}
+
+// Replay feeds trace through p as a demand-filled cache: every request
+// is a Get, and every miss is followed by a Put of that key.
+func Replay(p Policy, trace []string) ReplayResult {
+	var res ReplayResult
+	for _, key := range trace {
+		res.Requests++
+		if _, ok := p.Get(key); ok {
+			res.Hits++
+			continue
+		}
+		p.Put(key, struct{}{})
+	}
+	return res
+}
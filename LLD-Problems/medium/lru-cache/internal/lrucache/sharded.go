@@ -0,0 +1,77 @@
+package lrucache
+
+// ShardOptions configures a ShardedCache.
+type ShardOptions struct {
+	// Shards is the number of independent LRU segments, rounded up to a
+	// power of two. Defaults to 16.
+	Shards int
+	// Recency selects how Get hits update LRU order in each segment.
+	Recency RecencyMode
+	// SampleRate promotes one hit in SampleRate (RecencySampled).
+	// Defaults to 8.
+	SampleRate int
+	// BufferSize is the per-segment hit buffer (RecencyBuffered).
+	// Defaults to 64.
+	BufferSize int
+}
+
+// ShardedCache spreads keys over N independent LRUCache segments so that
+// operations on different keys rarely contend for the same lock.
+// Eviction is LRU per segment, i.e. approximately LRU overall.
+type ShardedCache struct {
+	shards []*LRUCache
+	mask   uint64
+}
+
+// NewSharded constructs a ShardedCache holding about capacity entries
+// in total, split evenly across segments.
+// (Factory Pattern)
+func NewSharded(capacity int, opts ShardOptions) *ShardedCache {
+	if opts.Shards <= 0 {
+		opts.Shards = 16
+	}
+	if opts.SampleRate <= 0 {
+		opts.SampleRate = 8
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 64
+	}
+	n := 1
+	for n < opts.Shards {
+		n <<= 1
+	}
+	perShard := (capacity + n - 1) / n
+
+	sc := &ShardedCache{
+		shards: make([]*LRUCache, n),
+		mask:   uint64(n - 1),
+	}
+	for i := range sc.shards {
+		sc.shards[i] = newWithRecency(perShard, opts.Recency, opts.SampleRate, opts.BufferSize)
+	}
+	return sc
+}
+
+// shard picks the segment responsible for key.
+func (sc *ShardedCache) shard(key interface{}) *LRUCache {
+	return sc.shards[hashKey(key)&sc.mask]
+}
+
+// Get looks up key in its segment.
+func (sc *ShardedCache) Get(key interface{}) (interface{}, bool) {
+	return sc.shard(key).Get(key)
+}
+
+// Put inserts or updates key in its segment.
+func (sc *ShardedCache) Put(key, value interface{}) {
+	sc.shard(key).Put(key, value)
+}
+
+// Len reports the number of entries across all segments.
+func (sc *ShardedCache) Len() int {
+	n := 0
+	for _, s := range sc.shards {
+		n += s.Len()
+	}
+	return n
+}
@@ -0,0 +1,77 @@
+package lrucache
+
+// sketchDepth is the number of hash rows in the count-min sketch.
+const sketchDepth = 4
+
+// sketchMaxCount saturates counters at 4 bits, as in TinyLFU.
+const sketchMaxCount = 15
+
+// countMinSketch estimates access frequencies in fixed memory.
+// Counters are periodically halved ("aging") so the estimate tracks
+// recent popularity rather than all-time popularity.
+// Not thread-safe: callers hold their own lock.
+type countMinSketch struct {
+	rows       [sketchDepth][]uint8
+	mask       uint64
+	additions  int
+	sampleSize int // halve all counters after this many increments
+}
+
+// newCountMinSketch sizes the sketch for roughly capacity distinct keys.
+func newCountMinSketch(capacity int) *countMinSketch {
+	width := 16
+	for width < capacity {
+		width <<= 1
+	}
+	s := &countMinSketch{
+		mask:       uint64(width - 1),
+		sampleSize: 10 * max(capacity, 1),
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// index derives the counter position for row i from a single hash.
+func (s *countMinSketch) index(h uint64, i int) uint64 {
+	h += uint64(i) * 0x9e3779b97f4a7c15
+	h ^= h >> 31
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 29
+	return h & s.mask
+}
+
+// Increment records one access of the key with hash h.
+func (s *countMinSketch) Increment(h uint64) {
+	for i := range s.rows {
+		if c := &s.rows[i][s.index(h, i)]; *c < sketchMaxCount {
+			*c++
+		}
+	}
+	s.additions++
+	if s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+// Estimate returns the (over-)estimated access count for hash h.
+func (s *countMinSketch) Estimate(h uint64) int {
+	est := sketchMaxCount
+	for i := range s.rows {
+		if c := int(s.rows[i][s.index(h, i)]); c < est {
+			est = c
+		}
+	}
+	return est
+}
+
+// reset halves every counter.
+func (s *countMinSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
@@ -0,0 +1,229 @@
+package lrucache
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// snapshotVersion is bumped whenever the Snapshot layout changes.
+const snapshotVersion = 1
+
+// SnapshotEntry is one cached entry as written to a snapshot.
+// Expiry times are absolute, so TTLs keep counting across a restart.
+type SnapshotEntry struct {
+	Key       interface{}
+	Value     interface{}
+	ExpiresAt time.Time // zero means never
+	RefreshAt time.Time // zero means never
+	Cost      int64
+}
+
+// Snapshot is the serialized form of a cache.
+// Entries are in recency order, most-recent first.
+type Snapshot struct {
+	Version int
+	SavedAt time.Time
+	Entries []SnapshotEntry
+}
+
+// Codec encodes and decodes snapshots.
+// (Strategy Pattern)
+type Codec interface {
+	Encode(w io.Writer, s *Snapshot) error
+	Decode(r io.Reader) (*Snapshot, error)
+}
+
+// GobCodec stores snapshots with encoding/gob. Keys and values of custom
+// types must be registered with gob.Register first.
+type GobCodec struct{}
+
+func (GobCodec) Encode(w io.Writer, s *Snapshot) error { return gob.NewEncoder(w).Encode(s) }
+
+func (GobCodec) Decode(r io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	if err := gob.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// JSONCodec stores snapshots as JSON. It is human-readable but lossy for
+// interface{} values: numbers come back as float64 and structs as maps.
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(w io.Writer, s *Snapshot) error { return json.NewEncoder(w).Encode(s) }
+
+func (JSONCodec) Decode(r io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SaveTo writes all live entries to w in recency order using the
+// cache's Codec. Expired entries and cached load failures are skipped.
+func (c *LRUCache) SaveTo(w io.Writer) error {
+	now := time.Now()
+	snap := &Snapshot{Version: snapshotVersion, SavedAt: now}
+
+	c.mu.RLock()
+	snap.Entries = make([]SnapshotEntry, 0, c.ll.Len())
+	for elem := c.ll.Front(); elem != nil; elem = elem.Next() {
+		e := elem.Value.(*entry)
+		if e.err != nil || e.expired(now) {
+			continue
+		}
+		snap.Entries = append(snap.Entries, SnapshotEntry{
+			Key:       e.key,
+			Value:     e.value,
+			ExpiresAt: e.expiresAt,
+			RefreshAt: e.refreshAt,
+			Cost:      e.cost,
+		})
+	}
+	c.mu.RUnlock()
+
+	return c.codec.Encode(w, snap)
+}
+
+// LoadFrom restores entries written by SaveTo, reproducing their exact
+// LRU order. Restored entries become more recent than anything already
+// cached; entries that expired while saved are dropped, and capacity
+// limits keep the most-recent entries of the snapshot.
+func (c *LRUCache) LoadFrom(r io.Reader) error {
+	snap, err := c.codec.Decode(r)
+	if err != nil {
+		return fmt.Errorf("lrucache: decode snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("lrucache: unsupported snapshot version %d", snap.Version)
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Insert least-recent first so the last insert ends up at the front.
+	for i := len(snap.Entries) - 1; i >= 0; i-- {
+		se := snap.Entries[i]
+		e := &entry{key: se.Key, value: se.Value, expiresAt: se.ExpiresAt, refreshAt: se.RefreshAt, cost: se.Cost}
+		if e.expired(now) {
+			continue
+		}
+		if e.cost <= 0 {
+			e.cost = 1
+		}
+		c.set(e) // entries too large for this cache are skipped
+	}
+	return nil
+}
+
+// SaveFile writes a snapshot to path atomically: it writes and fsyncs a
+// temporary file in the same directory, then renames it over path, so
+// readers never observe a partial snapshot.
+func (c *LRUCache) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if err := c.SaveTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile restores a snapshot written by SaveFile. A missing file is
+// reported as an error satisfying errors.Is(err, fs.ErrNotExist).
+func (c *LRUCache) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.LoadFrom(f)
+}
+
+// Snapshotter periodically saves a cache to a file in the background.
+type Snapshotter struct {
+	cache   *LRUCache
+	path    string
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	stopErr error
+	mu      sync.Mutex
+	lastErr error
+}
+
+// StartSnapshots saves the cache to path every interval until Stop.
+// It fails if interval is not positive. (Factory Pattern)
+func (c *LRUCache) StartSnapshots(path string, interval time.Duration) (*Snapshotter, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("lrucache: snapshot interval must be positive, got %v", interval)
+	}
+	s := &Snapshotter{
+		cache: c,
+		path:  path,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go s.run(interval)
+	return s, nil
+}
+
+func (s *Snapshotter) run(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.save()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// save writes one snapshot and records its outcome.
+func (s *Snapshotter) save() error {
+	err := s.cache.SaveFile(s.path)
+	s.mu.Lock()
+	s.lastErr = err
+	s.mu.Unlock()
+	return err
+}
+
+// LastError returns the result of the most recent snapshot attempt.
+func (s *Snapshotter) LastError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastErr
+}
+
+// Stop halts the background loop and writes a final snapshot. Later
+// calls return the final snapshot's result without saving again.
+func (s *Snapshotter) Stop() error {
+	s.once.Do(func() {
+		close(s.stop)
+		<-s.done
+		s.stopErr = s.save()
+	})
+	return s.stopErr
+}
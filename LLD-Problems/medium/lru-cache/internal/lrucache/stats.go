@@ -0,0 +1,81 @@
+package lrucache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// counters are updated lock-free on the hot path.
+type counters struct {
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	evictions     atomic.Uint64
+	expirations   atomic.Uint64
+	loadSuccesses atomic.Uint64
+	loadFailures  atomic.Uint64
+	loadNanos     atomic.Int64
+}
+
+func (s *counters) recordAccess(hit bool) {
+	if hit {
+		s.hits.Add(1)
+	} else {
+		s.misses.Add(1)
+	}
+}
+
+func (s *counters) recordLoad(d time.Duration, err error) {
+	if err != nil {
+		s.loadFailures.Add(1)
+	} else {
+		s.loadSuccesses.Add(1)
+	}
+	s.loadNanos.Add(int64(d))
+}
+
+// Stats is a point-in-time snapshot of cache counters.
+type Stats struct {
+	Hits          uint64        `json:"hits"`
+	Misses        uint64        `json:"misses"`
+	Evictions     uint64        `json:"evictions"`      // removed to make room
+	Expirations   uint64        `json:"expirations"`    // removed after their TTL
+	LoadSuccesses uint64        `json:"load_successes"` // GetOrLoad loader calls
+	LoadFailures  uint64        `json:"load_failures"`
+	TotalLoadTime time.Duration `json:"total_load_time_ns"`
+	Entries       int           `json:"entries"`
+	Weight        int64         `json:"weight"`
+}
+
+// HitRatio returns hits / (hits + misses).
+func (s Stats) HitRatio() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+// AverageLoadPenalty returns the mean loader latency.
+func (s Stats) AverageLoadPenalty() time.Duration {
+	if loads := s.LoadSuccesses + s.LoadFailures; loads > 0 {
+		return s.TotalLoadTime / time.Duration(loads)
+	}
+	return 0
+}
+
+// Stats returns a snapshot of the cache's counters.
+func (c *LRUCache) Stats() Stats {
+	c.mu.RLock()
+	entries, weight := c.ll.Len(), c.weight
+	c.mu.RUnlock()
+	return Stats{
+		Hits:          c.stats.hits.Load(),
+		Misses:        c.stats.misses.Load(),
+		Evictions:     c.stats.evictions.Load(),
+		Expirations:   c.stats.expirations.Load(),
+		LoadSuccesses: c.stats.loadSuccesses.Load(),
+		LoadFailures:  c.stats.loadFailures.Load(),
+		TotalLoadTime: time.Duration(c.stats.loadNanos.Load()),
+		Entries:       entries,
+		Weight:        weight,
+	}
+}
@@ -0,0 +1,159 @@
+package lrucache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// W-TinyLFU segment identifiers.
+const (
+	tlfuWindow    = iota // small admission window (LRU)
+	tlfuProbation        // main SLRU, entries seen once in main
+	tlfuProtected        // main SLRU, entries hit while in probation
+)
+
+// tlfuEntry lives in exactly one W-TinyLFU segment.
+type tlfuEntry struct {
+	key   interface{}
+	value interface{}
+	hash  uint64
+	where int
+}
+
+// TinyLFUCache is a thread-safe W-TinyLFU cache (Einziger et al.).
+// New entries land in a 1% LRU window. Entries leaving the window must
+// beat the main segment's eviction victim on estimated frequency (from a
+// count-min sketch) to be admitted; otherwise they are discarded. The main
+// segment is a segmented LRU split 20% probation / 80% protected.
+type TinyLFUCache struct {
+	windowCap    int
+	protectedCap int
+	mainCap      int
+	segments     [3]*list.List
+	items        map[interface{}]*list.Element
+	sketch       *countMinSketch
+	mu           sync.Mutex
+}
+
+// NewTinyLFU constructs a TinyLFUCache with the given capacity.
+// (Factory Pattern)
+func NewTinyLFU(capacity int) *TinyLFUCache {
+	windowCap := max(capacity/100, 1)
+	mainCap := max(capacity-windowCap, 0)
+	c := &TinyLFUCache{
+		windowCap:    windowCap,
+		mainCap:      mainCap,
+		protectedCap: mainCap * 8 / 10,
+		items:        make(map[interface{}]*list.Element, capacity),
+		sketch:       newCountMinSketch(capacity),
+	}
+	for i := range c.segments {
+		c.segments[i] = list.New()
+	}
+	return c
+}
+
+// Get returns the value for key, recording the access in the sketch.
+// O(1) time.
+func (c *TinyLFUCache) Get(key interface{}) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*tlfuEntry)
+	c.sketch.Increment(e.hash)
+	c.onHit(elem)
+	return e.value, true
+}
+
+// Put inserts or updates key. New keys enter the window; the window's
+// overflow competes with the main victim for admission.
+// O(1) time.
+func (c *TinyLFUCache) Put(key, value interface{}) {
+	if c.windowCap+c.mainCap <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		e := elem.Value.(*tlfuEntry)
+		e.value = value
+		c.sketch.Increment(e.hash)
+		c.onHit(elem)
+		return
+	}
+
+	e := &tlfuEntry{key: key, value: value, hash: hashKey(key), where: tlfuWindow}
+	c.sketch.Increment(e.hash)
+	window := c.segments[tlfuWindow]
+	c.items[key] = window.PushFront(e)
+	if window.Len() <= c.windowCap {
+		return
+	}
+
+	// Window overflow: its LRU entry becomes an admission candidate.
+	candElem := window.Back()
+	window.Remove(candElem)
+	cand := candElem.Value.(*tlfuEntry)
+
+	probation, protected := c.segments[tlfuProbation], c.segments[tlfuProtected]
+	if probation.Len()+protected.Len() < c.mainCap {
+		c.admit(cand)
+		return
+	}
+	victimElem := probation.Back()
+	if victimElem == nil {
+		victimElem = protected.Back()
+	}
+	if victimElem == nil {
+		delete(c.items, cand.key)
+		return
+	}
+	victim := victimElem.Value.(*tlfuEntry)
+	if c.sketch.Estimate(cand.hash) > c.sketch.Estimate(victim.hash) {
+		c.segments[victim.where].Remove(victimElem)
+		delete(c.items, victim.key)
+		c.admit(cand)
+		return
+	}
+	delete(c.items, cand.key)
+}
+
+// Len reports the number of resident entries.
+func (c *TinyLFUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// admit places a window candidate at the front of probation.
+func (c *TinyLFUCache) admit(e *tlfuEntry) {
+	e.where = tlfuProbation
+	c.items[e.key] = c.segments[tlfuProbation].PushFront(e)
+}
+
+// onHit updates recency and promotes probation hits to protected,
+// demoting protected's LRU entry back to probation when it overflows.
+func (c *TinyLFUCache) onHit(elem *list.Element) {
+	e := elem.Value.(*tlfuEntry)
+	switch e.where {
+	case tlfuWindow, tlfuProtected:
+		c.segments[e.where].MoveToFront(elem)
+	case tlfuProbation:
+		protected := c.segments[tlfuProtected]
+		c.segments[tlfuProbation].Remove(elem)
+		e.where = tlfuProtected
+		c.items[e.key] = protected.PushFront(e)
+		if protected.Len() > c.protectedCap {
+			demoted := protected.Back()
+			protected.Remove(demoted)
+			d := demoted.Value.(*tlfuEntry)
+			d.where = tlfuProbation
+			c.items[d.key] = c.segments[tlfuProbation].PushFront(d)
+		}
+	}
+}
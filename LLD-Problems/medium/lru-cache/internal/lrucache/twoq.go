@@ -0,0 +1,135 @@
+package lrucache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// 2Q queue identifiers.
+const (
+	twoQIn  = iota // A1in: FIFO of keys seen once
+	twoQOut        // A1out: ghost keys evicted from A1in
+	twoQHot        // Am: LRU of keys seen again after leaving A1in
+)
+
+// twoQEntry lives in exactly one 2Q queue.
+type twoQEntry struct {
+	key   interface{}
+	value interface{}
+	where int
+}
+
+// TwoQueueCache is a thread-safe full 2Q cache (Johnson & Shasha).
+// New keys enter a small FIFO (A1in); only keys requested again after
+// falling out of it (tracked in the ghost queue A1out) are admitted to
+// the main LRU (Am), so a single scan cannot flush the hot set.
+type TwoQueueCache struct {
+	capacity int
+	kIn      int // max size of A1in
+	kOut     int // max size of A1out
+	queues   [3]*list.List
+	items    map[interface{}]*list.Element
+	mu       sync.Mutex
+}
+
+// New2Q constructs a TwoQueueCache with the paper's recommended tuning:
+// A1in holds 25% of capacity and A1out remembers 50% of capacity.
+// (Factory Pattern)
+func New2Q(capacity int) *TwoQueueCache {
+	c := &TwoQueueCache{
+		capacity: capacity,
+		kIn:      max(capacity/4, 1),
+		kOut:     max(capacity/2, 1),
+		items:    make(map[interface{}]*list.Element, capacity+capacity/2),
+	}
+	for i := range c.queues {
+		c.queues[i] = list.New()
+	}
+	return c
+}
+
+// Get returns the value for a resident key. Hits in Am refresh recency;
+// hits in A1in deliberately do not (correlated references).
+// O(1) time.
+func (c *TwoQueueCache) Get(key interface{}) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*twoQEntry)
+	switch e.where {
+	case twoQHot:
+		c.queues[twoQHot].MoveToFront(elem)
+	case twoQOut:
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Put inserts or updates key.
+// O(1) time.
+func (c *TwoQueueCache) Put(key, value interface{}) {
+	if c.capacity <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		e := elem.Value.(*twoQEntry)
+		switch e.where {
+		case twoQHot:
+			e.value = value
+			c.queues[twoQHot].MoveToFront(elem)
+			return
+		case twoQIn:
+			e.value = value
+			return
+		case twoQOut:
+			// Seen again after leaving A1in: promote to Am.
+			c.reclaim()
+			c.queues[twoQOut].Remove(elem)
+			e.value, e.where = value, twoQHot
+			c.items[key] = c.queues[twoQHot].PushFront(e)
+			return
+		}
+	}
+
+	c.reclaim()
+	c.items[key] = c.queues[twoQIn].PushFront(&twoQEntry{key: key, value: value, where: twoQIn})
+}
+
+// Len reports the number of resident (non-ghost) entries.
+func (c *TwoQueueCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.queues[twoQIn].Len() + c.queues[twoQHot].Len()
+}
+
+// reclaim frees one resident slot if the cache is full.
+func (c *TwoQueueCache) reclaim() {
+	in, out, hot := c.queues[twoQIn], c.queues[twoQOut], c.queues[twoQHot]
+	if in.Len()+hot.Len() < c.capacity {
+		return
+	}
+	if in.Len() > c.kIn || hot.Len() == 0 {
+		// Demote the oldest A1in entry to a ghost in A1out.
+		elem := in.Back()
+		e := elem.Value.(*twoQEntry)
+		in.Remove(elem)
+		e.value, e.where = nil, twoQOut
+		c.items[e.key] = out.PushFront(e)
+		if out.Len() > c.kOut {
+			ghost := out.Back()
+			out.Remove(ghost)
+			delete(c.items, ghost.Value.(*twoQEntry).key)
+		}
+		return
+	}
+	elem := hot.Back()
+	hot.Remove(elem)
+	delete(c.items, elem.Value.(*twoQEntry).key)
+}
@@ -0,0 +1,222 @@
+package memcache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client errors.
+var (
+	ErrCacheMiss    = errors.New("memcache: cache miss")
+	ErrNotStored    = errors.New("memcache: item not stored")
+	ErrCASConflict  = errors.New("memcache: compare-and-swap conflict")
+	ErrMalformedKey = errors.New("memcache: key is empty, longer than 250 bytes, or contains spaces or control characters")
+)
+
+// Client is a minimal memcached text-protocol client for one server.
+// Requests on one Client are serialized over a single connection, which
+// is re-dialed after an I/O error.
+type Client struct {
+	addr    string
+	timeout time.Duration
+
+	mu   sync.Mutex // guards conn, r, w and serializes requests
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// NewClient returns a Client for addr. The connection is dialed lazily.
+// (Factory Pattern)
+func NewClient(addr string, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	return &Client{addr: addr, timeout: timeout}
+}
+
+// Addr returns the server address.
+func (c *Client) Addr() string { return c.addr }
+
+// Get fetches key, returning ErrCacheMiss if absent. The item's CAS
+// token is returned for use with CompareAndSwap.
+func (c *Client) Get(key string) (it *Item, casUnique uint64, err error) {
+	if !validKey(key) {
+		return nil, 0, ErrMalformedKey
+	}
+	err = c.do(func() error {
+		fmt.Fprintf(c.w, "gets %s\r\n", key)
+		if err := c.w.Flush(); err != nil {
+			return err
+		}
+		for {
+			line, err := c.readLine()
+			if err != nil {
+				return err
+			}
+			if line == replyEnd {
+				return nil
+			}
+			// VALUE <key> <flags> <bytes> <cas>
+			f := strings.Fields(line)
+			if len(f) != 5 || f[0] != "VALUE" {
+				return fmt.Errorf("memcache: unexpected response %q", line)
+			}
+			flags, _ := strconv.ParseUint(f[2], 10, 32)
+			size, err := strconv.Atoi(f[3])
+			if err != nil {
+				return fmt.Errorf("memcache: bad value size %q", f[3])
+			}
+			casUnique, _ = strconv.ParseUint(f[4], 10, 64)
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(c.r, data); err != nil {
+				return err
+			}
+			if string(data[size:]) != "\r\n" {
+				return fmt.Errorf("memcache: value for %s not terminated by CRLF", key)
+			}
+			it = &Item{Flags: uint32(flags), Data: data[:size]}
+		}
+	})
+	if err == nil && it == nil {
+		err = ErrCacheMiss
+	}
+	return it, casUnique, err
+}
+
+// Set stores it under key. exptime follows memcached rules (0 = never).
+func (c *Client) Set(key string, it *Item, exptime int64) error {
+	return c.store("set", key, it, exptime, 0)
+}
+
+// Add stores it only if key is absent, else returns ErrNotStored.
+func (c *Client) Add(key string, it *Item, exptime int64) error {
+	return c.store("add", key, it, exptime, 0)
+}
+
+// CompareAndSwap stores it only if key still has casUnique, returning
+// ErrCASConflict if it changed and ErrCacheMiss if it is gone.
+func (c *Client) CompareAndSwap(key string, it *Item, exptime int64, casUnique uint64) error {
+	return c.store("cas", key, it, exptime, casUnique)
+}
+
+func (c *Client) store(cmd, key string, it *Item, exptime int64, casUnique uint64) error {
+	if !validKey(key) {
+		return ErrMalformedKey
+	}
+	return c.do(func() error {
+		if cmd == "cas" {
+			fmt.Fprintf(c.w, "cas %s %d %d %d %d\r\n", key, it.Flags, exptime, len(it.Data), casUnique)
+		} else {
+			fmt.Fprintf(c.w, "%s %s %d %d %d\r\n", cmd, key, it.Flags, exptime, len(it.Data))
+		}
+		c.w.Write(it.Data)
+		c.w.WriteString("\r\n")
+		if err := c.w.Flush(); err != nil {
+			return err
+		}
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		switch line {
+		case replyStored:
+			return nil
+		case replyNotStored:
+			return ErrNotStored
+		case replyExists:
+			return ErrCASConflict
+		case replyNotFound:
+			return ErrCacheMiss
+		}
+		return replyErr(line)
+	})
+}
+
+// Delete removes key, returning ErrCacheMiss if it was absent.
+func (c *Client) Delete(key string) error {
+	if !validKey(key) {
+		return ErrMalformedKey
+	}
+	return c.do(func() error {
+		fmt.Fprintf(c.w, "delete %s\r\n", key)
+		if err := c.w.Flush(); err != nil {
+			return err
+		}
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		switch line {
+		case replyDeleted:
+			return nil
+		case replyNotFound:
+			return ErrCacheMiss
+		}
+		return replyErr(line)
+	})
+}
+
+// Close closes the underlying connection.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// do runs one request/response exchange, dialing if needed and dropping
+// the connection after any error that may have desynchronized it.
+func (c *Client) do(fn func() error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+		if err != nil {
+			return err
+		}
+		c.conn, c.r, c.w = conn, bufio.NewReader(conn), bufio.NewWriter(conn)
+	}
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	err := fn()
+	if err != nil && !isProtocolReply(err) {
+		c.conn.Close()
+		c.conn = nil
+	}
+	return err
+}
+
+func (c *Client) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// serverError is an ERROR / CLIENT_ERROR / SERVER_ERROR reply.
+type serverError string
+
+func (e serverError) Error() string { return "memcache: " + string(e) }
+
+func replyErr(line string) error { return serverError(line) }
+
+// isProtocolReply reports whether err is a well-formed server reply,
+// after which the connection is still in sync.
+func isProtocolReply(err error) bool {
+	var se serverError
+	return errors.Is(err, ErrCacheMiss) || errors.Is(err, ErrNotStored) ||
+		errors.Is(err, ErrCASConflict) || errors.As(err, &se)
+}
@@ -0,0 +1,46 @@
+package memcache
+
+import "time"
+
+// Item is the value stored in the cache for each memcached key.
+type Item struct {
+	Flags uint32
+	Data  []byte
+}
+
+// maxRelativeExptime is memcached's cut-off: larger exptimes are
+// absolute Unix timestamps rather than offsets in seconds.
+const maxRelativeExptime = 60 * 60 * 24 * 30
+
+// maxKeyLength is the longest key memcached accepts.
+const maxKeyLength = 250
+
+// ttlFromExptime converts a memcached exptime to a cache TTL.
+// 0 means never expire; expired reports an exptime already in the past.
+func ttlFromExptime(exptime int64, now time.Time) (ttl time.Duration, expired bool) {
+	switch {
+	case exptime == 0:
+		return 0, false
+	case exptime < 0:
+		return 0, true
+	case exptime <= maxRelativeExptime:
+		return time.Duration(exptime) * time.Second, false
+	default:
+		ttl = time.Unix(exptime, 0).Sub(now)
+		return ttl, ttl <= 0
+	}
+}
+
+// validKey reports whether key is a legal memcached key: 1-250 bytes
+// with no whitespace or control characters.
+func validKey(key string) bool {
+	if len(key) == 0 || len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,376 @@
+package memcache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Protocol replies.
+const (
+	replyStored    = "STORED"
+	replyNotStored = "NOT_STORED"
+	replyExists    = "EXISTS"
+	replyNotFound  = "NOT_FOUND"
+	replyDeleted   = "DELETED"
+	replyOK        = "OK"
+	replyEnd       = "END"
+	replyError     = "ERROR"
+)
+
+// maxLineLength bounds a command line: room for a get of a few hundred
+// keys of the maximum length.
+const maxLineLength = 64 << 10
+
+// errLineTooLong reports a command line over maxLineLength. The line
+// has been discarded and answered, so the connection stays usable.
+var errLineTooLong = errors.New("memcache: command line too long")
+
+// session holds the per-connection protocol state.
+type session struct {
+	srv *Server
+	r   *bufio.Reader
+	w   *bufio.Writer
+}
+
+// handle reads and executes one command. quit reports a "quit" command.
+func (s *session) handle() (quit bool, err error) {
+	line, err := s.readLine()
+	if errors.Is(err, errLineTooLong) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		s.reply(replyError)
+		return false, nil
+	}
+
+	switch cmd, args := fields[0], fields[1:]; cmd {
+	case "get", "gets":
+		s.get(args, cmd == "gets")
+	case "set", "add", "replace", "cas":
+		return false, s.store(cmd, args)
+	case "delete":
+		s.delete(args)
+	case "incr", "decr":
+		s.incrDecr(cmd == "incr", args)
+	case "flush_all":
+		s.flushAll(args)
+	case "stats":
+		s.stats(args)
+	case "version":
+		s.reply("VERSION " + Version)
+	case "quit":
+		return true, nil
+	default:
+		s.reply(replyError)
+	}
+	return false, nil
+}
+
+// readLine returns the next "\r\n"-terminated line without terminator.
+// A line longer than maxLineLength is skipped and answered with a
+// CLIENT_ERROR.
+func (s *session) readLine() (string, error) {
+	var line []byte
+	for {
+		chunk, err := s.r.ReadSlice('\n')
+		if len(line)+len(chunk) > maxLineLength {
+			for errors.Is(err, bufio.ErrBufferFull) {
+				_, err = s.r.ReadSlice('\n')
+			}
+			if err != nil {
+				return "", err
+			}
+			s.reply("CLIENT_ERROR line too long")
+			return "", errLineTooLong
+		}
+		line = append(line, chunk...)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(line), "\r\n"), nil
+	}
+}
+
+func (s *session) reply(line string) {
+	s.w.WriteString(line)
+	s.w.WriteString("\r\n")
+}
+
+// noreply strips a trailing "noreply" argument.
+func noreply(args []string) ([]string, bool) {
+	if n := len(args); n > 0 && args[n-1] == "noreply" {
+		return args[:n-1], true
+	}
+	return args, false
+}
+
+// get handles "get <key>*" and "gets <key>*".
+func (s *session) get(keys []string, withCAS bool) {
+	if len(keys) == 0 {
+		s.reply(replyError)
+		return
+	}
+	for _, key := range keys {
+		s.srv.cmdGet.Add(1)
+		v, version, ok := s.srv.cache.GetWithVersion(key)
+		if !ok {
+			continue
+		}
+		it := v.(*Item)
+		if withCAS {
+			fmt.Fprintf(s.w, "VALUE %s %d %d %d\r\n", key, it.Flags, len(it.Data), version)
+		} else {
+			fmt.Fprintf(s.w, "VALUE %s %d %d\r\n", key, it.Flags, len(it.Data))
+		}
+		s.w.Write(it.Data)
+		s.w.WriteString("\r\n")
+	}
+	s.reply(replyEnd)
+}
+
+// store handles "<set|add|replace> <key> <flags> <exptime> <bytes> [noreply]"
+// and "cas <key> <flags> <exptime> <bytes> <cas unique> [noreply]".
+// Only I/O errors are returned; protocol errors are replied to.
+func (s *session) store(cmd string, args []string) error {
+	args, quiet := noreply(args)
+	want := 4
+	if cmd == "cas" {
+		want = 5
+	}
+	if len(args) != want {
+		s.reply("CLIENT_ERROR bad command line format")
+		return nil
+	}
+	size, err := strconv.ParseInt(args[3], 10, 32)
+	if err != nil || size < 0 {
+		s.reply("CLIENT_ERROR bad command line format")
+		return nil
+	}
+	// The data block must be consumed even if the command is rejected.
+	if size > s.srv.maxItem {
+		if _, err := io.CopyN(io.Discard, s.r, size+2); err != nil {
+			return err
+		}
+		if cmd == "set" && validKey(args[0]) {
+			s.srv.dropItem(args[0]) // as memcached: the old value must not outlive a failed set
+		}
+		s.reply("SERVER_ERROR object too large for cache")
+		return nil
+	}
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(s.r, data); err != nil {
+		return err
+	}
+	if data[size] != '\r' || data[size+1] != '\n' {
+		s.reply("CLIENT_ERROR bad data chunk")
+		return nil
+	}
+	data = data[:size]
+
+	key := args[0]
+	flags, errF := strconv.ParseUint(args[1], 10, 32)
+	exptime, errE := strconv.ParseInt(args[2], 10, 64)
+	var casUnique uint64
+	var errC error
+	if cmd == "cas" {
+		casUnique, errC = strconv.ParseUint(args[4], 10, 64)
+	}
+	if !validKey(key) || errF != nil || errE != nil || errC != nil {
+		s.reply("CLIENT_ERROR bad command line format")
+		return nil
+	}
+	s.srv.cmdSet.Add(1)
+	result := s.srv.storeItem(cmd, key, &Item{Flags: uint32(flags), Data: data}, exptime, casUnique)
+	if !quiet {
+		s.reply(result)
+	}
+	return nil
+}
+
+// storeItem applies a storage command atomically and returns the reply.
+func (srv *Server) storeItem(cmd, key string, it *Item, exptime int64, casUnique uint64) string {
+	srv.writeMu.Lock()
+	defer srv.writeMu.Unlock()
+
+	info, exists := srv.cache.Inspect(key)
+	switch cmd {
+	case "add":
+		if exists {
+			return replyNotStored
+		}
+	case "replace":
+		if !exists {
+			return replyNotStored
+		}
+	case "cas":
+		if !exists {
+			return replyNotFound
+		}
+		if info.Version != casUnique {
+			return replyExists
+		}
+	}
+
+	ttl, expired := ttlFromExptime(exptime, time.Now())
+	if expired {
+		// Stored and immediately expired: nothing stays visible.
+		srv.cache.Remove(key)
+		return replyStored
+	}
+	if err := srv.cache.PutWithTTL(key, it, ttl); err != nil {
+		return "SERVER_ERROR object too large for cache" // the old value is gone too
+	}
+	return replyStored
+}
+
+// dropItem removes key after a set too large to store.
+func (srv *Server) dropItem(key string) {
+	srv.writeMu.Lock()
+	defer srv.writeMu.Unlock()
+	srv.cache.Remove(key)
+}
+
+// delete handles "delete <key> [noreply]".
+func (s *session) delete(args []string) {
+	args, quiet := noreply(args)
+	if len(args) != 1 {
+		s.reply("CLIENT_ERROR bad command line format")
+		return
+	}
+	s.srv.writeMu.Lock()
+	removed := s.srv.cache.Remove(args[0])
+	s.srv.writeMu.Unlock()
+	if quiet {
+		return
+	}
+	if removed {
+		s.reply(replyDeleted)
+	} else {
+		s.reply(replyNotFound)
+	}
+}
+
+// incrDecr handles "incr|decr <key> <delta> [noreply]". incr wraps at
+// 2^64; decr stops at 0. Flags and expiry are preserved.
+func (s *session) incrDecr(incr bool, args []string) {
+	args, quiet := noreply(args)
+	if len(args) != 2 {
+		s.reply(replyError)
+		return
+	}
+	delta, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		s.reply("CLIENT_ERROR invalid numeric delta argument")
+		return
+	}
+	result := s.srv.incrDecr(args[0], incr, delta)
+	if !quiet {
+		s.reply(result)
+	}
+}
+
+func (srv *Server) incrDecr(key string, incr bool, delta uint64) string {
+	srv.writeMu.Lock()
+	defer srv.writeMu.Unlock()
+
+	info, ok := srv.cache.Inspect(key)
+	if !ok {
+		return replyNotFound
+	}
+	it := info.Value.(*Item)
+	n, err := strconv.ParseUint(strings.TrimSpace(string(it.Data)), 10, 64)
+	if err != nil {
+		return "CLIENT_ERROR cannot increment or decrement non-numeric value"
+	}
+	switch {
+	case incr:
+		n += delta
+	case delta > n:
+		n = 0
+	default:
+		n -= delta
+	}
+
+	var ttl time.Duration
+	if !info.ExpiresAt.IsZero() {
+		if ttl = time.Until(info.ExpiresAt); ttl <= 0 {
+			return replyNotFound
+		}
+	}
+	value := strconv.FormatUint(n, 10)
+	if err := srv.cache.PutWithTTL(key, &Item{Flags: it.Flags, Data: []byte(value)}, ttl); err != nil {
+		return "SERVER_ERROR out of memory"
+	}
+	return value
+}
+
+// flushAll handles "flush_all [delay] [noreply]".
+func (s *session) flushAll(args []string) {
+	args, quiet := noreply(args)
+	var delay int64
+	if len(args) > 0 {
+		var err error
+		if delay, err = strconv.ParseInt(args[0], 10, 64); err != nil || len(args) > 1 {
+			s.reply("CLIENT_ERROR bad command line format")
+			return
+		}
+	}
+	flush := func() {
+		s.srv.writeMu.Lock()
+		s.srv.cache.Clear()
+		s.srv.writeMu.Unlock()
+	}
+	if delay > 0 {
+		time.AfterFunc(time.Duration(delay)*time.Second, flush)
+	} else {
+		flush()
+	}
+	if !quiet {
+		s.reply(replyOK)
+	}
+}
+
+// stats handles "stats". Sub-groups ("stats items", ...) are not
+// supported and return an empty listing.
+func (s *session) stats(args []string) {
+	if len(args) == 0 {
+		srv := s.srv
+		cs := srv.cache.Stats()
+		now := time.Now()
+		for _, kv := range []struct {
+			name  string
+			value interface{}
+		}{
+			{"pid", os.Getpid()},
+			{"uptime", int64(now.Sub(srv.started).Seconds())},
+			{"time", now.Unix()},
+			{"version", Version},
+			{"curr_connections", srv.currConns.Load()},
+			{"total_connections", srv.totalConns.Load()},
+			{"cmd_get", srv.cmdGet.Load()},
+			{"cmd_set", srv.cmdSet.Load()},
+			{"get_hits", cs.Hits},
+			{"get_misses", cs.Misses},
+			{"curr_items", cs.Entries},
+			{"bytes", cs.Weight},
+			{"evictions", cs.Evictions},
+			{"expired", cs.Expirations},
+		} {
+			fmt.Fprintf(s.w, "STAT %s %v\r\n", kv.name, kv.value)
+		}
+	}
+	s.reply(replyEnd)
+}
@@ -0,0 +1,175 @@
+package memcache
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"lru-cache/internal/lrucache"
+)
+
+// Version is reported by the "version" command and "stats".
+const Version = "1.6.0-lrucache"
+
+// Server exposes an LRUCache over TCP using the memcached text protocol,
+// so standard memcached clients can talk to it.
+//
+// Reads go straight to the cache. Writes (set/add/replace/cas/incr/decr/
+// delete/flush_all) are serialized by the server so that check-then-write
+// commands are atomic; CAS tokens are the cache's per-entry versions.
+type Server struct {
+	cache   *lrucache.LRUCache
+	maxItem int64 // largest accepted data block, in bytes
+
+	writeMu sync.Mutex // serializes mutating commands
+
+	mu       sync.Mutex // guards listener, conns and closed
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+
+	started    time.Time
+	currConns  atomic.Int64
+	totalConns atomic.Uint64
+	cmdGet     atomic.Uint64
+	cmdSet     atomic.Uint64
+}
+
+// NewServer wraps cache. Items larger than maxItemSize bytes are refused
+// with SERVER_ERROR (0 means 1 MiB, memcached's default).
+// (Factory Pattern)
+func NewServer(cache *lrucache.LRUCache, maxItemSize int64) *Server {
+	if maxItemSize <= 0 {
+		maxItemSize = 1 << 20
+	}
+	return &Server{
+		cache:   cache,
+		maxItem: maxItemSize,
+		conns:   make(map[net.Conn]struct{}),
+		started: time.Now(),
+	}
+}
+
+// NewCache builds an LRUCache suitable for NewServer: capacity is
+// measured in bytes of key+data, as in memcached's -m flag.
+func NewCache(maxBytes int64) *lrucache.LRUCache {
+	return lrucache.NewWithOptions(lrucache.Options{
+		MaxWeight: maxBytes,
+		Weigher: func(key, value interface{}) int64 {
+			return int64(len(key.(string)) + len(value.(*Item).Data))
+		},
+	})
+}
+
+// ListenAndServe listens on addr and serves until Close.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln, handling each in its own goroutine.
+// It returns nil after Close.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		ln.Close()
+		return nil
+	}
+	s.listener = ln
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		if !s.track(conn) {
+			conn.Close()
+			return nil
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Addr returns the listening address, or nil before Serve.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Close stops accepting connections and closes every open connection.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	return err
+}
+
+// track registers conn, refusing it if the server is closed.
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.currConns.Add(1)
+	s.totalConns.Add(1)
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+	s.currConns.Add(-1)
+}
+
+// serveConn runs the request/response loop for one client.
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.untrack(conn)
+	defer conn.Close()
+
+	sess := &session{
+		srv: s,
+		r:   bufio.NewReaderSize(conn, 4096),
+		w:   bufio.NewWriter(conn),
+	}
+	for {
+		quit, err := sess.handle()
+		if err != nil || quit {
+			sess.w.Flush()
+			return
+		}
+		// Only flush once the client has no more pipelined input.
+		if sess.r.Buffered() == 0 {
+			if err := sess.w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -1,10 +1,141 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"pubsub-system/internal/admin"
+	"pubsub-system/internal/commitlog"
+	"pubsub-system/internal/netbroker"
 	"pubsub-system/internal/pubsub"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// slowSubscriber takes a while to process each message.
+type slowSubscriber struct {
+	name  string
+	delay time.Duration
+}
+
+func (s *slowSubscriber) OnMessage(msg *pubsub.Message) error {
+	time.Sleep(s.delay)
+	fmt.Printf("[%s] processed: %s\n", s.name, msg.Text())
+	return nil
+}
+
+// flakySubscriber fails the first attempts of every message, never
+// processes "poison" messages, and hangs on "hang" messages.
+type flakySubscriber struct {
+	failures int // attempts to fail before succeeding
+}
+
+func (f *flakySubscriber) OnMessage(msg *pubsub.Message) error {
+	switch {
+	case strings.HasPrefix(msg.Text(), "poison"):
+		return errors.New("cannot parse message")
+	case strings.HasPrefix(msg.Text(), "hang") && msg.DeliveryAttempt == 1:
+		time.Sleep(200 * time.Millisecond) // misses the ack deadline
+		return nil
+	case msg.DeliveryAttempt <= f.failures:
+		fmt.Printf("[Flaky] attempt %d of %q failed\n", msg.DeliveryAttempt, msg.Text())
+		return errors.New("temporary failure")
+	}
+	fmt.Printf("[Flaky] attempt %d of %q acked\n", msg.DeliveryAttempt, msg.Text())
+	return nil
+}
+
+// workerSubscriber records which partitions its messages came from.
+type workerSubscriber struct {
+	name string
+}
+
+func (w *workerSubscriber) OnMessage(msg *pubsub.Message) error {
+	fmt.Printf("[%s] p%d@%d %s: %s\n", w.name, msg.Partition, msg.Offset, msg.Key, msg.Text())
+	return nil
+}
+
+// OrderPlaced is a typed event published with each codec.
+type OrderPlaced struct {
+	OrderID  string   `pb:"1"`
+	Customer string   `pb:"2"`
+	Items    []string `pb:"3"`
+	Total    float64  `pb:"4"`
+}
+
+// eventSubscriber decodes any payload generically via its content type
+// and dispatches on the event-type header.
+type eventSubscriber struct{}
+
+func (eventSubscriber) OnMessage(msg *pubsub.Message) error {
+	switch msg.Header(pubsub.EventTypeHeader) {
+	case pubsub.EventType(OrderPlaced{}):
+		var ev OrderPlaced
+		if err := msg.Decode(&ev); err != nil {
+			return err
+		}
+		fmt.Printf("[Events] %s… %-27s %3d bytes key=%s trace=%s %+v\n",
+			msg.ID[:8], msg.ContentType, len(msg.Payload), msg.Key, msg.Header("trace-id"), ev)
+	default:
+		fmt.Printf("[Events] %s… %s\n", msg.ID[:8], msg)
+	}
+	return nil
+}
+
+// topicSubscriber prints which topic each message arrived on.
+type topicSubscriber struct {
+	name string
+}
+
+func (t *topicSubscriber) OnMessage(msg *pubsub.Message) error {
+	fmt.Printf("[%s] %s: %s\n", t.name, msg.Topic, msg)
+	return nil
+}
+
+// priceService answers price requests through the broker.
+type priceService struct {
+	broker *pubsub.Broker
+}
+
+func (p *priceService) OnMessage(msg *pubsub.Message) error {
+	price := map[string]string{"apple": "0.50", "pear": "0.80"}[msg.Text()]
+	return p.broker.Reply(msg, pubsub.NewMessage(msg.Text()+" costs "+price))
+}
+
+// parserSubscriber rejects "poison" messages until it is fixed.
+type parserSubscriber struct {
+	fixed atomic.Bool
+}
+
+func (p *parserSubscriber) OnMessage(msg *pubsub.Message) error {
+	if strings.HasPrefix(msg.Text(), "poison") && !p.fixed.Load() {
+		return errors.New("cannot parse message")
+	}
+	fmt.Printf("[Parser] handled %q (attempt %d)\n", msg.Text(), msg.DeliveryAttempt)
+	return nil
+}
+
+// reminderSubscriber prints when each scheduled message arrives.
+type reminderSubscriber struct {
+	start time.Time
+	wg    *sync.WaitGroup
+}
+
+func (r *reminderSubscriber) OnMessage(msg *pubsub.Message) error {
+	fmt.Printf("[Reminder] +%dms: %s\n", time.Since(r.start).Round(50*time.Millisecond).Milliseconds(), msg)
+	r.wg.Done()
+	return nil
+}
+
 func main() {
 	// Create two topics
 	topic1 := pubsub.NewTopic("Topic1")
@@ -41,6 +172,581 @@ func main() {
 	pub1.Publish(topic1, pubsub.NewMessage("Message3 for Topic1"))
 	pub2.Publish(topic2, pubsub.NewMessage("Message2 for Topic2"))
 
-	// Give goroutines time to print
-	time.Sleep(1 * time.Second)
+	// pub2 never registered topic1: the error is returned to the caller
+	if err := pub2.Publish(topic1, pubsub.NewMessage("Sneaky message")); err != nil {
+		fmt.Println("Rejected:", err)
+	}
+
+	// Wait for every queued message to be delivered
+	topic1.Close()
+	topic2.Close()
+
+	demoOverflow()
+	demoRedelivery()
+	demoDurable()
+	demoConsumerGroups()
+	demoEvents()
+	demoBroker()
+	demoFilters()
+	demoNetwork()
+	demoACL()
+	demoRequestReply()
+	demoScheduled()
+	demoExactlyOnce()
+	demoAdmin()
+}
+
+// demoOverflow shows that a slow subscriber no longer blocks the
+// publisher, and how each overflow policy treats a full queue.
+func demoOverflow() {
+	fmt.Println("\n--- Slow subscribers & overflow policies ---")
+	topic := pubsub.NewTopic("Events")
+
+	fast := pubsub.NewPrintSubscriber("Fast")
+	topic.AddSubscriber(fast)
+
+	policies := []pubsub.OverflowPolicy{pubsub.DropOldest, pubsub.DropNewest, pubsub.Disconnect}
+	var subs []*pubsub.Subscription
+	for _, policy := range policies {
+		slow := &slowSubscriber{name: "Slow-" + policy.String(), delay: 50 * time.Millisecond}
+		sub, _ := topic.Subscribe(slow, pubsub.SubscriptionOptions{QueueSize: 2, Overflow: policy})
+		subs = append(subs, sub)
+	}
+
+	start := time.Now()
+	for i := 1; i <= 5; i++ {
+		topic.Publish(pubsub.NewMessage(fmt.Sprintf("event-%d", i)))
+	}
+	fmt.Printf("Published 5 events in %v (not waiting on slow subscribers)\n", time.Since(start).Round(time.Millisecond))
+
+	topic.Close()
+	for i, sub := range subs {
+		fmt.Printf("%s: dropped=%d err=%v\n", policies[i], sub.Dropped(), sub.Err())
+	}
+}
+
+// demoRedelivery shows nacks with exponential backoff, the ack deadline,
+// and poison messages routed to a dead-letter topic.
+func demoRedelivery() {
+	fmt.Println("\n--- Acks, redelivery & dead letters ---")
+	orders := pubsub.NewTopic("Orders")
+	deadLetters := pubsub.NewTopic("Orders.DLQ")
+	deadLetters.AddSubscriber(pubsub.NewPrintSubscriber("DLQ"))
+
+	sub, _ := orders.Subscribe(&flakySubscriber{failures: 2}, pubsub.SubscriptionOptions{
+		AckDeadline: 100 * time.Millisecond,
+		Retry: pubsub.RetryPolicy{
+			MaxAttempts:    4,
+			InitialBackoff: 10 * time.Millisecond,
+			Multiplier:     2,
+		},
+		DeadLetter: deadLetters,
+	})
+
+	orders.Publish(pubsub.NewMessage("order-1"))
+	orders.Publish(pubsub.NewMessage("poison-order"))
+	orders.Publish(pubsub.NewMessage("hang-order"))
+
+	orders.Close() // waits for redeliveries to settle
+	deadLetters.Close()
+	fmt.Printf("acked=%d redelivered=%d dead-lettered=%d\n", sub.Acked(), sub.Redelivered(), sub.DeadLettered())
+}
+
+// demoDurable shows a log-backed topic surviving a restart and new
+// subscribers replaying its history from different start positions.
+func demoDurable() {
+	fmt.Println("\n--- Durable topics ---")
+	dir, err := os.MkdirTemp("", "pubsub-log")
+	if err != nil {
+		fmt.Println("temp dir:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "Payments")
+	opts := commitlog.Options{SegmentBytes: 256, Fsync: commitlog.FsyncAlways}
+
+	payments, err := pubsub.NewDurableTopic("Payments", path, opts)
+	if err != nil {
+		fmt.Println("open:", err)
+		return
+	}
+	for i := 1; i <= 5; i++ {
+		payments.Publish(pubsub.NewMessage(fmt.Sprintf("payment-%d", i)))
+		time.Sleep(2 * time.Millisecond)
+	}
+	cutoff := time.Now()
+	payments.Publish(pubsub.NewMessage("payment-6"))
+	payments.Close()
+
+	// Reopen: history and offsets are restored from disk.
+	payments, err = pubsub.NewDurableTopic("Payments", path, opts)
+	if err != nil {
+		fmt.Println("reopen:", err)
+		return
+	}
+	payments.Subscribe(pubsub.NewPrintSubscriber("Replay-All"), pubsub.SubscriptionOptions{StartAt: pubsub.StartEarliest()})
+	payments.Subscribe(pubsub.NewPrintSubscriber("From-Offset-3"), pubsub.SubscriptionOptions{StartAt: pubsub.StartAtOffset(3)})
+	payments.Subscribe(pubsub.NewPrintSubscriber("Since-Cutoff"), pubsub.SubscriptionOptions{StartAt: pubsub.StartAtTime(cutoff)})
+	payments.Subscribe(pubsub.NewPrintSubscriber("Live"), pubsub.SubscriptionOptions{})
+
+	msg := pubsub.NewMessage("payment-7")
+	payments.Publish(msg)
+	payments.Close() // subscribers catch up with the log before closing
+	fmt.Printf("payment-7 stored at offset %d\n", msg.Offset)
+}
+
+// demoConsumerGroups shows keyed partitioning, a consumer group
+// rebalancing as members join and leave, and committed offsets letting
+// the group resume after a restart.
+func demoConsumerGroups() {
+	fmt.Println("\n--- Partitions & consumer groups ---")
+	dir, err := os.MkdirTemp("", "pubsub-groups")
+	if err != nil {
+		fmt.Println("temp dir:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	opts := commitlog.Options{Fsync: commitlog.FsyncNever}
+	start := pubsub.SubscriptionOptions{StartAt: pubsub.StartEarliest()}
+
+	orders, err := pubsub.NewPartitionedTopic("Orders", dir, 3, opts)
+	if err != nil {
+		fmt.Println("open:", err)
+		return
+	}
+	publish := func(round int) {
+		for _, customer := range []string{"alice", "dave", "ivan"} {
+			orders.Publish(pubsub.NewKeyedMessage(customer, fmt.Sprintf("order-%d", round)))
+		}
+	}
+
+	a, _ := orders.JoinGroup("billing", &workerSubscriber{name: "Worker-A"}, start)
+	publish(1)
+	b, _ := orders.JoinGroup("billing", &workerSubscriber{name: "Worker-B"}, start)
+	fmt.Printf("after B joined: A=%v B=%v\n", a.Partitions(), b.Partitions())
+	publish(2)
+	time.Sleep(20 * time.Millisecond)
+	a.Leave()
+	fmt.Printf("after A left: B=%v\n", b.Partitions())
+	publish(3)
+	orders.Close() // commits the group's offsets
+	fmt.Println("round 4 published while billing is offline")
+
+	orders, err = pubsub.NewPartitionedTopic("Orders", dir, 3, opts)
+	if err != nil {
+		fmt.Println("reopen:", err)
+		return
+	}
+	publish(4)
+	c, _ := orders.JoinGroup("billing", &workerSubscriber{name: "Worker-C"}, start)
+	time.Sleep(20 * time.Millisecond)
+	fmt.Printf("resumed from committed offsets: %v\n", c.Group().Committed())
+	orders.Close()
+}
+
+// demoEvents publishes the same typed event with each codec; the
+// subscriber decodes them without knowing which codec was used.
+func demoEvents() {
+	fmt.Println("\n--- Message envelope & codecs ---")
+	events := pubsub.NewTopic("Events")
+	events.AddSubscriber(eventSubscriber{})
+
+	order := OrderPlaced{OrderID: "o-42", Customer: "alice", Items: []string{"book", "pen"}, Total: 17.5}
+	codecs := []pubsub.Codec{pubsub.JSONCodec{}, pubsub.GobCodec{}, pubsub.BinaryCodec{}}
+	for i, codec := range codecs {
+		msg, err := pubsub.NewEvent(order, codec)
+		if err != nil {
+			fmt.Println("encode:", err)
+			continue
+		}
+		msg.Key = order.Customer
+		msg.SetHeader("trace-id", fmt.Sprintf("t-%d", i+1))
+		events.Publish(msg)
+	}
+	events.Publish(pubsub.NewMessage("plain text still works"))
+	events.Close()
+}
+
+// demoBroker shows topics owned by name, MQTT- and NATS-style wildcard
+// subscriptions, and topics auto-created on first publish.
+func demoBroker() {
+	fmt.Println("\n--- Broker & wildcard subscriptions ---")
+	broker := pubsub.NewBroker(pubsub.BrokerOptions{AutoCreate: true})
+	for _, pattern := range []string{"orders.+.created", "orders.#", "orders.eu.created"} {
+		if _, err := broker.Subscribe(pattern, &topicSubscriber{name: pattern}, pubsub.SubscriptionOptions{}); err != nil {
+			fmt.Println("subscribe:", err)
+		}
+	}
+	if _, err := broker.Subscribe("orders.#.created", &topicSubscriber{}, pubsub.SubscriptionOptions{}); err != nil {
+		fmt.Println("rejected:", err)
+	}
+	for _, topic := range []string{"orders", "orders.eu.created", "orders.us.created", "orders.eu.cancelled", "payments.eu.created"} {
+		broker.Publish(topic, pubsub.NewMessage("event on "+topic))
+	}
+	fmt.Println("auto-created topics:", broker.Topics())
+	broker.Close()
+
+	nats := pubsub.NewBroker(pubsub.BrokerOptions{Wildcards: pubsub.NATSWildcards})
+	nats.Subscribe("orders.>", &topicSubscriber{name: "orders.>"}, pubsub.SubscriptionOptions{})
+	nats.Subscribe("*.eu.*", &topicSubscriber{name: "*.eu.*"}, pubsub.SubscriptionOptions{})
+	if err := nats.Publish("orders", pubsub.NewMessage("no such topic yet")); err != nil {
+		fmt.Println("publish:", err)
+	}
+	for _, topic := range []string{"orders", "orders.eu.created"} {
+		nats.CreateTopic(topic)
+		nats.Publish(topic, pubsub.NewMessage("event on "+topic))
+	}
+	nats.Close()
+}
+
+// demoFilters shows subscriptions that only receive messages matching a
+// filter over headers and attributes, and a filter rejected at
+// subscribe time.
+func demoFilters() {
+	fmt.Println("\n--- Content filters ---")
+	payments := pubsub.NewTopic("Payments")
+	filters := map[string]string{
+		"Big-EU":   "region IN ('eu', 'uk') AND amount >= 100",
+		"Alice":    "$key = 'alice' AND NOT EXISTS(test)",
+		"Flagged":  "flagged = true OR (amount > 1000 AND region != 'eu')",
+		"Broken":   "amount >= 'lots'",
+		"Unclosed": "region IN ('eu'",
+	}
+	var subs []*pubsub.Subscription
+	var names []string
+	for _, name := range []string{"Big-EU", "Alice", "Flagged", "Broken", "Unclosed"} {
+		sub, err := payments.Subscribe(&topicSubscriber{name: name}, pubsub.SubscriptionOptions{Filter: filters[name]})
+		if err != nil {
+			fmt.Printf("%s rejected: %v\n", name, err)
+			continue
+		}
+		subs, names = append(subs, sub), append(names, name)
+	}
+
+	payment := func(key, region, amount string, extra ...string) *pubsub.Message {
+		msg := pubsub.NewKeyedMessage(key, fmt.Sprintf("%s pays %s in %s", key, amount, region))
+		msg.SetHeader("region", region)
+		msg.SetHeader("amount", amount)
+		for i := 0; i+1 < len(extra); i += 2 {
+			msg.SetHeader(extra[i], extra[i+1])
+		}
+		return msg
+	}
+	payments.Publish(payment("alice", "eu", "250"))
+	payments.Publish(payment("alice", "us", "40", "test", "yes"))
+	payments.Publish(payment("bob", "uk", "99.5"))
+	payments.Publish(payment("bob", "us", "5000"))
+	payments.Publish(payment("carol", "eu", "10", "flagged", "true"))
+	payments.Close()
+	for i, sub := range subs {
+		fmt.Printf("%s: delivered=%d filtered=%d\n", names[i], sub.Acked(), sub.Filtered())
+	}
+}
+
+// demoNetwork runs a broker server on a loopback port and connects two
+// clients: one subscribes with the same Subscriber types used
+// in-process, the other publishes.
+func demoNetwork() {
+	fmt.Println("\n--- Network broker ---")
+	broker := pubsub.NewBroker(pubsub.BrokerOptions{AutoCreate: true})
+	srv := netbroker.NewServer(broker)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Println("listen:", err)
+		return
+	}
+	go srv.Serve(ln)
+	addr := ln.Addr().String()
+
+	consumer, err := netbroker.Dial(addr, netbroker.ClientOptions{ClientID: "billing"})
+	if err != nil {
+		fmt.Println("dial:", err)
+		return
+	}
+	producer, err := netbroker.Dial(addr, netbroker.ClientOptions{})
+	if err != nil {
+		fmt.Println("dial:", err)
+		return
+	}
+	fmt.Printf("connected as %q and %q\n", consumer.ClientID(), producer.ClientID())
+
+	consumer.Subscribe("orders.#", &topicSubscriber{name: "Remote"}, pubsub.SubscriptionOptions{Filter: "$key != 'test'"})
+	consumer.Subscribe("orders.eu.created", &flakySubscriber{failures: 1}, pubsub.SubscriptionOptions{
+		Retry: pubsub.RetryPolicy{InitialBackoff: 10 * time.Millisecond},
+	})
+	if _, err := consumer.Subscribe("orders.#.x", &topicSubscriber{}, pubsub.SubscriptionOptions{}); err != nil {
+		fmt.Println("server rejected:", err)
+	}
+
+	for _, topic := range []string{"orders.eu.created", "orders.us.shipped"} {
+		msg := pubsub.NewKeyedMessage("alice", "remote "+topic)
+		if err := producer.Publish(topic, msg); err != nil {
+			fmt.Println("publish:", err)
+			continue
+		}
+		fmt.Printf("published %s at offset %d\n", msg.ID[:8], msg.Offset)
+	}
+	rtt, err := producer.Ping()
+	fmt.Printf("ping: ok=%v\n", err == nil && rtt > 0)
+
+	time.Sleep(100 * time.Millisecond) // let the retry arrive
+	producer.Close()
+	consumer.Close()
+	srv.Close()
+	broker.Close()
+}
+
+// demoACL shows per-principal publish/subscribe/admin rules with
+// wildcard patterns, deny-overrides-allow, and the audit trail.
+func demoACL() {
+	fmt.Println("\n--- Access control ---")
+	acl := pubsub.NewACL(pubsub.BrokerOptions{}, "root")
+	broker := pubsub.NewBroker(pubsub.BrokerOptions{AutoCreate: true, Authorizer: acl})
+
+	acl.Allow("root", "ops", "orders.#", pubsub.ActionAdmin)
+	acl.Allow("ops", "shop", "orders.+.created", pubsub.ActionPublish)
+	acl.Allow("ops", "billing", "orders.#", pubsub.ActionSubscribe)
+	acl.Deny("ops", "billing", "orders.internal.#", pubsub.ActionSubscribe)
+	if err := acl.Allow("ops", "shop", "payments.#", pubsub.ActionPublish); err != nil {
+		fmt.Println("ACL change rejected:", err)
+	}
+
+	try := func(what string, err error) {
+		if err != nil {
+			fmt.Printf("%-38s denied: %v (permission error: %v)\n", what, err, errors.Is(err, pubsub.ErrPermissionDenied))
+			return
+		}
+		fmt.Printf("%-38s ok\n", what)
+	}
+	_, err := broker.SubscribeAs("billing", "orders.#", &topicSubscriber{name: "billing"}, pubsub.SubscriptionOptions{})
+	try("billing subscribes orders.#", err)
+	_, err = broker.SubscribeAs("billing", "orders.eu.#", &topicSubscriber{name: "billing"}, pubsub.SubscriptionOptions{})
+	try("billing subscribes orders.eu.#", err)
+	try("shop publishes orders.eu.created", broker.PublishAs("shop", "orders.eu.created", pubsub.NewMessage("order 1")))
+	try("shop publishes orders.eu.cancelled", broker.PublishAs("shop", "orders.eu.cancelled", pubsub.NewMessage("order 1")))
+	try("anonymous publishes orders.eu.created", broker.Publish("orders.eu.created", pubsub.NewMessage("spoof")))
+	try("ops deletes orders.eu.created", broker.DeleteTopicAs("ops", "orders.eu.created"))
+
+	publisher := pubsub.NewAuthorizedPublisher("billing", acl)
+	invoices := pubsub.NewTopic("invoices")
+	publisher.RegisterTopic(invoices)
+	try("billing's Publisher publishes invoices", publisher.Publish(invoices, pubsub.NewMessage("invoice")))
+	broker.Close()
+
+	fmt.Println("audit trail:")
+	for _, e := range acl.Audit() {
+		fmt.Printf("  %-4s %-6s %-5s %-7s %-17s %-9s applied=%v\n",
+			e.By, e.Op, e.Rule.Effect, e.Rule.Principal, e.Rule.Pattern, e.Rule.Actions, e.Applied)
+	}
+}
+
+// demoRequestReply sends RPC-style requests over the broker: each gets
+// a private inbox topic and waits for the first reply.
+func demoRequestReply() {
+	fmt.Println("\n--- Request/reply ---")
+	broker := pubsub.NewBroker(pubsub.BrokerOptions{AutoCreate: true})
+	defer broker.Close()
+	broker.Subscribe("rpc.price", &priceService{broker: broker}, pubsub.SubscriptionOptions{})
+
+	for _, fruit := range []string{"apple", "pear"} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		req := pubsub.NewMessage(fruit)
+		reply, err := broker.Request(ctx, "rpc.price", req)
+		cancel()
+		if err != nil {
+			fmt.Println("request:", err)
+			continue
+		}
+		fmt.Printf("reply %q (correlates: %v)\n", reply.Text(), reply.Header(pubsub.CorrelationIDHeader) == req.ID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := broker.Request(ctx, "rpc.nobody-home", pubsub.NewMessage("hello?"))
+	fmt.Println("unanswered request:", err)
+	fmt.Println("topics left behind:", broker.Topics())
+}
+
+// demoScheduled delays messages on a timer wheel. The schedule is kept
+// in a directory, so a message still pending when the broker closes is
+// published by the next broker opened on it.
+func demoScheduled() {
+	fmt.Println("\n--- Scheduled messages ---")
+	dir, err := os.MkdirTemp("", "pubsub-schedule-")
+	if err != nil {
+		fmt.Println("tempdir:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	opts := pubsub.BrokerOptions{AutoCreate: true, ScheduleDir: dir}
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	broker, err := pubsub.OpenBroker(opts)
+	if err != nil {
+		fmt.Println("open:", err)
+		return
+	}
+	broker.Subscribe("reminders", &reminderSubscriber{start: start, wg: &wg}, pubsub.SubscriptionOptions{})
+	broker.StartScheduler()
+
+	wg.Add(2)
+	broker.PublishAfter("reminders", pubsub.NewMessage("second, after 200ms"), 200*time.Millisecond)
+	broker.PublishAfter("reminders", pubsub.NewMessage("first, after 100ms"), 100*time.Millisecond)
+	cancelled := pubsub.NewMessage("never sent")
+	broker.PublishAfter("reminders", cancelled, 150*time.Millisecond)
+	broker.PublishAt("reminders", pubsub.NewMessage("after a restart"), start.Add(400*time.Millisecond))
+	fmt.Printf("scheduled=%d, cancelled=%v\n", broker.Scheduled(), broker.CancelScheduled(cancelled.ID))
+	wg.Wait()
+	broker.Close()
+	fmt.Printf("broker closed with %d message(s) pending\n", broker.Scheduled())
+
+	wg.Add(1)
+	broker, err = pubsub.OpenBroker(opts)
+	if err != nil {
+		fmt.Println("reopen:", err)
+		return
+	}
+	fmt.Printf("reopened with %d message(s) pending\n", broker.Scheduled())
+	broker.Subscribe("reminders", &reminderSubscriber{start: start, wg: &wg}, pubsub.SubscriptionOptions{})
+	broker.StartScheduler()
+	wg.Wait()
+	broker.Close()
+}
+
+// demoExactlyOnce retries publishes against a topic with a dedup
+// window, then publishes a batch to two topics in one transaction.
+func demoExactlyOnce() {
+	fmt.Println("\n--- Idempotent producers & transactions ---")
+	payments := pubsub.NewTopic("payments")
+	payments.SetDedupWindow(time.Minute)
+	payments.AddSubscriber(pubsub.NewPrintSubscriber("Ledger"))
+
+	producer := pubsub.NewIdempotentPublisher()
+	producer.RegisterTopic(payments)
+	charge := pubsub.NewMessage("charge alice $10")
+	for attempt := 1; attempt <= 3; attempt++ { // e.g. retries after a lost reply
+		producer.Publish(payments, charge)
+		fmt.Printf("attempt %d: offset=%d seq=%s\n", attempt, charge.Offset, charge.Header(pubsub.ProducerSeqHeader))
+	}
+	// A different publisher resending the same message ID is caught too
+	resend := pubsub.NewMessage("charge alice $10")
+	resend.ID = charge.ID
+	other := pubsub.NewPublisher()
+	other.RegisterTopic(payments)
+	other.Publish(payments, resend)
+	producer.Publish(payments, pubsub.NewMessage("charge bob $5"))
+	payments.Close()
+	fmt.Printf("duplicates dropped: %d\n", payments.Duplicates())
+
+	orders, stock := pubsub.NewTopic("orders"), pubsub.NewTopic("stock")
+	orders.AddSubscriber(pubsub.NewPrintSubscriber("Orders"))
+	stock.AddSubscriber(pubsub.NewPrintSubscriber("Stock"))
+	producer.RegisterTopic(orders)
+	producer.RegisterTopic(stock)
+
+	tx := producer.Begin()
+	tx.Publish(orders, pubsub.NewMessage("order 7 placed"))
+	tx.Publish(stock, pubsub.NewMessage("reserve 2 x apple"))
+	tx.Publish(orders, pubsub.NewMessage("order 7 paid"))
+	if err := tx.Commit(); err != nil {
+		fmt.Println("commit:", err)
+	}
+	abandoned := producer.Begin()
+	abandoned.Publish(orders, pubsub.NewMessage("order 8 placed"))
+	abandoned.Abort()
+	fmt.Println("commit after abort:", abandoned.Commit())
+	orders.Close()
+	stock.Close()
+}
+
+// demoAdmin watches a broker through the admin HTTP API: subscription
+// stats, a purge, Prometheus metrics, and a dead-letter replay once
+// the failing subscriber is fixed.
+func demoAdmin() {
+	fmt.Println("\n--- Metrics & admin API ---")
+	dir, err := os.MkdirTemp("", "pubsub-admin-")
+	if err != nil {
+		fmt.Println("tempdir:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	broker := pubsub.NewBroker(pubsub.BrokerOptions{AutoCreate: true})
+	defer broker.Close()
+	dlq, err := pubsub.NewDurableTopic("dlq.orders", filepath.Join(dir, "dlq"), commitlog.Options{})
+	if err != nil {
+		fmt.Println("dlq:", err)
+		return
+	}
+	broker.AddTopic(dlq)
+
+	parser := &parserSubscriber{}
+	parsing, _ := broker.Subscribe("orders.#", parser, pubsub.SubscriptionOptions{
+		Retry:      pubsub.RetryPolicy{MaxAttempts: 2, InitialBackoff: 5 * time.Millisecond},
+		DeadLetter: dlq,
+	})
+	broker.Subscribe("orders.#", &slowSubscriber{name: "Archive", delay: 50 * time.Millisecond}, pubsub.SubscriptionOptions{})
+	for _, text := range []string{"order 1", "poison order", "order 2", "order 3", "order 4"} {
+		broker.Publish("orders.eu", pubsub.NewMessage(text))
+	}
+	for parsing.Subscription().Stats().Lag > 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	srv := httptest.NewServer(admin.NewHandler(broker))
+	defer srv.Close()
+
+	var subs []admin.SubscriptionInfo
+	getJSON(srv.URL+"/api/subscriptions", &subs)
+	for _, s := range subs {
+		fmt.Printf("%s %s: delivered=%d queued=%d lag=%d dead-lettered=%d p50<=%v\n",
+			s.ID, s.Pattern, s.Delivered, s.Queued, s.Lag, s.DeadLettered, s.Latency.Quantile(0.5))
+	}
+
+	var purged map[string]int
+	postJSON(srv.URL+"/api/subscriptions/sub-2/purge", &purged)
+	fmt.Printf("purged %d queued message(s) from sub-2\n", purged["purged"])
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		fmt.Println("metrics:", err)
+		return
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "pubsub_topic_published_total") || strings.HasPrefix(line, "pubsub_subscription_dead_lettered_total") {
+			fmt.Println("metric:", line)
+		}
+	}
+	resp.Body.Close()
+
+	parser.fixed.Store(true)
+	var replay admin.ReplayResult
+	postJSON(srv.URL+"/api/dead-letters/replay?topic=dlq.orders", &replay)
+	fmt.Printf("replayed %d dead letter(s), next offset %d\n", replay.Replayed, replay.Next)
+	for parsing.Subscription().Stats().Lag > 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func getJSON(url string, v interface{}) {
+	resp, err := http.Get(url)
+	if err != nil {
+		fmt.Println("GET:", err)
+		return
+	}
+	defer resp.Body.Close()
+	json.NewDecoder(resp.Body).Decode(v)
+}
+
+func postJSON(url string, v interface{}) {
+	resp, err := http.Post(url, "application/json", nil)
+	if err != nil {
+		fmt.Println("POST:", err)
+		return
+	}
+	defer resp.Body.Close()
+	json.NewDecoder(resp.Body).Decode(v)
 }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"pubsub-system/internal/admin"
+	"pubsub-system/internal/netbroker"
+	"pubsub-system/internal/pubsub"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:7422", "TCP address to listen on")
+	autoCreate := flag.Bool("auto-create", true, "create topics on first publish")
+	adminAddr := flag.String("admin", "127.0.0.1:7423", "HTTP address for metrics and the admin API; empty disables")
+	users := flag.String("users", "", "comma-separated user:password list; when set, clients must log in")
+	nats := flag.Bool("nats", false, "use NATS-style wildcards (* and >) instead of MQTT (+ and #)")
+	flag.Parse()
+
+	opts := pubsub.BrokerOptions{AutoCreate: *autoCreate}
+	if *nats {
+		opts.Wildcards = pubsub.NATSWildcards
+	}
+	broker := pubsub.NewBroker(opts)
+	srv := netbroker.NewServer(broker)
+	if *users != "" {
+		creds := make(map[string]string)
+		for _, pair := range strings.Split(*users, ",") {
+			user, password, _ := strings.Cut(pair, ":")
+			creds[user] = password
+		}
+		srv.SetAuthenticator(netbroker.PasswordAuthenticator(creds))
+	}
+
+	if *adminAddr != "" {
+		go func() {
+			log.Printf("admin API on http://%s (metrics at /metrics)", *adminAddr)
+			if err := http.ListenAndServe(*adminAddr, admin.NewHandler(broker)); err != nil {
+				log.Printf("admin API: %v", err)
+			}
+		}()
+	}
+
+	// Shut down cleanly on Ctrl-C / SIGTERM
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		srv.Close()
+	}()
+
+	log.Printf("pubsub broker listening on %s (%s wildcards)", *addr, opts.Wildcards)
+	if err := srv.ListenAndServe(*addr); err != nil {
+		log.Fatal(err)
+	}
+	broker.Close()
+}
@@ -0,0 +1,228 @@
+// Package admin serves a pubsub.Broker's metrics and an HTTP API to
+// inspect and operate it.
+//
+//	GET  /metrics                          Prometheus text format
+//	GET  /api/topics                       per-topic stats
+//	GET  /api/subscriptions                per-subscription stats
+//	GET  /api/groups                       per-group commit positions and lag
+//	POST /api/subscriptions/{id}/purge     discard a subscription's queue
+//	POST /api/dead-letters/replay?topic=T&from=N&max=M
+//	                                       republish dead letters from T
+//
+// The API is unauthenticated: bind it to a private address.
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"pubsub-system/internal/pubsub"
+)
+
+// SubscriptionInfo is one entry of GET /api/subscriptions. Pattern is
+// the broker pattern, or the name of the topic (or partition) the
+// subscription was made on; Group names its consumer group, if any.
+type SubscriptionInfo struct {
+	ID      string `json:"id"`
+	Pattern string `json:"pattern"`
+	Group   string `json:"group,omitempty"`
+	pubsub.SubscriptionStats
+}
+
+// GroupInfo is one entry of GET /api/groups.
+type GroupInfo struct {
+	Topic     string         `json:"topic"`
+	Group     string         `json:"group"`
+	Committed map[int]uint64 `json:"committed"`
+	Lag       map[int]uint64 `json:"lag"` // uncommitted messages per partition
+}
+
+// ReplayResult is the reply to POST /api/dead-letters/replay.
+type ReplayResult struct {
+	Replayed int    `json:"replayed"`
+	Next     uint64 `json:"next"` // pass as from to continue
+}
+
+// Handler serves the admin API for a broker, and for any partitioned
+// topics added to it.
+type Handler struct {
+	broker *pubsub.Broker
+	mux    *http.ServeMux
+
+	mu          sync.Mutex
+	partitioned []*pubsub.PartitionedTopic
+}
+
+// subscription is a listed subscription with its labels.
+type subscription struct {
+	id, pattern, group string
+	sub                *pubsub.Subscription
+}
+
+// NewHandler builds the admin API for broker (Factory Pattern).
+func NewHandler(broker *pubsub.Broker) *Handler {
+	h := &Handler{broker: broker, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /metrics", h.metrics)
+	h.mux.HandleFunc("GET /api/topics", h.topics)
+	h.mux.HandleFunc("GET /api/subscriptions", h.subscriptions)
+	h.mux.HandleFunc("GET /api/groups", h.groups)
+	h.mux.HandleFunc("POST /api/subscriptions/{id}/purge", h.purge)
+	h.mux.HandleFunc("POST /api/dead-letters/replay", h.replay)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) { h.mux.ServeHTTP(w, r) }
+
+// AddPartitionedTopic adds pt's partition subscriptions and consumer
+// groups to the API. The broker does not manage partitioned topics, so
+// they are registered here.
+func (h *Handler) AddPartitionedTopic(pt *pubsub.PartitionedTopic) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.partitioned = append(h.partitioned, pt)
+}
+
+func (h *Handler) partitionedTopics() []*pubsub.PartitionedTopic {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]*pubsub.PartitionedTopic(nil), h.partitioned...)
+}
+
+// Topics snapshots every topic, sorted by name.
+func (h *Handler) Topics() []pubsub.TopicStats {
+	var out []pubsub.TopicStats
+	for _, name := range h.broker.Topics() {
+		if t, ok := h.broker.Topic(name); ok { // may have been deleted meanwhile
+			out = append(out, t.Stats())
+		}
+	}
+	return out
+}
+
+// Subscriptions snapshots every subscription: the broker's pattern
+// subscriptions, oldest first, then the members' subscriptions of each
+// consumer group, then those made directly on a broker topic or a
+// partition.
+func (h *Handler) Subscriptions() []SubscriptionInfo {
+	var out []SubscriptionInfo
+	for _, s := range h.all() {
+		out = append(out, SubscriptionInfo{ID: s.id, Pattern: s.pattern, Group: s.group, SubscriptionStats: s.sub.Stats()})
+	}
+	return out
+}
+
+// all lists the subscriptions reported by Subscriptions.
+func (h *Handler) all() []subscription {
+	var out []subscription
+	seen := make(map[*pubsub.Subscription]bool)
+	add := func(pattern, group string, sub *pubsub.Subscription) {
+		if !seen[sub] {
+			seen[sub] = true
+			out = append(out, subscription{id: sub.ID(), pattern: pattern, group: group, sub: sub})
+		}
+	}
+	for _, bs := range h.broker.Subscriptions() {
+		add(bs.Pattern, "", bs.Subscription())
+	}
+	for _, pt := range h.partitionedTopics() {
+		for _, g := range pt.Groups() {
+			for _, sub := range g.Subscriptions() {
+				add(pt.Name, g.Name, sub)
+			}
+		}
+	}
+	for _, name := range h.broker.Topics() {
+		if t, ok := h.broker.Topic(name); ok {
+			for _, sub := range t.Subscriptions() {
+				add(name, "", sub)
+			}
+		}
+	}
+	for _, pt := range h.partitionedTopics() {
+		for i := range pt.Partitions() {
+			p := pt.Partition(i)
+			for _, sub := range p.Subscriptions() {
+				add(p.Name, "", sub)
+			}
+		}
+	}
+	return out
+}
+
+// Groups snapshots the commit positions and lag of every consumer group
+// of the added partitioned topics.
+func (h *Handler) Groups() []GroupInfo {
+	var out []GroupInfo
+	for _, pt := range h.partitionedTopics() {
+		for _, g := range pt.Groups() {
+			out = append(out, GroupInfo{Topic: pt.Name, Group: g.Name, Committed: g.Committed(), Lag: g.Lag()})
+		}
+	}
+	return out
+}
+
+func (h *Handler) topics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.Topics())
+}
+
+func (h *Handler) subscriptions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.Subscriptions())
+}
+
+func (h *Handler) groups(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.Groups())
+}
+
+func (h *Handler) purge(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	for _, s := range h.all() {
+		if s.id == id {
+			writeJSON(w, http.StatusOK, map[string]int{"purged": s.sub.Purge()})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "no subscription "+id)
+}
+
+func (h *Handler) replay(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var from uint64
+	var limit int
+	var err error
+	if s := q.Get("from"); s != "" {
+		if from, err = strconv.ParseUint(s, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "bad from: "+s)
+			return
+		}
+	}
+	if s := q.Get("max"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil {
+			writeError(w, http.StatusBadRequest, "bad max: "+s)
+			return
+		}
+	}
+	n, next, err := h.broker.ReplayDeadLetters(q.Get("topic"), from, limit)
+	switch {
+	case errors.Is(err, pubsub.ErrNoSuchTopic):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, pubsub.ErrNotDurable):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, ReplayResult{Replayed: n, Next: next})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,88 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"pubsub-system/internal/commitlog"
+	"pubsub-system/internal/pubsub"
+)
+
+type ackSubscriber struct{}
+
+func (ackSubscriber) OnMessage(*pubsub.Message) error { return nil }
+
+func TestListsTopicAndGroupSubscriptions(t *testing.T) {
+	broker := pubsub.NewBroker(pubsub.BrokerOptions{AutoCreate: true})
+	defer broker.Close()
+	broker.Subscribe("audit.#", ackSubscriber{}, pubsub.SubscriptionOptions{})
+	audit, err := broker.CreateTopic("audit.eu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	direct, _ := audit.Subscribe(ackSubscriber{}, pubsub.DefaultSubscriptionOptions())
+	orders, err := pubsub.NewPartitionedTopic("orders", t.TempDir(), 2, commitlog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer orders.Close()
+	orders.JoinGroup("billing", ackSubscriber{}, pubsub.SubscriptionOptions{})
+
+	h := NewHandler(broker)
+	h.AddPartitionedTopic(orders)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	var subs []SubscriptionInfo
+	get(t, srv.URL+"/api/subscriptions", &subs)
+	var got []string
+	for _, s := range subs {
+		got = append(got, s.ID+" "+s.Pattern+" "+s.Group)
+	}
+	want := []string{"sub-1 audit.# ", "orders-0/1 orders billing", "orders-1/1 orders billing", "audit.eu/1 audit.eu "}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("subscriptions = %q, want %q", got, want)
+	}
+
+	resp, err := http.Post(srv.URL+"/api/subscriptions/"+url.PathEscape(direct.ID())+"/purge", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("purge %s: status %d", direct.ID(), resp.StatusCode)
+	}
+
+	orders.Publish(pubsub.NewKeyedMessage("alice", "order"))
+	var groups []GroupInfo
+	get(t, srv.URL+"/api/groups", &groups)
+	if len(groups) != 1 || groups[0].Group != "billing" || len(groups[0].Lag) != 2 {
+		t.Fatalf("groups = %+v", groups)
+	}
+	resp, err = http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `pubsub_group_lag{topic="orders",group="billing",partition="1"}`) {
+		t.Fatalf("no group lag in metrics:\n%s", body)
+	}
+}
+
+func get(t *testing.T, url string, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
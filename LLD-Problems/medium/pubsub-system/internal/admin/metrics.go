@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"pubsub-system/internal/pubsub"
+)
+
+// metrics writes every topic, subscription and consumer group metric in the Prometheus
+// text exposition format (version 0.0.4).
+func (h *Handler) metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	topics := h.Topics()
+	topicMetric := func(name, typ, help string, value func(pubsub.TopicStats) float64) {
+		header(bw, name, typ, help)
+		for _, t := range topics {
+			sample(bw, name, labels("topic", t.Name), value(t))
+		}
+	}
+	topicMetric("pubsub_topic_published_total", "counter", "Messages published to the topic.",
+		func(t pubsub.TopicStats) float64 { return float64(t.Published) })
+	topicMetric("pubsub_topic_publish_rate", "gauge", "Messages published per second over the last minute.",
+		func(t pubsub.TopicStats) float64 { return t.PublishRate })
+	topicMetric("pubsub_topic_duplicates_total", "counter", "Publishes dropped as duplicates.",
+		func(t pubsub.TopicStats) float64 { return float64(t.Duplicates) })
+	topicMetric("pubsub_topic_subscriptions", "gauge", "Subscriptions attached to the topic.",
+		func(t pubsub.TopicStats) float64 { return float64(t.Subscriptions) })
+
+	subs := h.Subscriptions()
+	subMetric := func(name, typ, help string, value func(pubsub.SubscriptionStats) float64) {
+		header(bw, name, typ, help)
+		for _, s := range subs {
+			sample(bw, name, subLabels(s), value(s.SubscriptionStats))
+		}
+	}
+	subMetric("pubsub_subscription_delivered_total", "counter", "Messages acknowledged by the subscriber.",
+		func(s pubsub.SubscriptionStats) float64 { return float64(s.Delivered) })
+	subMetric("pubsub_subscription_delivery_rate", "gauge", "Acknowledgements per second over the last minute.",
+		func(s pubsub.SubscriptionStats) float64 { return s.DeliveryRate })
+	subMetric("pubsub_subscription_queue_depth", "gauge", "Messages waiting in the queue or for a redelivery.",
+		func(s pubsub.SubscriptionStats) float64 { return float64(s.Queued) })
+	subMetric("pubsub_subscription_lag", "gauge", "Messages published but not yet acknowledged.",
+		func(s pubsub.SubscriptionStats) float64 { return float64(s.Lag) })
+	subMetric("pubsub_subscription_redelivered_total", "counter", "Redeliveries after a nack or missed ack deadline.",
+		func(s pubsub.SubscriptionStats) float64 { return float64(s.Redelivered) })
+	subMetric("pubsub_subscription_dead_lettered_total", "counter", "Messages that exhausted their attempts.",
+		func(s pubsub.SubscriptionStats) float64 { return float64(s.DeadLettered) })
+	subMetric("pubsub_subscription_dropped_total", "counter", "Messages discarded by the overflow policy.",
+		func(s pubsub.SubscriptionStats) float64 { return float64(s.Dropped) })
+	subMetric("pubsub_subscription_purged_total", "counter", "Messages discarded by a purge.",
+		func(s pubsub.SubscriptionStats) float64 { return float64(s.Purged) })
+
+	const latency = "pubsub_subscription_processing_seconds"
+	header(bw, latency, "histogram", "Time spent in the subscriber's OnMessage.")
+	for _, s := range subs {
+		h := s.Latency
+		var cumulative uint64
+		for i, bound := range pubsub.LatencyBuckets {
+			cumulative += h.Counts[i]
+			le := strconv.FormatFloat(bound.Seconds(), 'g', -1, 64)
+			sample(bw, latency+"_bucket", subLabels(s)+","+labels("le", le), float64(cumulative))
+		}
+		sample(bw, latency+"_bucket", subLabels(s)+","+labels("le", "+Inf"), float64(h.Count))
+		sample(bw, latency+"_sum", subLabels(s), h.Sum.Seconds())
+		sample(bw, latency+"_count", subLabels(s), float64(h.Count))
+	}
+
+	const groupLag = "pubsub_group_lag"
+	header(bw, groupLag, "gauge", "Messages the consumer group has not committed, per partition.")
+	for _, g := range h.Groups() {
+		for p := range len(g.Lag) {
+			sample(bw, groupLag, labels("topic", g.Topic, "group", g.Group, "partition", strconv.Itoa(p)), float64(g.Lag[p]))
+		}
+	}
+}
+
+func subLabels(s SubscriptionInfo) string {
+	return labels("subscription", s.ID, "pattern", s.Pattern, "group", s.Group)
+}
+
+func header(w *bufio.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func sample(w *bufio.Writer, name, labels string, value float64) {
+	fmt.Fprintf(w, "%s{%s} %s\n", name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// labels formats name/value pairs, escaping the values.
+func labels(kv ...string) string {
+	var b strings.Builder
+	for i := 0; i < len(kv); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", kv[i], kv[i+1])
+	}
+	return b.String()
+}
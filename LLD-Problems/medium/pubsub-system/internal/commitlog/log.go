@@ -0,0 +1,321 @@
+package commitlog
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FsyncPolicy controls when appended records are flushed to disk.
+type FsyncPolicy int
+
+const (
+	// FsyncInterval flushes in the background every Options.FsyncInterval.
+	FsyncInterval FsyncPolicy = iota
+	// FsyncAlways flushes after every append (safest, slowest).
+	FsyncAlways
+	// FsyncNever leaves flushing to the operating system.
+	FsyncNever
+)
+
+// Options configures a Log.
+type Options struct {
+	SegmentBytes   int64         // roll to a new segment at this size; defaults to 16 MiB
+	Fsync          FsyncPolicy   // defaults to FsyncInterval
+	FsyncInterval  time.Duration // defaults to 1s; also paces retention checks
+	RetentionBytes int64         // delete oldest segments beyond this total; 0 = unlimited
+	RetentionAge   time.Duration // delete segments whose newest record is older; 0 = forever
+}
+
+// Record is one entry in the log.
+type Record struct {
+	Offset    uint64
+	Timestamp time.Time
+	Payload   []byte
+}
+
+var (
+	// ErrOutOfRange is returned by Read for offsets that were deleted by
+	// retention or have not been written yet.
+	ErrOutOfRange = errors.New("commitlog: offset out of range")
+	// ErrClosed is returned after Close.
+	ErrClosed = errors.New("commitlog: log closed")
+)
+
+// Log is a segmented, append-only record log on local disk. Each record
+// gets a sequential offset; segments roll at SegmentBytes and whole
+// segments are deleted by retention. Safe for concurrent use.
+type Log struct {
+	dir  string
+	opts Options
+
+	mu       sync.RWMutex
+	segments []*segment // oldest first; the last one is active
+	notify   chan struct{}
+	closed   bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Open opens the log in dir, creating it if needed. The newest segment
+// is verified record by record and any torn tail from a crash is
+// truncated.
+// (Factory Pattern)
+func Open(dir string, opts Options) (*Log, error) {
+	if opts.SegmentBytes <= 0 {
+		opts.SegmentBytes = 16 << 20
+	}
+	if opts.FsyncInterval <= 0 {
+		opts.FsyncInterval = time.Second
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	bases, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(bases) == 0 {
+		bases = []uint64{0}
+	}
+
+	l := &Log{
+		dir:    dir,
+		opts:   opts,
+		notify: make(chan struct{}),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	for i, base := range bases {
+		seg, err := openSegment(dir, base, i == len(bases)-1)
+		if err != nil {
+			l.closeSegments()
+			return nil, err
+		}
+		l.segments = append(l.segments, seg)
+	}
+	go l.maintain()
+	return l, nil
+}
+
+// listSegments returns the base offsets of the segments in dir, sorted.
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var bases []uint64
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".log")
+		if !ok {
+			continue
+		}
+		if base, err := strconv.ParseUint(name, 10, 64); err == nil {
+			bases = append(bases, base)
+		}
+	}
+	slices.Sort(bases)
+	return bases, nil
+}
+
+// Append writes payload with timestamp ts and returns its offset.
+// Timestamps are clamped to be non-decreasing so time lookups can
+// binary-search.
+func (l *Log) Append(payload []byte, ts time.Time) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return 0, ErrClosed
+	}
+
+	active := l.active()
+	if active.broken != nil {
+		return 0, active.broken // left for Open to recover, not rolled past
+	}
+	if active.size >= l.opts.SegmentBytes {
+		seg, err := openSegment(l.dir, active.next, false)
+		if err != nil {
+			return 0, err
+		}
+		if l.opts.Fsync != FsyncNever {
+			active.sync() // a rolled segment is never written again
+		}
+		l.segments = append(l.segments, seg)
+		active = seg
+		l.enforceRetention(time.Now())
+	}
+	if ts.Before(active.lastTS) {
+		ts = active.lastTS
+	}
+
+	offset, err := active.append(payload, ts)
+	if err != nil {
+		return 0, err
+	}
+	if l.opts.Fsync == FsyncAlways {
+		if err := active.sync(); err != nil {
+			return 0, err
+		}
+	}
+	close(l.notify)
+	l.notify = make(chan struct{})
+	return offset, nil
+}
+
+// Read returns the record at offset, or ErrOutOfRange.
+func (l *Log) Read(offset uint64) (Record, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return Record{}, ErrClosed
+	}
+	if offset < l.segments[0].base || offset >= l.active().next {
+		return Record{}, ErrOutOfRange
+	}
+	i := sort.Search(len(l.segments), func(i int) bool { return l.segments[i].base > offset }) - 1
+	return l.segments[i].read(offset)
+}
+
+// OldestOffset returns the first offset still retained.
+func (l *Log) OldestOffset() uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.segments[0].base
+}
+
+// NextOffset returns the offset the next Append will get.
+func (l *Log) NextOffset() uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.active().next
+}
+
+// OffsetForTime returns the first offset whose timestamp is >= t, or
+// NextOffset if every record is older.
+func (l *Log) OffsetForTime(t time.Time) (uint64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, seg := range l.segments {
+		if len(seg.positions) == 0 || seg.lastTS.Before(t) {
+			continue
+		}
+		var searchErr error
+		i := sort.Search(len(seg.positions), func(i int) bool {
+			ts, err := seg.timestampAt(i)
+			if err != nil {
+				searchErr = err
+				return true
+			}
+			return !ts.Before(t)
+		})
+		return seg.base + uint64(i), searchErr
+	}
+	return l.active().next, nil
+}
+
+// Changed returns a channel that is closed by the next Append, letting
+// readers that reached the end wait for new records.
+func (l *Log) Changed() <-chan struct{} {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.notify
+}
+
+// Size returns the total bytes stored across segments.
+func (l *Log) Size() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var n int64
+	for _, seg := range l.segments {
+		n += seg.size
+	}
+	return n
+}
+
+// Sync flushes the active segment to disk.
+func (l *Log) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return ErrClosed
+	}
+	return l.active().sync()
+}
+
+// Close flushes and closes the log.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	l.mu.Unlock()
+
+	close(l.stop)
+	<-l.done
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	err := l.active().sync()
+	return errors.Join(err, l.closeSegments())
+}
+
+// active returns the segment being appended to. Caller holds l.mu.
+func (l *Log) active() *segment {
+	return l.segments[len(l.segments)-1]
+}
+
+// maintain runs periodic fsyncs and retention until Close.
+func (l *Log) maintain() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.opts.FsyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.mu.Lock()
+			if l.opts.Fsync == FsyncInterval {
+				l.active().sync()
+			}
+			l.enforceRetention(time.Now())
+			l.mu.Unlock()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// enforceRetention deletes the oldest inactive segments that are past
+// RetentionAge or push the total over RetentionBytes. Caller holds l.mu.
+func (l *Log) enforceRetention(now time.Time) {
+	var total int64
+	for _, seg := range l.segments {
+		total += seg.size
+	}
+	for len(l.segments) > 1 {
+		oldest := l.segments[0]
+		tooOld := l.opts.RetentionAge > 0 && now.Sub(oldest.lastTS) > l.opts.RetentionAge
+		tooBig := l.opts.RetentionBytes > 0 && total > l.opts.RetentionBytes
+		if !tooOld && !tooBig {
+			return
+		}
+		total -= oldest.size
+		oldest.remove()
+		l.segments = l.segments[1:]
+	}
+}
+
+func (l *Log) closeSegments() error {
+	var errs []error
+	for _, seg := range l.segments {
+		errs = append(errs, seg.close())
+	}
+	return errors.Join(errs...)
+}
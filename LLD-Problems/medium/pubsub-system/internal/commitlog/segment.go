@@ -0,0 +1,259 @@
+package commitlog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Record layout in a .log file:
+//
+//	offset    uint64  (big endian)
+//	timestamp int64   (Unix nanoseconds)
+//	length    uint32  (payload bytes)
+//	crc       uint32  (CRC-32C of the 20 bytes above + payload)
+//	payload   [length]byte
+//
+// The .index file holds one uint64 file position per record, so record
+// (offset - base) is found with a single 8-byte lookup.
+const (
+	headerSize     = 24
+	indexEntrySize = 8
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errCorrupt marks a torn or damaged record.
+var errCorrupt = errors.New("commitlog: corrupt record")
+
+// segment is one .log/.index file pair holding offsets [base, next).
+type segment struct {
+	base      uint64
+	next      uint64
+	logFile   *os.File
+	indexFile *os.File
+	size      int64   // bytes in logFile
+	positions []int64 // in-memory copy of the index
+	firstTS   time.Time
+	lastTS    time.Time
+	broken    error // set when a failed append could not be rolled back
+}
+
+func segmentPaths(dir string, base uint64) (logPath, indexPath string) {
+	name := fmt.Sprintf("%020d", base)
+	return filepath.Join(dir, name+".log"), filepath.Join(dir, name+".index")
+}
+
+// openSegment opens (or creates) the segment starting at base. When
+// verify is set, or the index looks damaged, the log is rescanned,
+// truncated after the last valid record, and the index rebuilt.
+func openSegment(dir string, base uint64, verify bool) (*segment, error) {
+	logPath, indexPath := segmentPaths(dir, base)
+	logFile, err := os.OpenFile(logPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	indexFile, err := os.OpenFile(indexPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	s := &segment{base: base, logFile: logFile, indexFile: indexFile}
+
+	if !verify {
+		verify = s.loadIndex() != nil
+	}
+	if verify {
+		err = s.recover()
+	}
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	s.next = base + uint64(len(s.positions))
+	if len(s.positions) > 0 {
+		if s.firstTS, err = s.timestampAt(0); err == nil {
+			s.lastTS, err = s.timestampAt(len(s.positions) - 1)
+		}
+		if err != nil {
+			s.close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// loadIndex reads the index file and checks it against the log size.
+func (s *segment) loadIndex() error {
+	info, err := s.logFile.Stat()
+	if err != nil {
+		return err
+	}
+	raw, err := io.ReadAll(io.NewSectionReader(s.indexFile, 0, 1<<62))
+	if err != nil {
+		return err
+	}
+	if len(raw)%indexEntrySize != 0 {
+		return errCorrupt
+	}
+	s.positions = make([]int64, len(raw)/indexEntrySize)
+	for i := range s.positions {
+		s.positions[i] = int64(binary.BigEndian.Uint64(raw[i*indexEntrySize:]))
+	}
+	s.size = info.Size()
+	if n := len(s.positions); n > 0 {
+		// The last indexed record must end exactly at the end of the log.
+		hdr := make([]byte, headerSize)
+		if _, err := s.logFile.ReadAt(hdr, s.positions[n-1]); err != nil {
+			return errCorrupt
+		}
+		if s.positions[n-1]+headerSize+int64(binary.BigEndian.Uint32(hdr[16:])) != s.size {
+			return errCorrupt
+		}
+	} else if s.size != 0 {
+		return errCorrupt
+	}
+	return nil
+}
+
+// recover rescans the log, drops a torn tail and rewrites the index.
+func (s *segment) recover() error {
+	s.positions = s.positions[:0]
+	info, err := s.logFile.Stat()
+	if err != nil {
+		return err
+	}
+	var pos int64
+	for {
+		rec, n, err := readRecordAt(s.logFile, pos, info.Size())
+		if err != nil || rec.Offset != s.base+uint64(len(s.positions)) {
+			break // end of file or first damaged record
+		}
+		s.positions = append(s.positions, pos)
+		pos += n
+	}
+	if err := s.logFile.Truncate(pos); err != nil {
+		return err
+	}
+	s.size = pos
+
+	buf := make([]byte, len(s.positions)*indexEntrySize)
+	for i, p := range s.positions {
+		binary.BigEndian.PutUint64(buf[i*indexEntrySize:], uint64(p))
+	}
+	if err := s.indexFile.Truncate(0); err != nil {
+		return err
+	}
+	_, err = s.indexFile.Write(buf)
+	return err
+}
+
+// append writes one record. Caller holds the log's write lock.
+func (s *segment) append(payload []byte, ts time.Time) (uint64, error) {
+	offset := s.next
+	buf := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint64(buf[0:], offset)
+	binary.BigEndian.PutUint64(buf[8:], uint64(ts.UnixNano()))
+	binary.BigEndian.PutUint32(buf[16:], uint32(len(payload)))
+	copy(buf[headerSize:], payload)
+	crc := crc32.Update(crc32.Checksum(buf[:20], crcTable), crcTable, payload)
+	binary.BigEndian.PutUint32(buf[20:], crc)
+
+	if _, err := s.logFile.Write(buf); err != nil {
+		return 0, s.rollback(err)
+	}
+	var entry [indexEntrySize]byte
+	binary.BigEndian.PutUint64(entry[:], uint64(s.size))
+	if _, err := s.indexFile.Write(entry[:]); err != nil {
+		return 0, s.rollback(err)
+	}
+
+	s.positions = append(s.positions, s.size)
+	s.size += int64(len(buf))
+	s.next++
+	if offset == s.base {
+		s.firstTS = ts
+	}
+	s.lastTS = ts
+	return offset, nil
+}
+
+// rollback truncates both files back to their last whole record after
+// a failed append, so the next record is not written behind a partial
+// one. If that fails too, the segment refuses further appends; its files
+// no longer agree, so the next Open rebuilds it from the valid records.
+func (s *segment) rollback(err error) error {
+	terr := errors.Join(
+		s.logFile.Truncate(s.size),
+		s.indexFile.Truncate(int64(len(s.positions))*indexEntrySize),
+	)
+	if terr != nil {
+		s.broken = fmt.Errorf("commitlog: segment %d needs recovery: %w", s.base, errors.Join(err, terr))
+		return s.broken
+	}
+	return err
+}
+
+// read returns the record at offset, which must be in [base, next).
+func (s *segment) read(offset uint64) (Record, error) {
+	rec, _, err := readRecordAt(s.logFile, s.positions[offset-s.base], s.size)
+	return rec, err
+}
+
+// timestampAt returns the timestamp of the i-th record.
+func (s *segment) timestampAt(i int) (time.Time, error) {
+	hdr := make([]byte, headerSize)
+	if _, err := s.logFile.ReadAt(hdr, s.positions[i]); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(hdr[8:]))), nil
+}
+
+func (s *segment) sync() error {
+	if err := s.logFile.Sync(); err != nil {
+		return err
+	}
+	return s.indexFile.Sync()
+}
+
+func (s *segment) close() error {
+	return errors.Join(s.logFile.Close(), s.indexFile.Close())
+}
+
+// remove closes and deletes the segment's files.
+func (s *segment) remove() error {
+	s.close()
+	return errors.Join(os.Remove(s.logFile.Name()), os.Remove(s.indexFile.Name()))
+}
+
+// readRecordAt decodes and verifies the record at pos, returning its
+// total size on disk. limit is the file size, guarding against a
+// damaged length field.
+func readRecordAt(f *os.File, pos, limit int64) (Record, int64, error) {
+	hdr := make([]byte, headerSize)
+	if _, err := f.ReadAt(hdr, pos); err != nil {
+		return Record{}, 0, err
+	}
+	length := binary.BigEndian.Uint32(hdr[16:])
+	if pos+headerSize+int64(length) > limit {
+		return Record{}, 0, errCorrupt
+	}
+	payload := make([]byte, length)
+	if _, err := f.ReadAt(payload, pos+headerSize); err != nil {
+		return Record{}, 0, errCorrupt
+	}
+	crc := crc32.Update(crc32.Checksum(hdr[:20], crcTable), crcTable, payload)
+	if crc != binary.BigEndian.Uint32(hdr[20:]) {
+		return Record{}, 0, errCorrupt
+	}
+	return Record{
+		Offset:    binary.BigEndian.Uint64(hdr[0:]),
+		Timestamp: time.Unix(0, int64(binary.BigEndian.Uint64(hdr[8:]))),
+		Payload:   payload,
+	}, headerSize + int64(length), nil
+}
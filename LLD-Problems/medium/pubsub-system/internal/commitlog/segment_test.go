@@ -0,0 +1,58 @@
+package commitlog
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFailedAppendIsRolledBack(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"first", "second"} {
+		if _, err := l.Append([]byte(p), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seg := l.active()
+	size := seg.size
+	// The log write succeeds but the index write fails, and the index
+	// cannot be truncated either.
+	logPath, indexPath := segmentPaths(dir, seg.base)
+	readOnly, err := os.Open(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seg.indexFile.Close()
+	seg.indexFile = readOnly
+
+	if _, err := l.Append([]byte("lost"), time.Now()); err == nil {
+		t.Fatal("Append with a read-only index succeeded")
+	}
+	if info, err := os.Stat(logPath); err != nil || info.Size() != size {
+		t.Fatalf("log is %d bytes after a failed append, want %d", info.Size(), size)
+	}
+	if _, err := l.Append([]byte("after"), time.Now()); err == nil {
+		t.Fatal("Append to a broken segment succeeded")
+	}
+	l.Close()
+
+	l, err = Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if next := l.NextOffset(); next != 2 {
+		t.Fatalf("reopened log ends at %d, want 2", next)
+	}
+	off, err := l.Append([]byte("third"), time.Now())
+	if err != nil || off != 2 {
+		t.Fatalf("Append after reopen = %d, %v", off, err)
+	}
+	if rec, err := l.Read(1); err != nil || string(rec.Payload) != "second" {
+		t.Fatalf("Read(1) = %q, %v", rec.Payload, err)
+	}
+}
@@ -0,0 +1,340 @@
+package netbroker
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"pubsub-system/internal/pubsub"
+)
+
+// Client errors.
+var (
+	ErrClientClosed = errors.New("netbroker: client closed")
+	ErrTimeout      = errors.New("netbroker: request timed out")
+)
+
+// RemoteError is an ERROR reply from the server.
+type RemoteError struct {
+	Message string
+}
+
+func (e *RemoteError) Error() string { return e.Message }
+
+// ClientOptions configures Dial.
+type ClientOptions struct {
+	ClientID     string // sent in CONNECT; the server assigns one if empty
+	Username     string // credentials for the server's Authenticator
+	Password     string
+	Timeout      time.Duration // dial and per-request timeout; defaults to 5s
+	PingInterval time.Duration // keepalive PING period; defaults to 30s, negative disables
+}
+
+// Client is a connection to a netbroker Server. Its Publish and
+// Subscribe mirror pubsub.Broker, so code written against pubsub.Subscriber
+// works unchanged across the network. A Client is safe for concurrent
+// use; it does not reconnect after the connection fails.
+type Client struct {
+	nc       net.Conn
+	timeout  time.Duration
+	clientID string
+
+	wmu sync.Mutex
+	w   *bufio.Writer
+
+	mu      sync.Mutex // guards everything below
+	nextReq uint32
+	nextSID uint32
+	waiting map[uint32]chan frame
+	subs    map[uint32]*RemoteSubscription
+	err     error // why the connection ended
+
+	done chan struct{} // closed when the read loop exits
+}
+
+// RemoteSubscription is a subscription held on the server. Messages are
+// handed to its Subscriber one at a time from a dedicated goroutine; the
+// Subscriber's return value is sent back as the ACK (nil) or nack.
+type RemoteSubscription struct {
+	Pattern string
+
+	client     *Client
+	sid        uint32
+	subscriber pubsub.Subscriber
+	deliveries chan messageBody // at most one in flight, see protocol
+	stop       chan struct{}    // closed when the subscription is dropped
+}
+
+// Dial connects to addr and performs the CONNECT handshake.
+// (Factory Pattern)
+func Dial(addr string, opts ClientOptions) (*Client, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.PingInterval == 0 {
+		opts.PingInterval = 30 * time.Second
+	}
+	nc, err := net.DialTimeout("tcp", addr, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		nc:      nc,
+		timeout: opts.Timeout,
+		w:       bufio.NewWriter(nc),
+		waiting: make(map[uint32]chan frame),
+		subs:    make(map[uint32]*RemoteSubscription),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(bufio.NewReader(nc))
+
+	var ack connAckBody
+	if err := c.request(FrameConnect, connectBody{
+		ClientID: opts.ClientID,
+		Version:  ProtocolVersion,
+		Username: opts.Username,
+		Password: opts.Password,
+	}, &ack); err != nil {
+		c.Close()
+		return nil, err
+	}
+	c.clientID = ack.ClientID
+	if opts.PingInterval > 0 {
+		go c.keepalive(opts.PingInterval)
+	}
+	return c, nil
+}
+
+// ClientID returns the ID the server knows this client by.
+func (c *Client) ClientID() string { return c.clientID }
+
+// Publish sends msg to the named topic and waits for the broker to
+// accept it, then sets msg.ID, msg.Offset and msg.Partition from the
+// reply.
+func (c *Client) Publish(topic string, msg *pubsub.Message) error {
+	var reply publishReply
+	if err := c.request(FramePublish, publishBody{Topic: topic, Message: toWire(msg)}, &reply); err != nil {
+		return err
+	}
+	msg.ID, msg.Offset, msg.Partition = reply.ID, reply.Offset, reply.Partition
+	return nil
+}
+
+// Subscribe registers a pattern subscription on the server (see
+// pubsub.Broker.Subscribe). opts.DeadLetter is not sent: a dead-letter
+// topic cannot be referenced over the network.
+func (c *Client) Subscribe(pattern string, s pubsub.Subscriber, opts pubsub.SubscriptionOptions) (*RemoteSubscription, error) {
+	c.mu.Lock()
+	c.nextSID++
+	rs := &RemoteSubscription{
+		Pattern:    pattern,
+		client:     c,
+		sid:        c.nextSID,
+		subscriber: s,
+		deliveries: make(chan messageBody, 1),
+		stop:       make(chan struct{}),
+	}
+	c.subs[rs.sid] = rs
+	c.mu.Unlock()
+
+	go rs.run()
+	if err := c.request(FrameSubscribe, encodeOptions(rs.sid, pattern, opts), nil); err != nil {
+		c.dropSub(rs.sid)
+		return nil, err
+	}
+	return rs, nil
+}
+
+// Unsubscribe removes the subscription on the server. Messages that
+// arrive for it afterwards are acknowledged without delivery.
+func (rs *RemoteSubscription) Unsubscribe() error {
+	err := rs.client.request(FrameUnsubscribe, unsubscribeBody{SID: rs.sid}, nil)
+	rs.client.dropSub(rs.sid)
+	return err
+}
+
+// Ping measures a round trip to the server.
+func (c *Client) Ping() (time.Duration, error) {
+	start := time.Now()
+	err := c.request(FramePing, struct{}{}, nil)
+	return time.Since(start), err
+}
+
+// Close closes the connection; the server drops its subscriptions.
+func (c *Client) Close() error {
+	err := c.nc.Close()
+	<-c.done
+	return err
+}
+
+// Err returns why the connection ended, or nil while it is open.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// request sends a frame and waits for the reply with the same request
+// ID, decoding its body into reply if non-nil.
+func (c *Client) request(typ FrameType, body, reply interface{}) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextReq++
+	if c.nextReq == 0 { // 0 is reserved for unsolicited frames
+		c.nextReq++
+	}
+	id := c.nextReq
+	ch := make(chan frame, 1)
+	c.waiting[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.waiting, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(typ, id, body); err != nil {
+		return err
+	}
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case f := <-ch:
+		if f.typ == FrameError {
+			var e errorBody
+			json.Unmarshal(f.body, &e)
+			return &RemoteError{Message: e.Error}
+		}
+		if reply != nil {
+			return json.Unmarshal(f.body, reply)
+		}
+		return nil
+	case <-c.done:
+		return c.Err()
+	case <-timer.C:
+		return ErrTimeout
+	}
+}
+
+// send writes and flushes one frame.
+func (c *Client) send(typ FrameType, request uint32, body interface{}) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.nc.SetWriteDeadline(time.Now().Add(c.timeout))
+	if err := writeFrame(c.w, typ, request, body); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+// readLoop routes replies to waiting requests and MESSAGE frames to
+// their subscriptions until the connection fails.
+func (c *Client) readLoop(r *bufio.Reader) {
+	var err error
+	defer func() {
+		c.mu.Lock()
+		c.err = ErrClientClosed
+		if !errors.Is(err, net.ErrClosed) {
+			c.err = fmt.Errorf("netbroker: connection lost: %w", err)
+		}
+		subs := c.subs
+		c.subs = make(map[uint32]*RemoteSubscription)
+		c.mu.Unlock()
+		for _, rs := range subs {
+			close(rs.stop)
+		}
+		close(c.done)
+	}()
+
+	for {
+		var f frame
+		if f, err = readFrame(r); err != nil {
+			return
+		}
+		if f.typ == FrameMessage {
+			var m messageBody
+			if err = json.Unmarshal(f.body, &m); err != nil {
+				return
+			}
+			c.mu.Lock()
+			rs, ok := c.subs[m.SID]
+			c.mu.Unlock()
+			if !ok {
+				go c.send(FrameAck, 0, ackBody{Delivery: m.Delivery})
+				continue
+			}
+			select {
+			case rs.deliveries <- m:
+			case <-rs.stop:
+			}
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.waiting[f.request]
+		c.mu.Unlock()
+		if ok {
+			ch <- f
+		}
+	}
+}
+
+// dropSub forgets a subscription and stops its delivery goroutine.
+func (c *Client) dropSub(sid uint32) {
+	c.mu.Lock()
+	rs, ok := c.subs[sid]
+	delete(c.subs, sid)
+	c.mu.Unlock()
+	if ok {
+		close(rs.stop)
+	}
+}
+
+// run calls the Subscriber for each delivery and acks the result.
+func (rs *RemoteSubscription) run() {
+	for {
+		var m messageBody
+		select {
+		case m = <-rs.deliveries:
+		case <-rs.stop:
+			return
+		}
+		ack := ackBody{Delivery: m.Delivery}
+		if err := rs.invoke(fromWire(m.Message)); err != nil {
+			ack.Error = err.Error()
+		}
+		if rs.client.send(FrameAck, 0, ack) != nil {
+			return
+		}
+	}
+}
+
+// invoke calls the Subscriber, turning a panic into a nack.
+func (rs *RemoteSubscription) invoke(msg *pubsub.Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("netbroker: subscriber panicked: %v", r)
+		}
+	}()
+	return rs.subscriber.OnMessage(msg)
+}
+
+// keepalive pings the server so idle connections are not timed out.
+func (c *Client) keepalive(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.Ping()
+		case <-c.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Package netbroker exposes a pubsub.Broker over TCP.
+//
+// # Wire protocol
+//
+// Every frame is
+//
+//	length  uint32, big-endian: bytes that follow (5 + len(body))
+//	type    uint8:  one of the Frame* constants
+//	request uint32, big-endian: chosen by the sender of a request and
+//	        echoed in its reply (0 on MESSAGE and ACK)
+//	body    JSON object, described per type below
+//
+// A session starts with CONNECT → CONNACK; any other frame first is an
+// error and the server closes the connection. Requests from the client:
+//
+//	CONNECT     {"client_id", "version", "username", "password"}
+//	                                                   → CONNACK {"client_id", "server",
+//	                                                              "principal"}
+//	PUBLISH     {"topic", "message"}                   → OK {"id", "offset", "partition"}
+//	SUBSCRIBE   {"sid", "pattern", <options>}          → OK {}
+//	UNSUBSCRIBE {"sid"}                                → OK {}
+//	PING        {}                                     → PONG {}
+//
+// A failed request is answered with ERROR {"error"}. For each
+// subscription the server sends MESSAGE {"sid", "delivery", "message"}
+// and waits for the client's ACK {"delivery", "error"} before sending
+// that subscription's next message; a non-empty error is a nack and
+// the message is redelivered with backoff, as in-process. Messages use
+// the JSON form of wireMessage (payload base64-encoded).
+package netbroker
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"pubsub-system/internal/pubsub"
+)
+
+// ProtocolVersion is sent in CONNECT and checked by the server.
+const ProtocolVersion = 1
+
+// MaxFrameSize bounds a frame's length field (16 MiB).
+const MaxFrameSize = 16 << 20
+
+// FrameType identifies a frame.
+type FrameType uint8
+
+const (
+	FrameConnect FrameType = iota + 1
+	FrameConnAck
+	FramePublish
+	FrameSubscribe
+	FrameUnsubscribe
+	FrameMessage
+	FrameAck
+	FramePing
+	FramePong
+	FrameOK
+	FrameError
+)
+
+func (t FrameType) String() string {
+	names := [...]string{"?", "CONNECT", "CONNACK", "PUBLISH", "SUBSCRIBE", "UNSUBSCRIBE",
+		"MESSAGE", "ACK", "PING", "PONG", "OK", "ERROR"}
+	if int(t) < len(names) {
+		return names[t]
+	}
+	return fmt.Sprintf("FrameType(%d)", t)
+}
+
+// ErrFrameTooLarge is returned for frames over MaxFrameSize.
+var ErrFrameTooLarge = errors.New("netbroker: frame too large")
+
+// frame is one decoded frame; body is still JSON.
+type frame struct {
+	typ     FrameType
+	request uint32
+	body    []byte
+}
+
+// readFrame reads one frame from r.
+func readFrame(r *bufio.Reader) (frame, error) {
+	var hdr [9]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return frame{}, err
+	}
+	n := binary.BigEndian.Uint32(hdr[0:4])
+	if n < 5 {
+		return frame{}, fmt.Errorf("netbroker: frame length %d too short", n)
+	}
+	if n > MaxFrameSize {
+		return frame{}, ErrFrameTooLarge
+	}
+	f := frame{typ: FrameType(hdr[4]), request: binary.BigEndian.Uint32(hdr[5:9])}
+	f.body = make([]byte, n-5)
+	if _, err := io.ReadFull(r, f.body); err != nil {
+		return frame{}, err
+	}
+	return f, nil
+}
+
+// writeFrame encodes body as JSON and writes one frame to w (without
+// flushing).
+func writeFrame(w *bufio.Writer, typ FrameType, request uint32, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	if len(data)+5 > MaxFrameSize {
+		return ErrFrameTooLarge
+	}
+	var hdr [9]byte
+	binary.BigEndian.PutUint32(hdr[0:4], uint32(len(data)+5))
+	hdr[4] = byte(typ)
+	binary.BigEndian.PutUint32(hdr[5:9], request)
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Frame bodies.
+
+type connectBody struct {
+	ClientID string `json:"client_id,omitempty"`
+	Version  int    `json:"version"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type connAckBody struct {
+	ClientID  string `json:"client_id"`
+	Server    string `json:"server"`
+	Principal string `json:"principal,omitempty"`
+}
+
+type publishBody struct {
+	Topic   string      `json:"topic"`
+	Message wireMessage `json:"message"`
+}
+
+type publishReply struct {
+	ID        string `json:"id"`
+	Offset    uint64 `json:"offset"`
+	Partition int    `json:"partition"`
+}
+
+// subscribeBody carries the network-safe subset of
+// pubsub.SubscriptionOptions (a dead-letter topic cannot be named).
+type subscribeBody struct {
+	SID     uint32 `json:"sid"`
+	Pattern string `json:"pattern"`
+
+	QueueSize      int     `json:"queue_size,omitempty"`
+	Overflow       string  `json:"overflow,omitempty"`
+	Filter         string  `json:"filter,omitempty"`
+	Start          string  `json:"start,omitempty"` // see pubsub.ParseStartPosition
+	AckDeadlineMS  int64   `json:"ack_deadline_ms,omitempty"`
+	MaxAttempts    int     `json:"max_attempts,omitempty"`
+	InitialBackoff int64   `json:"initial_backoff_ms,omitempty"`
+	MaxBackoff     int64   `json:"max_backoff_ms,omitempty"`
+	Multiplier     float64 `json:"multiplier,omitempty"`
+}
+
+type unsubscribeBody struct {
+	SID uint32 `json:"sid"`
+}
+
+type messageBody struct {
+	SID      uint32      `json:"sid"`
+	Delivery uint64      `json:"delivery"`
+	Message  wireMessage `json:"message"`
+}
+
+type ackBody struct {
+	Delivery uint64 `json:"delivery"`
+	Error    string `json:"error,omitempty"` // non-empty: nack
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// wireMessage is the JSON form of a pubsub.Message.
+type wireMessage struct {
+	ID          string            `json:"id,omitempty"`
+	Key         string            `json:"key,omitempty"`
+	Topic       string            `json:"topic,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Payload     []byte            `json:"payload,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	TimestampNS int64             `json:"timestamp_ns,omitempty"`
+	Offset      uint64            `json:"offset,omitempty"`
+	Partition   int               `json:"partition,omitempty"`
+	Attempt     int               `json:"attempt,omitempty"`
+}
+
+func toWire(m *pubsub.Message) wireMessage {
+	w := wireMessage{
+		ID:          m.ID,
+		Key:         m.Key,
+		Topic:       m.Topic,
+		Headers:     m.Headers,
+		Payload:     m.Payload,
+		ContentType: m.ContentType,
+		Offset:      m.Offset,
+		Partition:   m.Partition,
+		Attempt:     m.DeliveryAttempt,
+	}
+	if !m.Timestamp.IsZero() {
+		w.TimestampNS = m.Timestamp.UnixNano()
+	}
+	return w
+}
+
+func fromWire(w wireMessage) *pubsub.Message {
+	m := &pubsub.Message{
+		ID:              w.ID,
+		Key:             w.Key,
+		Topic:           w.Topic,
+		Headers:         w.Headers,
+		Payload:         w.Payload,
+		ContentType:     w.ContentType,
+		Offset:          w.Offset,
+		Partition:       w.Partition,
+		DeliveryAttempt: w.Attempt,
+	}
+	if w.TimestampNS != 0 {
+		m.Timestamp = time.Unix(0, w.TimestampNS)
+	}
+	return m
+}
+
+// overflowNames maps the wire names of pubsub.OverflowPolicy.
+var overflowNames = map[string]pubsub.OverflowPolicy{
+	pubsub.Block.String():      pubsub.Block,
+	pubsub.DropOldest.String(): pubsub.DropOldest,
+	pubsub.DropNewest.String(): pubsub.DropNewest,
+	pubsub.Disconnect.String(): pubsub.Disconnect,
+}
+
+// encodeOptions converts subscription options for SUBSCRIBE.
+func encodeOptions(sid uint32, pattern string, o pubsub.SubscriptionOptions) subscribeBody {
+	return subscribeBody{
+		SID:            sid,
+		Pattern:        pattern,
+		QueueSize:      o.QueueSize,
+		Overflow:       o.Overflow.String(),
+		Filter:         o.Filter,
+		Start:          o.StartAt.String(),
+		AckDeadlineMS:  o.AckDeadline.Milliseconds(),
+		MaxAttempts:    o.Retry.MaxAttempts,
+		InitialBackoff: o.Retry.InitialBackoff.Milliseconds(),
+		MaxBackoff:     o.Retry.MaxBackoff.Milliseconds(),
+		Multiplier:     o.Retry.Multiplier,
+	}
+}
+
+// decodeOptions is the inverse of encodeOptions.
+func decodeOptions(b subscribeBody) (pubsub.SubscriptionOptions, error) {
+	o := pubsub.SubscriptionOptions{
+		QueueSize:   b.QueueSize,
+		Filter:      b.Filter,
+		AckDeadline: time.Duration(b.AckDeadlineMS) * time.Millisecond,
+		Retry: pubsub.RetryPolicy{
+			MaxAttempts:    b.MaxAttempts,
+			InitialBackoff: time.Duration(b.InitialBackoff) * time.Millisecond,
+			MaxBackoff:     time.Duration(b.MaxBackoff) * time.Millisecond,
+			Multiplier:     b.Multiplier,
+		},
+	}
+	if b.Overflow != "" {
+		p, ok := overflowNames[b.Overflow]
+		if !ok {
+			return o, fmt.Errorf("netbroker: unknown overflow policy %q", b.Overflow)
+		}
+		o.Overflow = p
+	}
+	start, err := pubsub.ParseStartPosition(b.Start)
+	if err != nil {
+		return o, err
+	}
+	o.StartAt = start
+	return o, nil
+}
@@ -0,0 +1,398 @@
+package netbroker
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"pubsub-system/internal/pubsub"
+)
+
+// ServerName is reported in CONNACK.
+const ServerName = "pubsub-netbroker/1"
+
+// DefaultIdleTimeout closes connections that send nothing (not even
+// PING) for this long.
+const DefaultIdleTimeout = 90 * time.Second
+
+// writeTimeout bounds each frame write, so a client that stops reading
+// cannot stall the subscriptions writing to it.
+const writeTimeout = 10 * time.Second
+
+// errDisconnected nacks a delivery whose client went away.
+var errDisconnected = errors.New("netbroker: client disconnected")
+
+// ErrBadCredentials rejects a CONNECT.
+var ErrBadCredentials = errors.New("netbroker: bad username or password")
+
+// Authenticator maps CONNECT credentials to the principal the session
+// acts as, or rejects them.
+type Authenticator func(clientID, username, password string) (pubsub.Principal, error)
+
+// PasswordAuthenticator accepts the given username → password pairs,
+// acting as the user's principal, and rejects everyone else.
+func PasswordAuthenticator(users map[string]string) Authenticator {
+	return func(_, username, password string) (pubsub.Principal, error) {
+		want, ok := users[username]
+		if !ok || subtle.ConstantTimeCompare([]byte(want), []byte(password)) != 1 {
+			return pubsub.Anonymous, ErrBadCredentials
+		}
+		return pubsub.Principal(username), nil
+	}
+}
+
+// Server exposes a pubsub.Broker over TCP using the framed protocol
+// described in the package documentation. Each session acts as the
+// principal chosen by the Authenticator, so the broker's Authorizer
+// applies to remote publishes and subscriptions.
+type Server struct {
+	broker      *pubsub.Broker
+	idleTimeout time.Duration
+	auth        Authenticator
+
+	mu       sync.Mutex // guards listener, conns, closed and nextClient
+	listener net.Listener
+	conns    map[*serverConn]struct{}
+	closed   bool
+
+	nextClient uint64
+}
+
+// NewServer wraps broker. Topics, wildcards and auto-creation follow
+// the broker's options. (Factory Pattern)
+func NewServer(broker *pubsub.Broker) *Server {
+	return &Server{
+		broker:      broker,
+		idleTimeout: DefaultIdleTimeout,
+		conns:       make(map[*serverConn]struct{}),
+	}
+}
+
+// SetIdleTimeout changes DefaultIdleTimeout; call before Serve.
+func (s *Server) SetIdleTimeout(d time.Duration) { s.idleTimeout = d }
+
+// SetAuthenticator checks CONNECT credentials; call before Serve.
+// Without one, every session is pubsub.Anonymous.
+func (s *Server) SetAuthenticator(a Authenticator) { s.auth = a }
+
+// ListenAndServe listens on addr and serves until Close.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln, handling each in its own goroutine.
+// It returns nil after Close.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		ln.Close()
+		return nil
+	}
+	s.listener = ln
+	s.mu.Unlock()
+
+	for {
+		nc, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		c := &serverConn{
+			srv:     s,
+			nc:      nc,
+			r:       bufio.NewReader(nc),
+			w:       bufio.NewWriter(nc),
+			subs:    make(map[uint32]*pubsub.BrokerSubscription),
+			pending: make(map[uint64]chan error),
+			gone:    make(chan struct{}),
+		}
+		if !s.track(c) {
+			nc.Close()
+			return nil
+		}
+		go c.serve()
+	}
+}
+
+// Addr returns the listening address, or nil before Serve.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Close stops accepting connections and closes every open connection,
+// which unsubscribes their subscriptions. The broker is left open.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for c := range s.conns {
+		c.nc.Close()
+	}
+	return err
+}
+
+// track registers c, refusing it if the server is closed.
+func (s *Server) track(c *serverConn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+func (s *Server) untrack(c *serverConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, c)
+}
+
+// serverConn is one client session.
+type serverConn struct {
+	srv       *Server
+	nc        net.Conn
+	r         *bufio.Reader
+	clientID  string
+	principal pubsub.Principal
+
+	wmu sync.Mutex // serializes frames from the reader and deliveries
+	w   *bufio.Writer
+
+	mu           sync.Mutex // guards subs, pending and nextDelivery
+	subs         map[uint32]*pubsub.BrokerSubscription
+	pending      map[uint64]chan error // delivery → ack result
+	nextDelivery uint64
+
+	gone chan struct{} // closed when the connection ends
+}
+
+// serve runs the read loop, then tears the session down.
+func (c *serverConn) serve() {
+	defer c.srv.untrack(c)
+	defer c.teardown()
+
+	connected := false
+	for {
+		if c.srv.idleTimeout > 0 {
+			c.nc.SetReadDeadline(time.Now().Add(c.srv.idleTimeout))
+		}
+		f, err := readFrame(c.r)
+		if err != nil {
+			return
+		}
+		if !connected {
+			if f.typ != FrameConnect {
+				c.reply(FrameError, f.request, errorBody{Error: "expected CONNECT"})
+				return
+			}
+			if err := c.connect(f); err != nil {
+				c.reply(FrameError, f.request, errorBody{Error: err.Error()})
+				return
+			}
+			connected = true
+			continue
+		}
+		if err := c.handle(f); err != nil {
+			c.reply(FrameError, f.request, errorBody{Error: err.Error()})
+		}
+	}
+}
+
+// teardown closes the connection, fails in-flight deliveries and
+// removes the session's subscriptions.
+func (c *serverConn) teardown() {
+	c.nc.Close()
+	close(c.gone)
+	c.mu.Lock()
+	subs := c.subs
+	c.subs = nil
+	c.mu.Unlock()
+	for _, bs := range subs {
+		bs.Unsubscribe()
+	}
+}
+
+func (c *serverConn) connect(f frame) error {
+	var b connectBody
+	if err := json.Unmarshal(f.body, &b); err != nil {
+		return err
+	}
+	if b.Version != ProtocolVersion {
+		return fmt.Errorf("netbroker: unsupported protocol version %d", b.Version)
+	}
+	c.clientID = b.ClientID
+	if c.clientID == "" {
+		c.srv.mu.Lock()
+		c.srv.nextClient++
+		c.clientID = fmt.Sprintf("client-%d", c.srv.nextClient)
+		c.srv.mu.Unlock()
+	}
+	if c.srv.auth != nil {
+		p, err := c.srv.auth(c.clientID, b.Username, b.Password)
+		if err != nil {
+			return err
+		}
+		c.principal = p
+	}
+	return c.reply(FrameConnAck, f.request, connAckBody{ClientID: c.clientID, Server: ServerName, Principal: string(c.principal)})
+}
+
+// handle serves one request after CONNECT. A returned error is sent
+// back as ERROR.
+func (c *serverConn) handle(f frame) error {
+	switch f.typ {
+	case FramePing:
+		return c.reply(FramePong, f.request, struct{}{})
+
+	case FramePublish:
+		var b publishBody
+		if err := json.Unmarshal(f.body, &b); err != nil {
+			return err
+		}
+		msg := fromWire(b.Message)
+		if err := c.srv.broker.PublishAs(c.principal, b.Topic, msg); err != nil {
+			return err
+		}
+		return c.reply(FrameOK, f.request, publishReply{ID: msg.ID, Offset: msg.Offset, Partition: msg.Partition})
+
+	case FrameSubscribe:
+		var b subscribeBody
+		if err := json.Unmarshal(f.body, &b); err != nil {
+			return err
+		}
+		opts, err := decodeOptions(b)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		_, dup := c.subs[b.SID]
+		c.mu.Unlock()
+		if dup {
+			return fmt.Errorf("netbroker: subscription %d already exists", b.SID)
+		}
+		bs, err := c.srv.broker.SubscribeAs(c.principal, b.Pattern, &remoteSubscriber{conn: c, sid: b.SID, ackDeadline: opts.AckDeadline}, opts)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		c.subs[b.SID] = bs
+		c.mu.Unlock()
+		return c.reply(FrameOK, f.request, struct{}{})
+
+	case FrameUnsubscribe:
+		var b unsubscribeBody
+		if err := json.Unmarshal(f.body, &b); err != nil {
+			return err
+		}
+		c.mu.Lock()
+		bs, ok := c.subs[b.SID]
+		delete(c.subs, b.SID)
+		c.mu.Unlock()
+		if !ok {
+			return fmt.Errorf("netbroker: no subscription %d", b.SID)
+		}
+		bs.Unsubscribe()
+		return c.reply(FrameOK, f.request, struct{}{})
+
+	case FrameAck:
+		var b ackBody
+		if err := json.Unmarshal(f.body, &b); err != nil {
+			return err
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[b.Delivery]
+		delete(c.pending, b.Delivery)
+		c.mu.Unlock()
+		if ok {
+			var result error
+			if b.Error != "" {
+				result = errors.New(b.Error)
+			}
+			ch <- result
+		}
+		return nil // late or duplicate acks are ignored
+
+	default:
+		return fmt.Errorf("netbroker: unexpected %s frame", f.typ)
+	}
+}
+
+// reply writes and flushes one frame.
+func (c *serverConn) reply(typ FrameType, request uint32, body interface{}) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.nc.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := writeFrame(c.w, typ, request, body); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+// remoteSubscriber forwards a broker subscription's messages to the
+// client and reports its ACK as the delivery result.
+type remoteSubscriber struct {
+	conn        *serverConn
+	sid         uint32
+	ackDeadline time.Duration
+}
+
+func (rs *remoteSubscriber) OnMessage(msg *pubsub.Message) error {
+	c := rs.conn
+	ch := make(chan error, 1)
+	c.mu.Lock()
+	c.nextDelivery++
+	id := c.nextDelivery
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.reply(FrameMessage, 0, messageBody{SID: rs.sid, Delivery: id, Message: toWire(msg)}); err != nil {
+		return errDisconnected
+	}
+
+	// The subscription enforces the ack deadline too; this timer only
+	// stops the wait from outliving it.
+	var timeout <-chan time.Time
+	if rs.ackDeadline > 0 {
+		timer := time.NewTimer(rs.ackDeadline)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case err := <-ch:
+		return err
+	case <-c.gone:
+		return errDisconnected
+	case <-timeout:
+		return pubsub.ErrAckDeadlineExceeded
+	}
+}
@@ -0,0 +1,264 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Principal identifies who is publishing, subscribing or administering.
+type Principal string
+
+const (
+	// Anonymous is the principal of callers that did not authenticate,
+	// including the plain (non-"As") Broker methods.
+	Anonymous Principal = ""
+	// Everyone in an ACL rule matches every principal, even Anonymous.
+	Everyone Principal = "*"
+)
+
+func (p Principal) String() string {
+	if p == Anonymous {
+		return "anonymous"
+	}
+	return string(p)
+}
+
+// Action is a set of permissions.
+type Action uint8
+
+const (
+	ActionPublish Action = 1 << iota
+	ActionSubscribe
+	// ActionAdmin allows creating and deleting topics and changing the
+	// ACL rules for matching patterns.
+	ActionAdmin
+
+	ActionAll = ActionPublish | ActionSubscribe | ActionAdmin
+)
+
+func (a Action) String() string {
+	var names []string
+	for _, n := range []struct {
+		a    Action
+		name string
+	}{{ActionPublish, "publish"}, {ActionSubscribe, "subscribe"}, {ActionAdmin, "admin"}} {
+		if a&n.a != 0 {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// ErrPermissionDenied is matched (errors.Is) by every DeniedError.
+var ErrPermissionDenied = errors.New("pubsub: permission denied")
+
+// DeniedError reports which permission was missing.
+type DeniedError struct {
+	Principal Principal
+	Action    Action
+	Target    string // topic name or subscription pattern
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("pubsub: %s may not %s %q", e.Principal, e.Action, e.Target)
+}
+
+func (e *DeniedError) Unwrap() error { return ErrPermissionDenied }
+
+// Authorizer decides whether a principal may perform an action on a
+// topic or subscription pattern (Strategy Pattern).
+type Authorizer interface {
+	Authorize(p Principal, action Action, target string) error
+}
+
+// Effect is whether an ACL rule grants or forbids.
+type Effect int
+
+const (
+	Allow Effect = iota
+	Deny
+)
+
+func (e Effect) String() string {
+	switch e {
+	case Allow:
+		return "allow"
+	case Deny:
+		return "deny"
+	default:
+		return "Unknown"
+	}
+}
+
+// ACLRule grants or forbids Actions on topics matching Pattern.
+type ACLRule struct {
+	Principal Principal // or Everyone
+	Pattern   string    // topic name or wildcard pattern
+	Actions   Action
+	Effect    Effect
+}
+
+// AuditEntry records one attempted ACL change.
+type AuditEntry struct {
+	Time    time.Time `json:"time"`
+	By      Principal `json:"by"`
+	Op      string    `json:"op"` // "allow", "deny" or "revoke"
+	Rule    ACLRule   `json:"rule"`
+	Applied bool      `json:"applied"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// ACL is a rule-based Authorizer. A request is allowed if it is made by
+// a superuser, or if an Allow rule covers the whole target and no Deny
+// rule overlaps it (deny wins). Everything else is denied. For a
+// subscription pattern, "covers" means every topic it can match.
+//
+// Rules are changed by superusers or by principals holding ActionAdmin
+// over the rule's pattern, and every attempt is kept in an audit trail.
+type ACL struct {
+	sep    string
+	syntax WildcardSyntax
+
+	mu         sync.RWMutex
+	superusers map[Principal]bool
+	rules      []aclRule
+	audit      []AuditEntry
+	auditLog   io.Writer
+}
+
+// aclRule is an ACLRule with its parsed pattern.
+type aclRule struct {
+	ACLRule
+	pattern pattern
+}
+
+var _ Authorizer = (*ACL)(nil)
+
+// NewACL builds an ACL whose patterns use the same separator and
+// wildcard syntax as a Broker built with opts. (Factory Pattern)
+func NewACL(opts BrokerOptions, superusers ...Principal) *ACL {
+	if opts.Separator == "" {
+		opts.Separator = "."
+	}
+	a := &ACL{sep: opts.Separator, syntax: opts.Wildcards, superusers: make(map[Principal]bool)}
+	for _, p := range superusers {
+		a.superusers[p] = true
+	}
+	return a
+}
+
+// SetAuditLog also writes every audit entry to w as a JSON line.
+func (a *ACL) SetAuditLog(w io.Writer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.auditLog = w
+}
+
+// Allow lets principal perform actions on topics matching pattern.
+func (a *ACL) Allow(by, principal Principal, pattern string, actions Action) error {
+	return a.change(by, "allow", ACLRule{Principal: principal, Pattern: pattern, Actions: actions, Effect: Allow})
+}
+
+// Deny forbids principal from performing actions on topics matching
+// pattern, overriding any Allow rule.
+func (a *ACL) Deny(by, principal Principal, pattern string, actions Action) error {
+	return a.change(by, "deny", ACLRule{Principal: principal, Pattern: pattern, Actions: actions, Effect: Deny})
+}
+
+// Revoke removes every rule for principal with exactly this pattern.
+func (a *ACL) Revoke(by, principal Principal, pattern string) error {
+	return a.change(by, "revoke", ACLRule{Principal: principal, Pattern: pattern})
+}
+
+// change validates and applies one rule change, recording it in the
+// audit trail whether or not it succeeds.
+func (a *ACL) change(by Principal, op string, rule ACLRule) error {
+	p, err := parsePattern(rule.Pattern, a.sep, a.syntax)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err == nil {
+		err = a.authorizeLocked(by, ActionAdmin, p, rule.Pattern)
+	}
+	if err == nil {
+		if op == "revoke" {
+			a.rules = slices.DeleteFunc(a.rules, func(r aclRule) bool {
+				return r.Principal == rule.Principal && r.Pattern == rule.Pattern
+			})
+		} else {
+			a.rules = append(a.rules, aclRule{ACLRule: rule, pattern: p})
+		}
+	}
+
+	entry := AuditEntry{Time: time.Now(), By: by, Op: op, Rule: rule, Applied: err == nil}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	a.audit = append(a.audit, entry)
+	if a.auditLog != nil {
+		line, _ := json.Marshal(entry)
+		a.auditLog.Write(append(line, '\n'))
+	}
+	return err
+}
+
+// Rules returns a copy of the current rules.
+func (a *ACL) Rules() []ACLRule {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	out := make([]ACLRule, len(a.rules))
+	for i, r := range a.rules {
+		out[i] = r.ACLRule
+	}
+	return out
+}
+
+// Audit returns the audit trail, oldest first.
+func (a *ACL) Audit() []AuditEntry {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return slices.Clone(a.audit)
+}
+
+// Authorize implements Authorizer. target is a topic name or pattern.
+func (a *ACL) Authorize(p Principal, action Action, target string) error {
+	t, err := parsePattern(target, a.sep, a.syntax)
+	if err != nil {
+		return err
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.authorizeLocked(p, action, t, target)
+}
+
+// authorizeLocked evaluates the rules. Caller holds a.mu.
+func (a *ACL) authorizeLocked(p Principal, action Action, t pattern, target string) error {
+	if a.superusers[p] {
+		return nil
+	}
+	allowed := false
+	for _, r := range a.rules {
+		if r.Actions&action == 0 || (r.Principal != p && r.Principal != Everyone) {
+			continue
+		}
+		switch {
+		case r.Effect == Deny && r.pattern.overlaps(t):
+			return &DeniedError{Principal: p, Action: action, Target: target}
+		case r.Effect == Allow && r.pattern.covers(t):
+			allowed = true
+		}
+	}
+	if !allowed {
+		return &DeniedError{Principal: p, Action: action, Target: target}
+	}
+	return nil
+}
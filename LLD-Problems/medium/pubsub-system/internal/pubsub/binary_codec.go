@@ -0,0 +1,304 @@
+package pubsub
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// BinaryCodec is a compact, protobuf-like encoding of structs. Each
+// exported field is written as a key — field number << 3 | wire type —
+// followed by its value:
+//
+//	wire 0 (varint):  bool, ints (zig-zag), uints
+//	wire 1 (fixed64): float32, float64
+//	wire 2 (bytes):   string, []byte, nested struct (length-prefixed)
+//
+// Field numbers come from a `pb:"N"` tag, or default to the field's
+// position (1-based). Slices other than []byte are repeated fields, one
+// key per element; their elements may not be nil or repeated fields
+// themselves. Types implementing encoding.BinaryMarshaler (such as
+// time.Time) are written as bytes in their own format; other structs
+// need at least one exported field. Zero values are omitted, and
+// unknown field numbers are skipped when decoding, so fields can be
+// added without breaking older readers.
+type BinaryCodec struct{}
+
+var errBinaryTruncated = errors.New("pubsub: binary payload truncated")
+
+var (
+	binaryMarshalerType   = reflect.TypeFor[encoding.BinaryMarshaler]()
+	binaryUnmarshalerType = reflect.TypeFor[encoding.BinaryUnmarshaler]()
+)
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func (BinaryCodec) ContentType() string { return "application/x-pubsub-binary" }
+
+func (BinaryCodec) Marshal(v interface{}) ([]byte, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("pubsub: BinaryCodec encodes structs, not %T", v)
+	}
+	return appendStruct(nil, rv)
+}
+
+func (BinaryCodec) Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("pubsub: BinaryCodec decodes into a struct pointer, not %T", v)
+	}
+	return decodeStruct(data, rv.Elem())
+}
+
+// fieldNumber returns the wire field number of struct field i.
+func fieldNumber(f reflect.StructField, i int) (uint64, error) {
+	tag := f.Tag.Get("pb")
+	if tag == "" {
+		return uint64(i + 1), nil
+	}
+	n, err := strconv.ParseUint(tag, 10, 29)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("pubsub: bad pb tag %q on field %s", tag, f.Name)
+	}
+	return n, nil
+}
+
+func appendStruct(b []byte, rv reflect.Value) ([]byte, error) {
+	t := rv.Type()
+	if t.NumField() > 0 && !hasExportedField(t) {
+		return nil, fmt.Errorf("pubsub: BinaryCodec cannot encode %s: no exported fields", t)
+	}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		num, err := fieldNumber(f, i)
+		if err != nil {
+			return nil, err
+		}
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			for j := range fv.Len() {
+				elem := fv.Index(j)
+				if elem.Kind() == reflect.Pointer && elem.IsNil() {
+					return nil, fmt.Errorf("pubsub: BinaryCodec cannot encode nil element %d of %s", j, f.Name)
+				}
+				if b, err = appendField(b, num, elem, true); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if b, err = appendField(b, num, fv, false); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// appendField writes one key/value pair. Zero values are skipped unless
+// they are elements of a repeated field.
+func appendField(b []byte, num uint64, v reflect.Value, keepZero bool) ([]byte, error) {
+	if !keepZero && v.IsZero() {
+		return b, nil
+	}
+	if m, ok := binaryMarshaler(v); ok {
+		data, err := m.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("pubsub: BinaryCodec cannot encode %s: %w", v.Type(), err)
+		}
+		b = binary.AppendUvarint(b, num<<3|wireBytes)
+		b = binary.AppendUvarint(b, uint64(len(data)))
+		return append(b, data...), nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		x := uint64(0)
+		if v.Bool() {
+			x = 1
+		}
+		b = binary.AppendUvarint(binary.AppendUvarint(b, num<<3|wireVarint), x)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b = binary.AppendVarint(binary.AppendUvarint(b, num<<3|wireVarint), v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		b = binary.AppendUvarint(binary.AppendUvarint(b, num<<3|wireVarint), v.Uint())
+	case reflect.Float32, reflect.Float64:
+		b = binary.AppendUvarint(b, num<<3|wireFixed64)
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v.Float()))
+	case reflect.String:
+		b = binary.AppendUvarint(b, num<<3|wireBytes)
+		b = appendString(b, v.String())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return nil, fmt.Errorf("pubsub: BinaryCodec cannot encode nested repeated field %s", v.Type())
+		}
+		b = binary.AppendUvarint(b, num<<3|wireBytes)
+		b = binary.AppendUvarint(b, uint64(v.Len()))
+		b = append(b, v.Bytes()...)
+	case reflect.Struct:
+		inner, err := appendStruct(nil, v)
+		if err != nil {
+			return nil, err
+		}
+		b = binary.AppendUvarint(b, num<<3|wireBytes)
+		b = binary.AppendUvarint(b, uint64(len(inner)))
+		b = append(b, inner...)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return b, nil
+		}
+		return appendField(b, num, v.Elem(), true)
+	default:
+		return nil, fmt.Errorf("pubsub: BinaryCodec cannot encode %s", v.Type())
+	}
+	return b, nil
+}
+
+// binaryMarshaler returns v as an encoding.BinaryMarshaler if its type
+// (or, when addressable, its pointer) implements one.
+func binaryMarshaler(v reflect.Value) (encoding.BinaryMarshaler, bool) {
+	switch {
+	case v.Kind() == reflect.Pointer || !v.CanInterface():
+		return nil, false
+	case v.Type().Implements(binaryMarshalerType):
+		return v.Interface().(encoding.BinaryMarshaler), true
+	case v.CanAddr() && v.Addr().Type().Implements(binaryMarshalerType):
+		return v.Addr().Interface().(encoding.BinaryMarshaler), true
+	}
+	return nil, false
+}
+
+func hasExportedField(t reflect.Type) bool {
+	for i := range t.NumField() {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeStruct(data []byte, rv reflect.Value) error {
+	t := rv.Type()
+	fields := make(map[uint64]int, t.NumField())
+	for i := range t.NumField() {
+		if f := t.Field(i); f.IsExported() {
+			num, err := fieldNumber(f, i)
+			if err != nil {
+				return err
+			}
+			fields[num] = i
+		}
+	}
+
+	for len(data) > 0 {
+		key, w := binary.Uvarint(data)
+		if w <= 0 {
+			return errBinaryTruncated
+		}
+		data = data[w:]
+		num, wire := key>>3, key&7
+
+		// Read the raw value for this wire type.
+		var (
+			scalar uint64
+			raw    []byte
+		)
+		switch wire {
+		case wireVarint:
+			if scalar, w = binary.Uvarint(data); w <= 0 {
+				return errBinaryTruncated
+			}
+			data = data[w:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errBinaryTruncated
+			}
+			scalar, data = binary.LittleEndian.Uint64(data), data[8:]
+		case wireBytes:
+			n, w := binary.Uvarint(data)
+			if w <= 0 || n > uint64(len(data)-w) {
+				return errBinaryTruncated
+			}
+			raw, data = data[w:w+int(n)], data[w+int(n):]
+		default:
+			return fmt.Errorf("pubsub: unknown wire type %d", wire)
+		}
+
+		i, ok := fields[num]
+		if !ok {
+			continue // field added by a newer writer
+		}
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			elem := reflect.New(fv.Type().Elem()).Elem()
+			if err := setField(elem, wire, scalar, raw); err != nil {
+				return err
+			}
+			fv.Set(reflect.Append(fv, elem))
+			continue
+		}
+		if err := setField(fv, wire, scalar, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setField stores a decoded value, checking it has the expected wire type.
+func setField(v reflect.Value, wire, scalar uint64, raw []byte) error {
+	want := uint64(wireBytes)
+	switch v.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		want = wireVarint
+	case reflect.Float32, reflect.Float64:
+		want = wireFixed64
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setField(v.Elem(), wire, scalar, raw)
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(binaryUnmarshalerType) {
+		if wire != wireBytes {
+			return fmt.Errorf("pubsub: wire type %d does not match %s", wire, v.Type())
+		}
+		return v.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(raw)
+	}
+	if wire != want {
+		return fmt.Errorf("pubsub: wire type %d does not match %s", wire, v.Type())
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(scalar != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(scalar>>1) ^ -int64(scalar&1)) // zig-zag
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(scalar)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(math.Float64frombits(scalar))
+	case reflect.String:
+		v.SetString(string(raw))
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("pubsub: BinaryCodec cannot decode nested repeated field %s", v.Type())
+		}
+		v.SetBytes(append([]byte(nil), raw...))
+	case reflect.Struct:
+		return decodeStruct(raw, v)
+	default:
+		return fmt.Errorf("pubsub: BinaryCodec cannot decode %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package pubsub
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBinaryCodecRejectsUnsupportedSlices(t *testing.T) {
+	type point struct{ X int }
+	cases := map[string]interface{}{
+		"nested ints":    struct{ Grid [][]int }{Grid: [][]int{{1, 2}, {3}}},
+		"nested strings": struct{ Rows [][]string }{Rows: [][]string{{"a"}}},
+		"nil element":    struct{ Points []*point }{Points: []*point{{X: 1}, nil, {X: 2}}},
+	}
+	for name, v := range cases {
+		if _, err := (BinaryCodec{}).Marshal(v); err == nil || !strings.Contains(err.Error(), "cannot encode") {
+			t.Errorf("%s: Marshal error = %v, want cannot encode", name, err)
+		}
+	}
+}
+
+func TestBinaryCodecRoundTripsTime(t *testing.T) {
+	type event struct {
+		Name string
+		At   time.Time
+		Seen []time.Time
+	}
+	at := time.Date(2025, 6, 6, 18, 30, 0, 123, time.UTC)
+	in := event{Name: "door", At: at, Seen: []time.Time{at, at.Add(time.Hour)}}
+	data, err := (BinaryCodec{}).Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out event
+	if err := (BinaryCodec{}).Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.At.Equal(at) || len(out.Seen) != 2 || !out.Seen[1].Equal(at.Add(time.Hour)) {
+		t.Fatalf("round trip = %+v, want %+v", out, in)
+	}
+}
+
+func TestBinaryCodecRejectsOpaqueStructs(t *testing.T) {
+	type opaque struct{ secret int }
+	v := struct{ O opaque }{O: opaque{secret: 1}}
+	if _, err := (BinaryCodec{}).Marshal(v); err == nil {
+		t.Fatal("Marshal of a struct without exported fields succeeded")
+	}
+}
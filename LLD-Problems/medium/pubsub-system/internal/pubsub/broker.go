@@ -0,0 +1,420 @@
+package pubsub
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"pubsub-system/internal/commitlog"
+)
+
+// ErrNoSuchTopic is returned when publishing to an unknown topic on a
+// Broker without AutoCreate.
+var ErrNoSuchTopic = errors.New("pubsub: no such topic")
+
+// ErrBrokerClosed is returned by a closed Broker.
+var ErrBrokerClosed = errors.New("pubsub: broker closed")
+
+// ErrNotDurable is returned when replaying an in-memory topic, which
+// keeps no history.
+var ErrNotDurable = errors.New("pubsub: topic is not durable")
+
+// BrokerOptions configures a Broker.
+type BrokerOptions struct {
+	// AutoCreate creates a topic on its first Publish instead of
+	// failing with ErrNoSuchTopic.
+	AutoCreate bool
+	// Separator splits hierarchical names into levels; defaults to ".".
+	Separator string
+	// Wildcards selects MQTT ("+", "#") or NATS ("*", ">") patterns.
+	Wildcards WildcardSyntax
+	// Authorizer, if set, checks every publish, subscribe and topic
+	// change (see ACL). Without one everything is allowed.
+	Authorizer Authorizer
+	// ScheduleDir, if set, stores messages scheduled with PublishAt so
+	// they survive a restart. Without it they are kept in memory only.
+	ScheduleDir string
+	// DedupWindow, if set, is applied to the topics the broker creates
+	// (see Topic.SetDedupWindow).
+	DedupWindow time.Duration
+}
+
+// Broker owns topics by hierarchical name (e.g. "orders.eu.created")
+// and routes wildcard subscriptions to every matching topic, including
+// topics created after subscribing.
+type Broker struct {
+	opts BrokerOptions
+
+	mu     sync.RWMutex
+	topics map[string]*Topic
+	subs   map[*BrokerSubscription]struct{}
+	closed bool
+	subSeq uint64 // numbers BrokerSubscription IDs
+
+	sched *scheduler
+}
+
+// BrokerSubscription is a pattern subscription on a Broker. All the
+// topics it matches feed a single queue, so its Subscriber is called
+// from one goroutine and sees each topic's messages in order.
+type BrokerSubscription struct {
+	ID      string // "sub-N", unique within the broker
+	Pattern string
+
+	broker  *Broker
+	seq     uint64
+	pattern pattern
+	start   StartPosition
+	sub     *Subscription
+}
+
+// patternSubscriber gives each BrokerSubscription a distinct key in the
+// topics' subscriber maps, even if the same Subscriber is reused.
+type patternSubscriber struct {
+	Subscriber
+}
+
+// NewBroker constructs an empty Broker (Factory Pattern). It panics if
+// opts.ScheduleDir cannot be loaded; use OpenBroker to get the error.
+func NewBroker(opts BrokerOptions) *Broker {
+	b, err := OpenBroker(opts)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// OpenBroker constructs an empty Broker and reschedules the messages
+// left pending in opts.ScheduleDir, if set. (Factory Pattern)
+func OpenBroker(opts BrokerOptions) (*Broker, error) {
+	if opts.Separator == "" {
+		opts.Separator = "."
+	}
+	b := &Broker{
+		opts:   opts,
+		topics: make(map[string]*Topic),
+		subs:   make(map[*BrokerSubscription]struct{}),
+	}
+	sched, err := newScheduler(b, opts.ScheduleDir)
+	if err != nil {
+		return nil, err
+	}
+	b.sched = sched
+	return b, nil
+}
+
+// authorize consults the Authorizer, if any.
+func (b *Broker) authorize(p Principal, action Action, target string) error {
+	if b.opts.Authorizer == nil {
+		return nil
+	}
+	return b.opts.Authorizer.Authorize(p, action, target)
+}
+
+// CreateTopic is CreateTopicAs(Anonymous, name).
+func (b *Broker) CreateTopic(name string) (*Topic, error) {
+	return b.CreateTopicAs(Anonymous, name)
+}
+
+// CreateTopicAs returns the named in-memory topic, creating it if
+// needed. Creating a topic requires ActionAdmin on its name.
+func (b *Broker) CreateTopicAs(p Principal, name string) (*Topic, error) {
+	if err := validateTopicName(name, b.opts.Separator, b.opts.Wildcards); err != nil {
+		return nil, err
+	}
+	if t, ok := b.Topic(name); ok {
+		return t, nil
+	}
+	if err := b.authorize(p, ActionAdmin, name); err != nil {
+		return nil, err
+	}
+	return b.createTopic(name)
+}
+
+// createTopic creates a topic without authorization.
+func (b *Broker) createTopic(name string) (*Topic, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok := b.topics[name]; ok {
+		return t, nil
+	}
+	t := NewTopic(name)
+	t.SetDedupWindow(b.opts.DedupWindow) // in-memory: cannot fail
+	return t, b.addLocked(t)
+}
+
+// AddTopic registers an existing topic (e.g. from NewDurableTopic)
+// under its Name. Handing over a *Topic is in-process setup, so it is
+// not authorized.
+func (b *Broker) AddTopic(t *Topic) error {
+	if err := validateTopicName(t.Name, b.opts.Separator, b.opts.Wildcards); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.topics[t.Name]; ok {
+		return fmt.Errorf("pubsub: topic %q already exists", t.Name)
+	}
+	return b.addLocked(t)
+}
+
+// addLocked stores t and attaches every matching pattern subscription.
+// Caller holds b.mu.
+func (b *Broker) addLocked(t *Topic) error {
+	if b.closed {
+		return ErrBrokerClosed
+	}
+	levels := strings.Split(t.Name, b.opts.Separator)
+	for bs := range b.subs {
+		if bs.pattern.matches(levels) {
+			t.attach(bs.sub, bs.start)
+		}
+	}
+	b.topics[t.Name] = t
+	return nil
+}
+
+// Topic looks up a topic by name.
+func (b *Broker) Topic(name string) (*Topic, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	t, ok := b.topics[name]
+	return t, ok
+}
+
+// Topics returns the sorted names of all topics.
+func (b *Broker) Topics() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	names := make([]string, 0, len(b.topics))
+	for name := range b.topics {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// DeleteTopic is DeleteTopicAs(Anonymous, name).
+func (b *Broker) DeleteTopic(name string) error {
+	return b.DeleteTopicAs(Anonymous, name)
+}
+
+// DeleteTopicAs removes a topic and closes it; it requires ActionAdmin.
+// Pattern subscriptions are detached first and keep receiving from
+// their other topics.
+func (b *Broker) DeleteTopicAs(p Principal, name string) error {
+	if err := b.authorize(p, ActionAdmin, name); err != nil {
+		return err
+	}
+	return b.deleteTopic(name)
+}
+
+// deleteTopic removes a topic without authorization.
+func (b *Broker) deleteTopic(name string) error {
+	b.mu.Lock()
+	t, ok := b.topics[name]
+	if !ok {
+		b.mu.Unlock()
+		return ErrNoSuchTopic
+	}
+	delete(b.topics, name)
+	for bs := range b.subs {
+		t.detach(bs.sub)
+	}
+	b.mu.Unlock()
+	return t.Close()
+}
+
+// Publish is PublishAs(Anonymous, topic, msg).
+func (b *Broker) Publish(topic string, msg *Message) error {
+	return b.PublishAs(Anonymous, topic, msg)
+}
+
+// PublishAs sends msg to the named topic on behalf of p, which needs
+// ActionPublish on it. If AutoCreate is set a missing topic is created
+// first; publish permission is enough for that.
+func (b *Broker) PublishAs(p Principal, topic string, msg *Message) error {
+	if err := validateTopicName(topic, b.opts.Separator, b.opts.Wildcards); err != nil {
+		return err
+	}
+	if err := b.authorize(p, ActionPublish, topic); err != nil {
+		return err
+	}
+	return b.publish(topic, msg)
+}
+
+// publish sends msg to the named topic without authorization, creating
+// the topic if AutoCreate is set.
+func (b *Broker) publish(topic string, msg *Message) error {
+	t, ok := b.Topic(topic)
+	if !ok {
+		if !b.opts.AutoCreate {
+			return fmt.Errorf("%w: %s", ErrNoSuchTopic, topic)
+		}
+		var err error
+		if t, err = b.createTopic(topic); err != nil {
+			return err
+		}
+	}
+	return t.Publish(msg)
+}
+
+// ReplayDeadLetters redelivers up to limit messages (all if limit <= 0)
+// from the durable dead-letter topic dlq, starting at offset from, to
+// the subscription that gave up on each (DeadLetterSubscriptionHeader)
+// on its original topic; other subscribers of that topic, which may
+// have acked it, do not see it again. Each arrives with its original
+// topic, offset and ID and without the dead-letter headers, as a first
+// delivery. Messages whose subscription is gone, or that predate the
+// header, are skipped. It returns how many were replayed and the offset
+// to continue from. Replay is an admin operation and is not authorized.
+func (b *Broker) ReplayDeadLetters(dlq string, from uint64, limit int) (int, uint64, error) {
+	t, ok := b.Topic(dlq)
+	if !ok {
+		return 0, from, fmt.Errorf("%w: %s", ErrNoSuchTopic, dlq)
+	}
+	if t.log == nil {
+		return 0, from, fmt.Errorf("%w: %s", ErrNotDurable, dlq)
+	}
+	next := max(from, t.log.OldestOffset())
+	replayed := 0
+	for ; next < t.log.NextOffset() && (limit <= 0 || replayed < limit); next++ {
+		rec, err := t.log.Read(next)
+		if errors.Is(err, commitlog.ErrOutOfRange) {
+			continue // removed by retention meanwhile
+		}
+		if err != nil {
+			return replayed, next, err
+		}
+		msg := t.decodeMessage(rec)
+		orig, ok := b.Topic(msg.Header(OriginalTopicHeader))
+		if !ok {
+			continue
+		}
+		sub, ok := orig.subscriptionByID(msg.Header(DeadLetterSubscriptionHeader))
+		if !ok {
+			continue
+		}
+		offset, err := strconv.ParseUint(msg.Header(OriginalOffsetHeader), 10, 64)
+		if err != nil {
+			continue
+		}
+		for _, h := range []string{OriginalTopicHeader, OriginalOffsetHeader, DeadLetterReasonHeader, DeadLetterSubscriptionHeader} {
+			delete(msg.Headers, h)
+		}
+		msg.Topic, msg.Offset, msg.Partition, msg.txn = orig.Name, offset, orig.partition, nil
+		if sub.enqueue(msg) {
+			orig.detach(sub)
+			continue
+		}
+		replayed++
+	}
+	return replayed, next, nil
+}
+
+// Subscribe is SubscribeAs(Anonymous, pattern, s, opts).
+func (b *Broker) Subscribe(pattern string, s Subscriber, opts SubscriptionOptions) (*BrokerSubscription, error) {
+	return b.SubscribeAs(Anonymous, pattern, s, opts)
+}
+
+// SubscribeAs delivers messages from every topic matching pattern, now
+// or later, to s through one subscription queue configured by opts. A
+// pattern without wildcards subscribes to a single topic by name,
+// whether or not it exists yet. The principal needs ActionSubscribe
+// covering the whole pattern; the check is made once, at subscribe time.
+func (b *Broker) SubscribeAs(principal Principal, pattern string, s Subscriber, opts SubscriptionOptions) (*BrokerSubscription, error) {
+	p, err := parsePattern(pattern, b.opts.Separator, b.opts.Wildcards)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.authorize(principal, ActionSubscribe, pattern); err != nil {
+		return nil, err
+	}
+	sub, err := newSubscription(&patternSubscriber{s}, opts)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.Close()
+		return nil, ErrBrokerClosed
+	}
+	b.subSeq++
+	sub.id = fmt.Sprintf("sub-%d", b.subSeq)
+	bs := &BrokerSubscription{
+		ID:      sub.id,
+		seq:     b.subSeq,
+		Pattern: pattern,
+		broker:  b,
+		pattern: p,
+		start:   opts.StartAt,
+		sub:     sub,
+	}
+	for name, t := range b.topics {
+		if p.matches(strings.Split(name, b.opts.Separator)) {
+			t.attach(bs.sub, bs.start)
+		}
+	}
+	b.subs[bs] = struct{}{}
+	return bs, nil
+}
+
+// Subscriptions returns the open pattern subscriptions, oldest first.
+func (b *Broker) Subscriptions() []*BrokerSubscription {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make([]*BrokerSubscription, 0, len(b.subs))
+	for bs := range b.subs {
+		out = append(out, bs)
+	}
+	slices.SortFunc(out, func(x, y *BrokerSubscription) int { return cmp.Compare(x.seq, y.seq) })
+	return out
+}
+
+// Subscription exposes the queue's state and counters.
+func (bs *BrokerSubscription) Subscription() *Subscription { return bs.sub }
+
+// Unsubscribe detaches from all topics and closes the queue; already
+// queued messages are still delivered (see Subscription.Wait).
+func (bs *BrokerSubscription) Unsubscribe() {
+	b := bs.broker
+	b.mu.Lock()
+	delete(b.subs, bs)
+	for _, t := range b.topics {
+		t.detach(bs.sub)
+	}
+	b.mu.Unlock()
+	bs.sub.Close()
+}
+
+// Close stops the scheduler, then closes every topic and pattern
+// subscription and waits for queued messages to be delivered. Pending
+// scheduled messages stay in ScheduleDir for the next OpenBroker.
+func (b *Broker) Close() error {
+	b.sched.close()
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	topics, subs := b.topics, b.subs
+	b.topics, b.subs = make(map[string]*Topic), make(map[*BrokerSubscription]struct{})
+	b.mu.Unlock()
+
+	var errs []error
+	for _, t := range topics {
+		errs = append(errs, t.Close())
+	}
+	for bs := range subs {
+		bs.sub.Close()
+		bs.sub.Wait()
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,102 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"pubsub-system/internal/commitlog"
+)
+
+// failingSubscriber nacks every message until fixed.
+type failingSubscriber struct {
+	countingSubscriber
+	fixed bool
+}
+
+func (f *failingSubscriber) OnMessage(msg *Message) error {
+	f.mu.Lock()
+	fixed := f.fixed
+	f.mu.Unlock()
+	if !fixed {
+		return errors.New("cannot parse")
+	}
+	return f.countingSubscriber.OnMessage(msg)
+}
+
+func TestReplayDeadLettersOnlyToFailedSubscription(t *testing.T) {
+	b := NewBroker(BrokerOptions{AutoCreate: true})
+	defer b.Close()
+	dlq, err := NewDurableTopic("dlq", t.TempDir(), commitlog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.AddTopic(dlq)
+
+	parser, archive := &failingSubscriber{}, &countingSubscriber{}
+	parsing, _ := b.Subscribe("orders", parser, SubscriptionOptions{
+		Retry:      RetryPolicy{MaxAttempts: 1},
+		DeadLetter: dlq,
+	})
+	b.Subscribe("orders", archive, SubscriptionOptions{})
+	if err := b.Publish("orders", NewMessage("order 1")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return parsing.Subscription().DeadLettered() == 1 && archive.count() == 1 })
+
+	parser.mu.Lock()
+	parser.fixed = true
+	parser.mu.Unlock()
+	n, next, err := b.ReplayDeadLetters("dlq", 0, 0)
+	if err != nil || n != 1 || next != 1 {
+		t.Fatalf("ReplayDeadLetters = %d, %d, %v; want 1, 1, nil", n, next, err)
+	}
+	waitFor(t, func() bool { return parser.count() == 1 })
+	time.Sleep(20 * time.Millisecond)
+	if n := archive.count(); n != 1 {
+		t.Errorf("archive received %d messages, want only the original", n)
+	}
+	got := parser.msgs[0]
+	if got.Topic != "orders" || got.Offset != 0 || got.Header(DeadLetterSubscriptionHeader) != "" {
+		t.Errorf("replayed message = topic %q offset %d headers %v", got.Topic, got.Offset, got.Headers)
+	}
+}
+
+// waitFor polls cond for up to five seconds.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+	}
+}
+
+// responder replies to every request with "pong".
+type responder struct{ b *Broker }
+
+func (r responder) OnMessage(msg *Message) error {
+	return r.b.Reply(msg, NewMessage("pong"))
+}
+
+func TestWildcardsDoNotMatchInboxes(t *testing.T) {
+	b := NewBroker(BrokerOptions{AutoCreate: true})
+	defer b.Close()
+	everything := &countingSubscriber{}
+	b.Subscribe("#", everything, SubscriptionOptions{})
+	b.Subscribe("rpc", responder{b}, SubscriptionOptions{})
+
+	reply, err := b.Request(context.Background(), "rpc", NewMessage("ping"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Text() != "pong" {
+		t.Fatalf("reply = %q", reply.Text())
+	}
+	waitFor(t, func() bool { return everything.count() >= 1 })
+	time.Sleep(10 * time.Millisecond)
+	if n := everything.count(); n != 1 {
+		t.Fatalf("%q saw %d messages, want only the request", "#", n)
+	}
+}
@@ -0,0 +1,109 @@
+package pubsub
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// EventTypeHeader names the Go type a payload was encoded from, so
+// subscribers can dispatch on it before decoding.
+const EventTypeHeader = "event-type"
+
+// Codec converts typed values to and from message payloads
+// (Strategy Pattern).
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec encodes with encoding/json.
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string                        { return "application/json" }
+func (JSONCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// GobCodec encodes with encoding/gob. Each payload carries its own type
+// description, so it is larger than BinaryCodec but needs no tags.
+type GobCodec struct{}
+
+func (GobCodec) ContentType() string { return "application/x-gob" }
+
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{}
+)
+
+func init() {
+	RegisterCodec(JSONCodec{})
+	RegisterCodec(GobCodec{})
+	RegisterCodec(BinaryCodec{})
+}
+
+// RegisterCodec makes c available to Message.Decode under its content
+// type, replacing any codec already registered for it.
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[c.ContentType()] = c
+}
+
+// CodecFor returns the codec registered for contentType.
+func CodecFor(contentType string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[contentType]
+	return c, ok
+}
+
+// NewEvent encodes v with codec into a Message whose ContentType and
+// EventTypeHeader describe it (Factory Pattern).
+func NewEvent(v interface{}, codec Codec) (*Message, error) {
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	m := &Message{Payload: data, ContentType: codec.ContentType()}
+	m.SetHeader(EventTypeHeader, EventType(v))
+	return m, nil
+}
+
+// EventType is the EventTypeHeader value for v: its type name without
+// pointers, e.g. "main.OrderPlaced".
+func EventType(v interface{}) string {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
+
+// Decode unmarshals the payload into v using the codec registered for
+// the message's ContentType.
+func (m *Message) Decode(v interface{}) error {
+	c, ok := CodecFor(m.ContentType)
+	if !ok {
+		return fmt.Errorf("pubsub: no codec registered for content type %q", m.ContentType)
+	}
+	return c.Unmarshal(m.Payload, v)
+}
@@ -0,0 +1,144 @@
+package pubsub
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"pubsub-system/internal/commitlog"
+)
+
+// Headers set by an idempotent Publisher.
+const (
+	ProducerIDHeader  = "producer-id"
+	ProducerSeqHeader = "producer-seq"
+)
+
+// dedupWindow remembers what a topic published recently so retried
+// publishes can be dropped: message IDs for a fixed window, and the
+// last sequence number of every idempotent producer.
+type dedupWindow struct {
+	window     time.Duration
+	ids        map[string]uint64 // message ID → offset
+	expiry     []dedupEntry      // oldest first
+	producers  map[string]producerState
+	duplicates uint64
+}
+
+type dedupEntry struct {
+	id string
+	at time.Time
+}
+
+type producerState struct {
+	seq    uint64
+	offset uint64
+}
+
+func newDedupWindow(window time.Duration) *dedupWindow {
+	return &dedupWindow{
+		window:    window,
+		ids:       make(map[string]uint64),
+		producers: make(map[string]producerState),
+	}
+}
+
+// check reports whether msg was already published, and at which offset
+// if known.
+func (d *dedupWindow) check(msg *Message, now time.Time) (offset uint64, known, dup bool) {
+	d.expire(now)
+	if off, ok := d.ids[msg.ID]; ok {
+		d.duplicates++
+		return off, true, true
+	}
+	if pid, seq, ok := producerSeq(msg); ok {
+		if st, seen := d.producers[pid]; seen && seq <= st.seq {
+			d.duplicates++
+			return st.offset, seq == st.seq, true
+		}
+	}
+	return 0, false, false
+}
+
+// record remembers a published msg.
+func (d *dedupWindow) record(msg *Message, at time.Time) {
+	if _, ok := d.ids[msg.ID]; !ok {
+		d.ids[msg.ID] = msg.Offset
+		d.expiry = append(d.expiry, dedupEntry{id: msg.ID, at: at})
+	}
+	if pid, seq, ok := producerSeq(msg); ok {
+		if st, seen := d.producers[pid]; !seen || seq > st.seq {
+			d.producers[pid] = producerState{seq: seq, offset: msg.Offset}
+		}
+	}
+}
+
+// expire forgets IDs recorded more than a window before now.
+func (d *dedupWindow) expire(now time.Time) {
+	cutoff := now.Add(-d.window)
+	n := 0
+	for n < len(d.expiry) && d.expiry[n].at.Before(cutoff) {
+		delete(d.ids, d.expiry[n].id)
+		n++
+	}
+	d.expiry = d.expiry[n:]
+}
+
+// producerSeq extracts the idempotent producer headers.
+func producerSeq(msg *Message) (string, uint64, bool) {
+	pid := msg.Header(ProducerIDHeader)
+	if pid == "" {
+		return "", 0, false
+	}
+	seq, err := strconv.ParseUint(msg.Header(ProducerSeqHeader), 10, 64)
+	return pid, seq, err == nil
+}
+
+// SetDedupWindow makes Publish drop a message whose ID was published
+// in the last window, or whose idempotent producer already published
+// that sequence number or a later one. A dropped message is reported
+// as published, with msg.Offset set to the original's when known.
+// 0 turns deduplication off.
+//
+// A durable topic primes the window from the log, so duplicates are
+// still caught after a restart (producer sequence numbers only as far
+// back as the window reaches).
+func (t *Topic) SetDedupWindow(window time.Duration) error {
+	t.pubMu.Lock()
+	defer t.pubMu.Unlock()
+	if window <= 0 {
+		t.dedup = nil
+		return nil
+	}
+	d := newDedupWindow(window)
+	if t.log != nil {
+		from, err := t.log.OffsetForTime(time.Now().Add(-window))
+		if err != nil {
+			return err
+		}
+		for off := from; off < t.log.NextOffset(); off++ {
+			rec, err := t.log.Read(off)
+			if errors.Is(err, commitlog.ErrOutOfRange) {
+				continue // removed by retention meanwhile
+			}
+			if err != nil {
+				return err
+			}
+			if m := t.decodeMessage(rec); m.txn != skippedTxn {
+				d.record(m, rec.Timestamp)
+			}
+		}
+	}
+	t.dedup = d
+	return nil
+}
+
+// Duplicates returns how many publishes were dropped as duplicates.
+func (t *Topic) Duplicates() uint64 {
+	t.pubMu.Lock()
+	defer t.pubMu.Unlock()
+	if t.dedup == nil {
+		return 0
+	}
+	return t.dedup.duplicates
+}
@@ -0,0 +1,234 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"pubsub-system/internal/commitlog"
+)
+
+// startKind enumerates where a durable subscription begins reading.
+type startKind int
+
+const (
+	startLatest startKind = iota
+	startEarliest
+	startOffset
+	startTime
+)
+
+// StartPosition selects the first message a subscription receives on a
+// durable topic. In-memory topics keep no history and always start at
+// the latest message.
+type StartPosition struct {
+	kind   startKind
+	offset uint64
+	time   time.Time
+}
+
+// StartLatest receives only messages published after subscribing (default).
+func StartLatest() StartPosition { return StartPosition{kind: startLatest} }
+
+// StartEarliest replays every retained message first.
+func StartEarliest() StartPosition { return StartPosition{kind: startEarliest} }
+
+// StartAtOffset starts at the given offset (or the oldest retained one).
+func StartAtOffset(offset uint64) StartPosition {
+	return StartPosition{kind: startOffset, offset: offset}
+}
+
+// StartAtTime starts at the first message published at or after t.
+func StartAtTime(t time.Time) StartPosition { return StartPosition{kind: startTime, time: t} }
+
+// String formats the position as "latest", "earliest", "offset:N" or
+// "time:<RFC 3339>"; ParseStartPosition reads it back.
+func (p StartPosition) String() string {
+	switch p.kind {
+	case startEarliest:
+		return "earliest"
+	case startOffset:
+		return "offset:" + strconv.FormatUint(p.offset, 10)
+	case startTime:
+		return "time:" + p.time.Format(time.RFC3339Nano)
+	default:
+		return "latest"
+	}
+}
+
+// ParseStartPosition parses the output of StartPosition.String; the
+// empty string means latest.
+func ParseStartPosition(s string) (StartPosition, error) {
+	kind, arg, _ := strings.Cut(s, ":")
+	switch kind {
+	case "", "latest":
+		return StartLatest(), nil
+	case "earliest":
+		return StartEarliest(), nil
+	case "offset":
+		n, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return StartPosition{}, fmt.Errorf("pubsub: bad start offset %q", arg)
+		}
+		return StartAtOffset(n), nil
+	case "time":
+		t, err := time.Parse(time.RFC3339Nano, arg)
+		if err != nil {
+			return StartPosition{}, fmt.Errorf("pubsub: bad start time %q", arg)
+		}
+		return StartAtTime(t), nil
+	}
+	return StartPosition{}, fmt.Errorf("pubsub: unknown start position %q", s)
+}
+
+// NewDurableTopic constructs a Topic backed by a segmented append-only
+// log in dir. Reopening the same dir restores its history and offsets.
+// (Factory Pattern)
+func NewDurableTopic(name, dir string, opts commitlog.Options) (*Topic, error) {
+	log, err := commitlog.Open(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	t := NewTopic(name)
+	t.log = log
+	if err := t.loadAbortedTxns(); err != nil {
+		log.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// loadAbortedTxns finds the transactions in the log that did not
+// commit: their last marker is an abort, or they have no marker at all
+// because the process stopped before Commit wrote one.
+func (t *Topic) loadAbortedTxns() error {
+	committed := make(map[string]bool)
+	for off := t.log.OldestOffset(); off < t.log.NextOffset(); off++ {
+		rec, err := t.log.Read(off)
+		if errors.Is(err, commitlog.ErrOutOfRange) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		m, err := decodeEnvelope(rec.Payload)
+		if err != nil {
+			continue
+		}
+		id := m.Header(TxnHeader)
+		if id == "" {
+			continue
+		}
+		switch m.Header(txnMarkerHeader) {
+		case txnCommitted:
+			committed[id] = true
+		case txnAborted:
+			committed[id] = false
+		default: // a record; any marker comes after it
+			if _, ok := committed[id]; !ok {
+				committed[id] = false
+			}
+		}
+	}
+	for id, ok := range committed {
+		if !ok {
+			if t.aborted == nil {
+				t.aborted = make(map[string]struct{})
+			}
+			t.aborted[id] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// decodeMessage rebuilds a Message from one of t's log records. A
+// record that is not a valid envelope is delivered as raw bytes.
+// Transaction markers and records of aborted transactions come back
+// with skippedTxn, so subscriptions pass over them.
+func (t *Topic) decodeMessage(rec commitlog.Record) *Message {
+	m, err := decodeEnvelope(rec.Payload)
+	if err != nil {
+		m = &Message{Payload: rec.Payload, ContentType: "application/octet-stream", Timestamp: rec.Timestamp}
+	}
+	m.Topic, m.Offset, m.Partition = t.Name, rec.Offset, t.partition
+	if id := m.Header(TxnHeader); id != "" {
+		t.mu.RLock()
+		m.txn = t.txns[id] // nil once committed
+		_, aborted := t.aborted[id]
+		t.mu.RUnlock()
+		if aborted || m.Header(txnMarkerHeader) != "" {
+			m.txn = skippedTxn
+		}
+	}
+	return m
+}
+
+// resolveStart turns a StartPosition into a log offset.
+func (t *Topic) resolveStart(pos StartPosition) (uint64, error) {
+	switch pos.kind {
+	case startEarliest:
+		return t.log.OldestOffset(), nil
+	case startOffset:
+		return max(pos.offset, t.log.OldestOffset()), nil
+	case startTime:
+		return t.log.OffsetForTime(pos.time)
+	default:
+		return t.log.NextOffset(), nil
+	}
+}
+
+// drainFeeders lets every log reader catch up with the end of the log,
+// then stops them.
+func (t *Topic) drainFeeders() {
+	t.closeOnce.Do(func() { close(t.closing) })
+	t.feeders.Wait()
+}
+
+// feed copies messages from the log into sub's queue, starting at next,
+// and then follows the log as it grows until sub is closed.
+func (t *Topic) feed(sub *Subscription, next uint64) {
+	defer t.feeders.Done()
+	defer sub.setUnread(t, 0)
+	for {
+		changed := t.log.Changed() // taken before Read so no append is missed
+		if end := t.log.NextOffset(); end > next {
+			sub.setUnread(t, end-next)
+		} else {
+			sub.setUnread(t, 0)
+		}
+		rec, err := t.log.Read(next)
+		switch {
+		case err == nil:
+			if sub.enqueue(t.decodeMessage(rec)) {
+				t.detach(sub)
+				return
+			}
+			next++
+		case errors.Is(err, commitlog.ErrOutOfRange):
+			if oldest := t.log.OldestOffset(); next < oldest {
+				next = oldest // skipped past messages deleted by retention
+				continue
+			}
+			select {
+			case <-t.closing:
+				return // caught up and the topic is closing
+			default:
+			}
+			select {
+			case <-changed:
+			case <-sub.stopped:
+				return
+			case <-t.closing:
+			}
+		default:
+			return // log closed or unreadable
+		}
+		select {
+		case <-sub.stopped:
+			return
+		default:
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package pubsub
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// envelopeVersion is the first byte of every stored envelope.
+const envelopeVersion = 1
+
+var errBadEnvelope = errors.New("pubsub: malformed message envelope")
+
+// encodeEnvelope serializes the persisted fields of msg for the log:
+//
+//	version | ID | Key | uvarint(#headers) | (name | value)* |
+//	ContentType | varint(Timestamp ns) | Payload
+//
+// where each string is uvarint(len) followed by its bytes, and Payload
+// runs to the end of the record. Offset and Partition come from the log
+// itself and are not stored.
+func encodeEnvelope(msg *Message) []byte {
+	size := 16 + len(msg.ID) + len(msg.Key) + len(msg.ContentType) + len(msg.Payload)
+	for k, v := range msg.Headers {
+		size += 4 + len(k) + len(v)
+	}
+	b := make([]byte, 0, size)
+	b = append(b, envelopeVersion)
+	b = appendString(b, msg.ID)
+	b = appendString(b, msg.Key)
+	b = binary.AppendUvarint(b, uint64(len(msg.Headers)))
+	for k, v := range msg.Headers {
+		b = appendString(b, k)
+		b = appendString(b, v)
+	}
+	b = appendString(b, msg.ContentType)
+	b = binary.AppendVarint(b, msg.Timestamp.UnixNano())
+	return append(b, msg.Payload...)
+}
+
+// decodeEnvelope is the inverse of encodeEnvelope.
+func decodeEnvelope(data []byte) (*Message, error) {
+	if len(data) == 0 || data[0] != envelopeVersion {
+		return nil, errBadEnvelope
+	}
+	r := envelopeReader{buf: data[1:]}
+	m := &Message{ID: r.string(), Key: r.string()}
+	if n := r.uvarint(); n > 0 && r.err == nil {
+		if n > uint64(len(r.buf)) {
+			return nil, errBadEnvelope
+		}
+		m.Headers = make(map[string]string, n)
+		for range n {
+			k := r.string()
+			m.Headers[k] = r.string()
+		}
+	}
+	m.ContentType = r.string()
+	ts, w := binary.Varint(r.buf)
+	if r.err != nil || w <= 0 {
+		return nil, errBadEnvelope
+	}
+	m.Timestamp = time.Unix(0, ts)
+	m.Payload = r.buf[w:]
+	return m, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// envelopeReader consumes length-prefixed fields, remembering the
+// first error so callers can check once at the end.
+type envelopeReader struct {
+	buf []byte
+	err error
+}
+
+func (r *envelopeReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, w := binary.Uvarint(r.buf)
+	if w <= 0 {
+		r.err = errBadEnvelope
+		return 0
+	}
+	r.buf = r.buf[w:]
+	return v
+}
+
+func (r *envelopeReader) string() string {
+	n := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if n > uint64(len(r.buf)) {
+		r.err = errBadEnvelope
+		return ""
+	}
+	s := string(r.buf[:n])
+	r.buf = r.buf[n:]
+	return s
+}
@@ -0,0 +1,428 @@
+package pubsub
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter is a parsed content filter over a message's headers and
+// attributes. Subscriptions with a filter only receive matching
+// messages; the rest are skipped before they are queued.
+//
+// Grammar (keywords are case-insensitive):
+//
+//	expr    = and { "OR" and }
+//	and     = unary { "AND" unary }
+//	unary   = "NOT" unary | primary
+//	primary = "(" expr ")"
+//	        | "EXISTS" "(" name ")"
+//	        | name ( "=" | "!=" | "<" | "<=" | ">" | ">=" ) literal
+//	        | name [ "NOT" ] "IN" "(" literal { "," literal } ")"
+//	literal = 'string' | "string" | number | TRUE | FALSE
+//
+// A name is a header (e.g. region, trace-id) or one of the message
+// attributes $id, $key, $topic, $content_type, $partition, $offset and
+// $attempt. Ordering comparisons need a number literal and compare
+// numerically; a value that is missing or not a number never matches.
+//
+// Example: region IN ('eu', 'us') AND amount >= 100 AND NOT EXISTS(test)
+type Filter struct {
+	expr string
+	root filterNode
+}
+
+// filterNode is one node of the parsed expression tree.
+type filterNode interface {
+	eval(msg *Message) bool
+}
+
+// ParseFilter parses and validates a filter expression.
+func ParseFilter(expr string) (*Filter, error) {
+	toks, err := lexFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{expr: expr, toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return &Filter{expr: expr, root: root}, nil
+}
+
+// Match reports whether msg passes the filter.
+func (f *Filter) Match(msg *Message) bool { return f.root.eval(msg) }
+
+func (f *Filter) String() string { return f.expr }
+
+// --- evaluation ---
+
+// attributes resolves the $-prefixed names.
+var attributes = map[string]func(*Message) string{
+	"$id":           func(m *Message) string { return m.ID },
+	"$key":          func(m *Message) string { return m.Key },
+	"$topic":        func(m *Message) string { return m.Topic },
+	"$content_type": func(m *Message) string { return m.ContentType },
+	"$partition":    func(m *Message) string { return strconv.Itoa(m.Partition) },
+	"$offset":       func(m *Message) string { return strconv.FormatUint(m.Offset, 10) },
+	"$attempt":      func(m *Message) string { return strconv.Itoa(m.DeliveryAttempt) },
+}
+
+// lookup returns the value of a header or attribute, and whether it is set.
+func lookup(msg *Message, name string) (string, bool) {
+	if attr, ok := attributes[name]; ok {
+		v := attr(msg)
+		return v, v != ""
+	}
+	v, ok := msg.Headers[name]
+	return v, ok
+}
+
+// literal is a constant from the expression. Numbers compare
+// numerically, everything else as strings.
+type literal struct {
+	text    string
+	num     float64
+	numeric bool
+}
+
+// equals compares a message value with the literal.
+func (l literal) equals(v string) bool {
+	if l.numeric {
+		f, err := strconv.ParseFloat(v, 64)
+		return err == nil && f == l.num
+	}
+	return v == l.text
+}
+
+type andNode struct{ left, right filterNode }
+type orNode struct{ left, right filterNode }
+type notNode struct{ inner filterNode }
+type existsNode struct{ name string }
+
+type compareNode struct {
+	name string
+	op   string
+	lit  literal
+}
+
+type inNode struct {
+	name string
+	lits []literal
+}
+
+func (n andNode) eval(m *Message) bool    { return n.left.eval(m) && n.right.eval(m) }
+func (n orNode) eval(m *Message) bool     { return n.left.eval(m) || n.right.eval(m) }
+func (n notNode) eval(m *Message) bool    { return !n.inner.eval(m) }
+func (n existsNode) eval(m *Message) bool { _, ok := lookup(m, n.name); return ok }
+
+func (n compareNode) eval(m *Message) bool {
+	v, ok := lookup(m, n.name)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "=":
+		return n.lit.equals(v)
+	case "!=":
+		return !n.lit.equals(v)
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return f < n.lit.num
+	case "<=":
+		return f <= n.lit.num
+	case ">":
+		return f > n.lit.num
+	default: // ">="
+		return f >= n.lit.num
+	}
+}
+
+func (n inNode) eval(m *Message) bool {
+	v, ok := lookup(m, n.name)
+	if !ok {
+		return false
+	}
+	for _, l := range n.lits {
+		if l.equals(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// --- lexing ---
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokName
+	tokString
+	tokNumber
+	tokOp      // = != < <= > >=
+	tokPunct   // ( ) ,
+	tokKeyword // AND OR NOT IN EXISTS TRUE FALSE
+)
+
+type token struct {
+	kind tokKind
+	text string // keywords are upper-cased
+	pos  int
+}
+
+var filterKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IN": true, "EXISTS": true, "TRUE": true, "FALSE": true,
+}
+
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' || r == '$'
+}
+
+func lexFilter(expr string) ([]token, error) {
+	var toks []token
+	rs := []rune(expr)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == ',':
+			toks = append(toks, token{tokPunct, string(r), i})
+			i++
+		case r == '=':
+			toks = append(toks, token{tokOp, "=", i})
+			i++
+		case r == '!' || r == '<' || r == '>':
+			op := string(r)
+			if i+1 < len(rs) && rs[i+1] == '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("pubsub: filter: expected \"!=\" at %d", i)
+			}
+			toks = append(toks, token{tokOp, op, i})
+			i += len(op)
+		case r == '\'' || r == '"':
+			j := i + 1
+			for j < len(rs) && rs[j] != r {
+				j++
+			}
+			if j == len(rs) {
+				return nil, fmt.Errorf("pubsub: filter: unterminated string at %d", i)
+			}
+			toks = append(toks, token{tokString, string(rs[i+1 : j]), i})
+			i = j + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(rs) && unicode.IsDigit(rs[i+1])):
+			j := i + 1
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.' || rs[j] == 'e' || rs[j] == 'E') {
+				j++
+			}
+			toks = append(toks, token{tokNumber, string(rs[i:j]), i})
+			i = j
+		case isNameRune(r):
+			j := i + 1
+			for j < len(rs) && isNameRune(rs[j]) {
+				j++
+			}
+			word := string(rs[i:j])
+			if up := strings.ToUpper(word); filterKeywords[up] {
+				toks = append(toks, token{tokKeyword, up, i})
+			} else {
+				toks = append(toks, token{tokName, word, i})
+			}
+			i = j
+		default:
+			return nil, fmt.Errorf("pubsub: filter: unexpected %q at %d", r, i)
+		}
+	}
+	return append(toks, token{tokEOF, "end of expression", len(rs)}), nil
+}
+
+// --- parsing ---
+
+type filterParser struct {
+	expr string
+	toks []token
+	pos  int
+}
+
+func (p *filterParser) peek() token { return p.toks[p.pos] }
+
+func (p *filterParser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *filterParser) errorf(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("pubsub: filter: %s at %d", fmt.Sprintf(format, args...), t.pos)
+}
+
+// accept consumes the next token if it is the given keyword or punctuation.
+func (p *filterParser) accept(text string) bool {
+	if t := p.peek(); (t.kind == tokKeyword || t.kind == tokPunct) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) expect(text string) error {
+	if !p.accept(text) {
+		t := p.peek()
+		return p.errorf(t, "expected %q, got %q", text, t.text)
+	}
+	return nil
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("AND") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.accept("NOT") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	if p.accept("(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	}
+	if p.accept("EXISTS") {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		return existsNode{name}, p.expect(")")
+	}
+
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	negate := p.accept("NOT")
+	if p.accept("IN") {
+		n, err := p.parseIn(name)
+		if err != nil || !negate {
+			return n, err
+		}
+		return notNode{n}, nil
+	}
+	if negate {
+		t := p.peek()
+		return nil, p.errorf(t, "expected IN after NOT, got %q", t.text)
+	}
+
+	op := p.next()
+	if op.kind != tokOp {
+		return nil, p.errorf(op, "expected comparison after %q, got %q", name, op.text)
+	}
+	lit, err := p.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+	if op.text != "=" && op.text != "!=" && !lit.numeric {
+		return nil, p.errorf(op, "%q needs a number, got %q", op.text, lit.text)
+	}
+	return compareNode{name: name, op: op.text, lit: lit}, nil
+}
+
+func (p *filterParser) parseIn(name string) (filterNode, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	n := inNode{name: name}
+	for {
+		lit, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		n.lits = append(n.lits, lit)
+		if !p.accept(",") {
+			break
+		}
+	}
+	return n, p.expect(")")
+}
+
+func (p *filterParser) parseName() (string, error) {
+	t := p.next()
+	if t.kind != tokName {
+		return "", p.errorf(t, "expected a header or attribute name, got %q", t.text)
+	}
+	if strings.HasPrefix(t.text, "$") {
+		if _, ok := attributes[t.text]; !ok {
+			return "", p.errorf(t, "unknown attribute %q", t.text)
+		}
+	}
+	return t.text, nil
+}
+
+func (p *filterParser) parseLiteral() (literal, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokString:
+		return literal{text: t.text}, nil
+	case t.kind == tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return literal{}, p.errorf(t, "bad number %q", t.text)
+		}
+		return literal{text: t.text, num: f, numeric: true}, nil
+	case t.kind == tokKeyword && (t.text == "TRUE" || t.text == "FALSE"):
+		return literal{text: strings.ToLower(t.text)}, nil
+	}
+	return literal{}, p.errorf(t, "expected a literal, got %q", t.text)
+}
@@ -0,0 +1,341 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// commitInterval bounds how often acks are flushed to the offsets file.
+const commitInterval = time.Second
+
+// ConsumerGroup shares a PartitionedTopic's partitions between its
+// members: each partition is consumed by exactly one member at a time,
+// so each message is handled once per group (at least once across
+// rebalances and restarts). Acked positions are committed per partition
+// to groups/<name>.json in the topic directory, and a restarted group
+// resumes from them.
+type ConsumerGroup struct {
+	Name  string
+	topic *PartitionedTopic
+	path  string
+
+	mu      sync.Mutex // guards members and rebalancing
+	members []*GroupMember
+
+	offMu    sync.Mutex
+	cursors  []*cursor
+	lastSave time.Time
+}
+
+// cursor tracks the commit position of one partition. Acks may arrive
+// out of order (a nacked message is retried after later ones), so next
+// only advances over a contiguous run of finished offsets, or jumps to
+// the oldest offset retention kept when the records before it are gone.
+type cursor struct {
+	known bool   // next has been committed or resolved from StartAt
+	next  uint64 // first offset not yet finished
+	done  map[uint64]struct{}
+}
+
+// GroupMember is one Subscriber's membership in a ConsumerGroup.
+type GroupMember struct {
+	group      *ConsumerGroup
+	subscriber Subscriber
+	opts       SubscriptionOptions
+	filter     *Filter
+
+	partitions []int
+	subs       []*Subscription
+	handlers   []*groupHandler
+}
+
+// groupHandler forwards one partition's messages to a member and
+// records which offsets it has finished.
+type groupHandler struct {
+	member    *GroupMember
+	partition int
+}
+
+func (h *groupHandler) OnMessage(msg *Message) error {
+	// Filtered messages are finished without a delivery. The filter runs
+	// here rather than in the Subscription so they still advance the
+	// commit position.
+	if h.member.filter != nil && !h.member.filter.Match(msg) {
+		h.member.group.finish(h.partition, msg.Offset)
+		return nil
+	}
+	err := h.member.subscriber.OnMessage(msg)
+	// A message that failed its last attempt is dead-lettered, so it is
+	// finished as far as the commit position is concerned.
+	if err == nil || msg.DeliveryAttempt >= h.member.opts.Retry.MaxAttempts {
+		h.member.group.finish(h.partition, msg.Offset)
+	}
+	return err
+}
+
+// skipped finishes a message the subscription will never deliver, such
+// as one from an aborted transaction.
+func (h *groupHandler) skipped(msg *Message) {
+	h.member.group.finish(h.partition, msg.Offset)
+}
+
+// JoinGroup adds s to the named consumer group, creating the group (and
+// loading its committed offsets) on first use, and rebalances the
+// partitions across all members. opts.StartAt applies only to
+// partitions the group has never committed; opts.Filter applies to this
+// member only. Group subscriptions always use the Block overflow policy:
+// a dropped message would stall the commit position. Joining twice
+// returns the existing member.
+func (pt *PartitionedTopic) JoinGroup(group string, s Subscriber, opts SubscriptionOptions) (*GroupMember, error) {
+	var filter *Filter
+	if opts.Filter != "" {
+		var err error
+		if filter, err = ParseFilter(opts.Filter); err != nil {
+			return nil, err
+		}
+		opts.Filter = ""
+	}
+	pt.mu.Lock()
+	if pt.closed {
+		pt.mu.Unlock()
+		return nil, ErrTopicClosed
+	}
+	g, ok := pt.groups[group]
+	if !ok {
+		var err error
+		if g, err = pt.openGroup(group); err != nil {
+			pt.mu.Unlock()
+			return nil, err
+		}
+		pt.groups[group] = g
+	}
+	pt.mu.Unlock()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, m := range g.members {
+		if m.subscriber == s {
+			return m, nil
+		}
+	}
+	opts.Overflow = Block
+	opts.Retry = opts.Retry.withDefaults()
+	m := &GroupMember{group: g, subscriber: s, opts: opts, filter: filter}
+	g.members = append(g.members, m)
+	return m, g.rebalance()
+}
+
+// openGroup creates a group and loads its committed offsets.
+func (pt *PartitionedTopic) openGroup(name string) (*ConsumerGroup, error) {
+	g := &ConsumerGroup{
+		Name:    name,
+		topic:   pt,
+		path:    filepath.Join(pt.dir, "groups", name+".json"),
+		cursors: make([]*cursor, len(pt.partitions)),
+	}
+	for i := range g.cursors {
+		g.cursors[i] = &cursor{done: make(map[uint64]struct{})}
+	}
+	data, err := os.ReadFile(g.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return g, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var committed map[int]uint64
+	if err := json.Unmarshal(data, &committed); err != nil {
+		return nil, err
+	}
+	for p, off := range committed {
+		if p >= 0 && p < len(g.cursors) {
+			g.cursors[p].known, g.cursors[p].next = true, off
+		}
+	}
+	return g, nil
+}
+
+// Leave removes the member from its group and hands its partitions to
+// the remaining members. Messages already queued for it are delivered
+// first.
+func (m *GroupMember) Leave() error {
+	g := m.group
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, other := range g.members {
+		if other == m {
+			g.members = append(g.members[:i], g.members[i+1:]...)
+			m.stop()
+			return g.rebalance()
+		}
+	}
+	return nil
+}
+
+// Partitions returns the partitions currently assigned to the member.
+func (m *GroupMember) Partitions() []int {
+	m.group.mu.Lock()
+	defer m.group.mu.Unlock()
+	return append([]int(nil), m.partitions...)
+}
+
+// Committed returns the group's commit position for each partition:
+// the first offset not yet acknowledged.
+func (g *ConsumerGroup) Committed() map[int]uint64 {
+	g.offMu.Lock()
+	defer g.offMu.Unlock()
+	return g.committedLocked()
+}
+
+// Commit flushes the commit positions to disk now instead of waiting
+// for the next periodic flush or rebalance.
+func (g *ConsumerGroup) Commit() error {
+	g.offMu.Lock()
+	defer g.offMu.Unlock()
+	return g.saveLocked()
+}
+
+// Subscriptions returns the partition subscriptions of every member, in
+// join order.
+func (g *ConsumerGroup) Subscriptions() []*Subscription {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var out []*Subscription
+	for _, m := range g.members {
+		out = append(out, m.subs...)
+	}
+	return out
+}
+
+// Group returns the member's consumer group.
+func (m *GroupMember) Group() *ConsumerGroup { return m.group }
+
+// rebalance stops every member, commits, and assigns partitions
+// round-robin in join order (partition p goes to member p mod n), then
+// restarts each member at its partitions' commit positions.
+// Caller holds g.mu.
+func (g *ConsumerGroup) rebalance() error {
+	for _, m := range g.members {
+		m.stop()
+	}
+
+	g.offMu.Lock()
+	defer g.offMu.Unlock()
+	for _, c := range g.cursors {
+		clear(c.done) // redelivered from next by the new owner
+	}
+	if len(g.members) > 0 {
+		for p := range g.topic.partitions {
+			m := g.members[p%len(g.members)]
+			m.partitions = append(m.partitions, p)
+		}
+		for _, m := range g.members {
+			m.startLocked()
+		}
+	}
+	return g.saveLocked()
+}
+
+// startLocked subscribes the member to its assigned partitions.
+// Caller holds g.offMu.
+func (m *GroupMember) startLocked() {
+	g := m.group
+	for _, p := range m.partitions {
+		part := g.topic.partitions[p]
+		c := g.cursors[p]
+		if !c.known {
+			start, err := part.resolveStart(m.opts.StartAt)
+			if err != nil {
+				start = part.log.NextOffset()
+			}
+			c.known, c.next = true, start
+		}
+		opts := m.opts
+		opts.StartAt = StartAtOffset(c.next)
+		h := &groupHandler{member: m, partition: p}
+		m.handlers = append(m.handlers, h)
+		sub, _ := part.Subscribe(h, opts) // the filter was parsed in JoinGroup
+		m.subs = append(m.subs, sub)
+	}
+}
+
+// stop detaches the member from its partitions and waits until its
+// queued messages have been handled.
+func (m *GroupMember) stop() {
+	for i, h := range m.handlers {
+		m.group.topic.partitions[h.partition].RemoveSubscriber(h)
+		m.subs[i].Wait()
+	}
+	m.partitions, m.subs, m.handlers = nil, nil, nil
+}
+
+// finish marks offset as handled and advances the partition's commit
+// position, flushing it to disk at most once per commitInterval.
+func (g *ConsumerGroup) finish(partition int, offset uint64) {
+	g.offMu.Lock()
+	defer g.offMu.Unlock()
+	c := g.cursors[partition]
+	if oldest := g.topic.partitions[partition].log.OldestOffset(); c.next < oldest {
+		// retention deleted the records in between; they can never finish
+		c.next = oldest
+		for off := range c.done {
+			if off < oldest {
+				delete(c.done, off)
+			}
+		}
+	}
+	if offset < c.next {
+		return
+	}
+	c.done[offset] = struct{}{}
+	for {
+		if _, ok := c.done[c.next]; !ok {
+			break
+		}
+		delete(c.done, c.next)
+		c.next++
+	}
+	if time.Since(g.lastSave) >= commitInterval {
+		g.saveLocked()
+	}
+}
+
+// committedLocked snapshots the known commit positions.
+func (g *ConsumerGroup) committedLocked() map[int]uint64 {
+	out := make(map[int]uint64, len(g.cursors))
+	for p, c := range g.cursors {
+		if c.known {
+			out[p] = c.next
+		}
+	}
+	return out
+}
+
+// saveLocked atomically rewrites the offsets file. Caller holds g.offMu.
+func (g *ConsumerGroup) saveLocked() error {
+	data, err := json.Marshal(g.committedLocked())
+	if err != nil {
+		return err
+	}
+	tmp := g.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	g.lastSave = time.Now()
+	return os.Rename(tmp, g.path)
+}
+
+// close stops every member and commits their final positions.
+func (g *ConsumerGroup) close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, m := range g.members {
+		m.stop()
+	}
+	g.members = nil
+	return g.Commit()
+}
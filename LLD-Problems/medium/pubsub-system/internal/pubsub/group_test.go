@@ -0,0 +1,57 @@
+package pubsub
+
+import (
+	"testing"
+
+	"pubsub-system/internal/commitlog"
+)
+
+// gatedSubscriber holds every delivery until the gate is closed.
+type gatedSubscriber struct {
+	countingSubscriber
+	started chan struct{}
+	gate    chan struct{}
+}
+
+func (g *gatedSubscriber) OnMessage(msg *Message) error {
+	select {
+	case g.started <- struct{}{}:
+	default:
+	}
+	<-g.gate
+	return g.countingSubscriber.OnMessage(msg)
+}
+
+func TestPurgedGroupMessagesAreCommitted(t *testing.T) {
+	orders, err := NewPartitionedTopic("orders", t.TempDir(), 1, commitlog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer orders.Close()
+	worker := &gatedSubscriber{started: make(chan struct{}, 1), gate: make(chan struct{})}
+	member, err := orders.JoinGroup("billing", worker, SubscriptionOptions{StartAt: StartEarliest()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		orders.Publish(NewMessage("order"))
+	}
+	<-worker.started // the first message is in flight, the rest queued
+	subs := member.Group().Subscriptions()
+	if len(subs) != 1 {
+		t.Fatalf("group has %d subscriptions, want 1", len(subs))
+	}
+	waitFor(t, func() bool { return subs[0].Pending() == 4 })
+	if n := subs[0].Purge(); n != 4 {
+		t.Fatalf("purged %d messages, want 4", n)
+	}
+	close(worker.gate)
+
+	waitFor(t, func() bool { return member.Group().Committed()[0] == 5 })
+	if lag := member.Group().Lag()[0]; lag != 0 {
+		t.Fatalf("lag after purge = %d, want 0", lag)
+	}
+	if n := worker.count(); n != 1 {
+		t.Fatalf("delivered %d messages, want 1", n)
+	}
+}
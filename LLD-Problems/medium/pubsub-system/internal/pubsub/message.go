@@ -1,11 +1,117 @@
 package pubsub
 
-// Message represents the payload sent via topics.
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+)
+
+// ContentTypeText is the content type of messages built by NewMessage.
+const ContentTypeText = "text/plain"
+
+// Message is the envelope sent via topics. Subscribers must treat the
+// Payload and Headers they receive as read-only.
 type Message struct {
-	Content string
+	// ID identifies the message; Publish assigns a random one if empty.
+	ID string
+
+	// Key orders and routes the message: on a PartitionedTopic messages
+	// with the same key land on the same partition and keep their order.
+	Key string
+
+	// Headers carry application metadata (e.g. EventTypeHeader).
+	Headers map[string]string
+
+	// Payload is the body, encoded as described by ContentType.
+	Payload     []byte
+	ContentType string
+
+	// Timestamp is the publish time; Publish sets it if zero.
+	Timestamp time.Time
+
+	// Offset is the message's position in its topic, assigned by
+	// Topic.Publish. Offsets increase by one per message; for durable
+	// topics they are the log offsets and survive restarts.
+	Offset uint64
+
+	// Topic is the name of the topic the message was published to,
+	// useful to wildcard subscribers.
+	Topic string
+
+	// Partition is the partition the message was published to (always
+	// 0 on a plain Topic).
+	Partition int
+
+	// DeliveryAttempt is set on the copy handed to a Subscriber:
+	// 1 on first delivery, 2 on the first redelivery, and so on.
+	DeliveryAttempt int
+
+	// txn holds back delivery until the transaction that published the
+	// message commits; nil outside transactions.
+	txn *txnLatch
 }
 
-// NewMessage is a simple factory for Message.
+// NewMessage builds a plain-text Message (Factory Pattern).
 func NewMessage(content string) *Message {
-	return &Message{Content: content}
+	return &Message{Payload: []byte(content), ContentType: ContentTypeText}
+}
+
+// NewKeyedMessage builds a plain-text Message routed by key.
+func NewKeyedMessage(key, content string) *Message {
+	m := NewMessage(content)
+	m.Key = key
+	return m
+}
+
+// Text returns the payload as a string.
+func (m *Message) Text() string { return string(m.Payload) }
+
+// Header returns the named header, or "" if unset.
+func (m *Message) Header(name string) string { return m.Headers[name] }
+
+// SetHeader sets a header, allocating the map on first use.
+func (m *Message) SetHeader(name, value string) {
+	if m.Headers == nil {
+		m.Headers = make(map[string]string)
+	}
+	m.Headers[name] = value
+}
+
+// String renders text payloads as-is and summarizes binary ones.
+func (m *Message) String() string {
+	if m.ContentType == ContentTypeText || strings.HasSuffix(m.ContentType, "json") {
+		return m.Text()
+	}
+	return fmt.Sprintf("<%d bytes of %s>", len(m.Payload), m.ContentType)
+}
+
+// clone copies the message with its own Headers map, so one
+// subscriber's changes cannot leak to another.
+func (m *Message) clone() *Message {
+	c := *m
+	c.Headers = maps.Clone(m.Headers)
+	return &c
+}
+
+// stamp fills in the ID and Timestamp of a message being published.
+func (m *Message) stamp() {
+	if m.ID == "" {
+		m.ID = newMessageID()
+	}
+	if m.Timestamp.IsZero() {
+		m.Timestamp = time.Now()
+	}
+}
+
+// newMessageID returns a random RFC 4122 version 4 UUID.
+func newMessageID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
 }
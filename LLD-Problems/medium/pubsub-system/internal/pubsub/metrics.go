@@ -0,0 +1,223 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// rateWindow is the period rates are averaged over.
+const rateWindow = time.Minute
+
+// rateMeter counts events in one-second buckets over the last
+// rateWindow, giving a moving events-per-second rate.
+type rateMeter struct {
+	mu      sync.Mutex
+	buckets [int(rateWindow / time.Second)]uint64
+	last    int64 // Unix second of the newest bucket
+	total   uint64
+}
+
+func (r *rateMeter) mark(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.advance(now.Unix())
+	r.buckets[r.last%int64(len(r.buckets))]++
+	r.total++
+}
+
+// rate returns events per second over the last rateWindow.
+func (r *rateMeter) rate(now time.Time) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.advance(now.Unix())
+	var sum uint64
+	for _, n := range r.buckets {
+		sum += n
+	}
+	return float64(sum) / rateWindow.Seconds()
+}
+
+func (r *rateMeter) count() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.total
+}
+
+// advance zeroes the buckets of the seconds skipped since the last
+// event. Caller holds r.mu.
+func (r *rateMeter) advance(sec int64) {
+	for s := r.last + 1; s <= sec && s <= r.last+int64(len(r.buckets)); s++ {
+		r.buckets[s%int64(len(r.buckets))] = 0
+	}
+	if sec > r.last {
+		r.last = sec
+	}
+}
+
+// LatencyBuckets are the upper bounds of the processing latency
+// histogram; a last, implicit bucket catches everything slower.
+var LatencyBuckets = []time.Duration{
+	100 * time.Microsecond, 250 * time.Microsecond, 500 * time.Microsecond,
+	time.Millisecond, 2500 * time.Microsecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+// Histogram is a snapshot of a latency histogram over LatencyBuckets.
+type Histogram struct {
+	Counts []uint64      `json:"counts"` // per bucket; len(LatencyBuckets)+1
+	Count  uint64        `json:"count"`
+	Sum    time.Duration `json:"sum_ns"`
+}
+
+// Quantile estimates the q-quantile (0 < q ≤ 1) as the upper bound of
+// the bucket it falls in; the overflow bucket reports the last bound.
+func (h Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(h.Count) + 0.5)
+	var seen uint64
+	for i, n := range h.Counts {
+		seen += n
+		if seen >= rank && i < len(LatencyBuckets) {
+			return LatencyBuckets[i]
+		}
+	}
+	return LatencyBuckets[len(LatencyBuckets)-1]
+}
+
+// Mean returns the average observation.
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// observe adds one observation. Caller synchronizes.
+func (h *Histogram) observe(d time.Duration) {
+	if h.Counts == nil {
+		h.Counts = make([]uint64, len(LatencyBuckets)+1)
+	}
+	i := 0
+	for i < len(LatencyBuckets) && d > LatencyBuckets[i] {
+		i++
+	}
+	h.Counts[i]++
+	h.Count++
+	h.Sum += d
+}
+
+func (h Histogram) clone() Histogram {
+	if h.Counts == nil {
+		h.Counts = make([]uint64, len(LatencyBuckets)+1)
+	} else {
+		h.Counts = append([]uint64(nil), h.Counts...)
+	}
+	return h
+}
+
+// TopicStats is a point-in-time view of a Topic.
+type TopicStats struct {
+	Name          string  `json:"name"`
+	Durable       bool    `json:"durable"`
+	Published     uint64  `json:"published"`
+	PublishRate   float64 `json:"publish_rate"` // per second, last minute
+	Duplicates    uint64  `json:"duplicates"`
+	Subscriptions int     `json:"subscriptions"`
+	NextOffset    uint64  `json:"next_offset"`
+}
+
+// Stats snapshots the topic's counters.
+func (t *Topic) Stats() TopicStats {
+	now := time.Now()
+	st := TopicStats{
+		Name:        t.Name,
+		Durable:     t.Durable(),
+		Published:   t.published.count(),
+		PublishRate: t.published.rate(now),
+		Duplicates:  t.Duplicates(),
+	}
+	t.mu.RLock()
+	st.Subscriptions = len(t.subscriptions)
+	t.mu.RUnlock()
+	if t.log != nil {
+		st.NextOffset = t.log.NextOffset()
+	} else {
+		t.pubMu.Lock()
+		st.NextOffset = t.nextOffset
+		t.pubMu.Unlock()
+	}
+	return st
+}
+
+// SubscriptionStats is a point-in-time view of a Subscription.
+type SubscriptionStats struct {
+	// Queued counts messages waiting in the queue or for a redelivery.
+	Queued   int `json:"queued"`
+	InFlight int `json:"in_flight"`
+	// Lag counts messages published but not yet acked or given up on:
+	// Queued, InFlight, and records a durable topic has not yet fed in.
+	Lag uint64 `json:"lag"`
+
+	Delivered    uint64    `json:"delivered"`     // acked
+	DeliveryRate float64   `json:"delivery_rate"` // acks per second, last minute
+	Redelivered  uint64    `json:"redelivered"`
+	DeadLettered uint64    `json:"dead_lettered"`
+	Dropped      uint64    `json:"dropped"`
+	Filtered     uint64    `json:"filtered"`
+	Purged       uint64    `json:"purged"`
+	Latency      Histogram `json:"latency"` // OnMessage processing time
+	Closed       bool      `json:"closed"`
+	Err          string    `json:"error,omitempty"`
+}
+
+// Stats snapshots the subscription's counters.
+func (s *Subscription) Stats() SubscriptionStats {
+	rate := s.delivered.rate(time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := SubscriptionStats{
+		Queued:       s.count + len(s.retries) + s.scheduled,
+		InFlight:     s.inFlight,
+		Delivered:    s.acked,
+		DeliveryRate: rate,
+		Redelivered:  s.redelivered,
+		DeadLettered: s.deadLettered,
+		Dropped:      s.dropped,
+		Filtered:     s.filtered,
+		Purged:       s.purged,
+		Latency:      s.latency.clone(),
+		Closed:       s.closed,
+	}
+	st.Lag = uint64(st.Queued + st.InFlight)
+	for _, n := range s.unread {
+		st.Lag += n
+	}
+	if s.err != nil {
+		st.Err = s.err.Error()
+	}
+	return st
+}
+
+// Lag returns, per partition, how many messages the group has not
+// committed yet.
+func (g *ConsumerGroup) Lag() map[int]uint64 {
+	committed := g.Committed()
+	lag := make(map[int]uint64, g.topic.Partitions())
+	for p := range g.topic.Partitions() {
+		end := g.topic.Partition(p).log.NextOffset()
+		next, ok := committed[p]
+		if !ok {
+			next = g.topic.Partition(p).log.OldestOffset()
+		}
+		if end > next {
+			lag[p] = end - next
+		} else {
+			lag[p] = 0
+		}
+	}
+	return lag
+}
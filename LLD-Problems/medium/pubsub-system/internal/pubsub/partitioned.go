@@ -0,0 +1,142 @@
+package pubsub
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"pubsub-system/internal/commitlog"
+)
+
+// Partitioner picks the partition a message is published to
+// (Strategy Pattern).
+type Partitioner interface {
+	Partition(msg *Message, partitions int) int
+}
+
+// HashPartitioner sends keyed messages to hash(Key) mod partitions, so
+// one key always lands on one partition, and spreads unkeyed messages
+// round-robin.
+type HashPartitioner struct {
+	next atomic.Uint64
+}
+
+func (h *HashPartitioner) Partition(msg *Message, partitions int) int {
+	if msg.Key == "" {
+		return int((h.next.Add(1) - 1) % uint64(partitions))
+	}
+	f := fnv.New32a()
+	f.Write([]byte(msg.Key))
+	return int(f.Sum32() % uint32(partitions))
+}
+
+// PartitionedTopic splits a topic into independently ordered durable
+// partitions, each stored in its own log under dir. Messages are only
+// ordered within a partition. Consumer groups share the partitions
+// between their members (see JoinGroup).
+type PartitionedTopic struct {
+	Name        string
+	dir         string
+	partitions  []*Topic
+	partitioner Partitioner
+
+	mu     sync.Mutex
+	groups map[string]*ConsumerGroup
+	closed bool
+}
+
+// ErrTopicClosed is returned when using a closed PartitionedTopic.
+var ErrTopicClosed = errors.New("pubsub: topic closed")
+
+// NewPartitionedTopic opens (or creates) a topic with n partitions in
+// dir. Reopening must use the same partition count, or keys would move
+// between partitions. (Factory Pattern)
+func NewPartitionedTopic(name, dir string, n int, opts commitlog.Options) (*PartitionedTopic, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("pubsub: partition count must be positive, got %d", n)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "groups"), 0o755); err != nil {
+		return nil, err
+	}
+	pt := &PartitionedTopic{
+		Name:        name,
+		dir:         dir,
+		partitioner: &HashPartitioner{},
+		groups:      make(map[string]*ConsumerGroup),
+	}
+	for i := range n {
+		p, err := NewDurableTopic(fmt.Sprintf("%s-%d", name, i), filepath.Join(dir, fmt.Sprintf("partition-%04d", i)), opts)
+		if err != nil {
+			for _, open := range pt.partitions {
+				open.Close()
+			}
+			return nil, err
+		}
+		p.partition = i
+		pt.partitions = append(pt.partitions, p)
+	}
+	return pt, nil
+}
+
+// SetPartitioner replaces the default HashPartitioner.
+func (pt *PartitionedTopic) SetPartitioner(p Partitioner) { pt.partitioner = p }
+
+// Partitions returns the number of partitions.
+func (pt *PartitionedTopic) Partitions() int { return len(pt.partitions) }
+
+// Partition returns partition i as a Topic, e.g. to Subscribe to it
+// directly outside of any consumer group.
+func (pt *PartitionedTopic) Partition(i int) *Topic { return pt.partitions[i] }
+
+// Groups returns the consumer groups joined so far, sorted by name.
+func (pt *PartitionedTopic) Groups() []*ConsumerGroup {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	out := make([]*ConsumerGroup, 0, len(pt.groups))
+	for _, g := range pt.groups {
+		out = append(out, g)
+	}
+	slices.SortFunc(out, func(x, y *ConsumerGroup) int { return cmp.Compare(x.Name, y.Name) })
+	return out
+}
+
+// Publish appends msg to the partition chosen by the Partitioner and
+// sets msg.Partition and msg.Offset.
+func (pt *PartitionedTopic) Publish(msg *Message) error {
+	i := pt.partitioner.Partition(msg, len(pt.partitions))
+	if i < 0 || i >= len(pt.partitions) {
+		return fmt.Errorf("pubsub: partitioner chose partition %d of %d", i, len(pt.partitions))
+	}
+	return pt.partitions[i].Publish(msg)
+}
+
+// Close lets every consumer group catch up with the partitions, stops
+// the groups, saving their offsets, and closes the partitions.
+func (pt *PartitionedTopic) Close() error {
+	pt.mu.Lock()
+	if pt.closed {
+		pt.mu.Unlock()
+		return nil
+	}
+	pt.closed = true
+	groups := pt.groups
+	pt.mu.Unlock()
+
+	for _, p := range pt.partitions {
+		p.drainFeeders()
+	}
+	var errs []error
+	for _, g := range groups {
+		errs = append(errs, g.close())
+	}
+	for _, p := range pt.partitions {
+		errs = append(errs, p.Close())
+	}
+	return errors.Join(errs...)
+}
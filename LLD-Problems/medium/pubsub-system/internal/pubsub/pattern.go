@@ -0,0 +1,149 @@
+package pubsub
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// WildcardSyntax selects the wildcard tokens a Broker understands.
+type WildcardSyntax int
+
+const (
+	// MQTTWildcards: "+" matches one level, "#" (last) matches zero or
+	// more levels, so "orders.#" also matches "orders".
+	MQTTWildcards WildcardSyntax = iota
+	// NATSWildcards: "*" matches one level, ">" (last) matches one or
+	// more levels, so "orders.>" does not match "orders".
+	NATSWildcards
+)
+
+func (w WildcardSyntax) String() string {
+	switch w {
+	case MQTTWildcards:
+		return "MQTT"
+	case NATSWildcards:
+		return "NATS"
+	default:
+		return "Unknown"
+	}
+}
+
+// tokens returns the single- and multi-level wildcard of the syntax.
+func (w WildcardSyntax) tokens() (single, multi string) {
+	if w == NATSWildcards {
+		return "*", ">"
+	}
+	return "+", "#"
+}
+
+// pattern is a parsed subscription pattern.
+type pattern struct {
+	levels   []string // literal levels, or "" for a single-level wildcard
+	multi    bool     // ends in a multi-level wildcard
+	minExtra int      // levels the multi-level wildcard must match
+}
+
+// validateTopicName checks name has no empty levels and no wildcards.
+func validateTopicName(name, sep string, syntax WildcardSyntax) error {
+	single, multi := syntax.tokens()
+	for _, level := range strings.Split(name, sep) {
+		switch {
+		case level == "":
+			return fmt.Errorf("pubsub: topic %q has an empty level", name)
+		case strings.Contains(level, single) || strings.Contains(level, multi):
+			return fmt.Errorf("pubsub: topic %q contains a wildcard", name)
+		}
+	}
+	return nil
+}
+
+// parsePattern validates and parses a subscription pattern. Wildcards
+// must fill a whole level, and the multi-level one must come last.
+func parsePattern(s, sep string, syntax WildcardSyntax) (pattern, error) {
+	single, multi := syntax.tokens()
+	parts := strings.Split(s, sep)
+	var p pattern
+	for i, level := range parts {
+		switch {
+		case level == multi:
+			if i != len(parts)-1 {
+				return pattern{}, fmt.Errorf("pubsub: %q must be the last level of %q", multi, s)
+			}
+			p.multi = true
+			if syntax == NATSWildcards {
+				p.minExtra = 1
+			}
+		case level == single:
+			p.levels = append(p.levels, "")
+		case level == "":
+			return pattern{}, fmt.Errorf("pubsub: pattern %q has an empty level", s)
+		case strings.Contains(level, single) || strings.Contains(level, multi):
+			return pattern{}, fmt.Errorf("pubsub: wildcard must fill a whole level in %q", s)
+		default:
+			p.levels = append(p.levels, level)
+		}
+	}
+	return p, nil
+}
+
+// matches reports whether the topic, split into levels, matches p.
+// Like MQTT's "$" topics, reply inboxes are only matched by patterns
+// whose first level is InboxPrefix, so "#" does not see every reply.
+func (p pattern) matches(topic []string) bool {
+	if topic[0] == InboxPrefix && (len(p.levels) == 0 || p.levels[0] == "") {
+		return false
+	}
+	if len(topic) < len(p.levels)+p.minExtra {
+		return false
+	}
+	if !p.multi && len(topic) != len(p.levels) {
+		return false
+	}
+	for i, level := range p.levels {
+		if level != "" && level != topic[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// maxLevels returns the most levels a matching topic can have.
+func (p pattern) maxLevels() int {
+	if p.multi {
+		return math.MaxInt
+	}
+	return len(p.levels)
+}
+
+// covers reports whether every topic matched by q is also matched by p.
+// Topic names are patterns without wildcards, so this also answers
+// whether p matches a single topic.
+func (p pattern) covers(q pattern) bool {
+	for i, ql := range q.levels {
+		if i == len(p.levels) {
+			return p.multi // absorbs q's remaining (one or more) levels
+		}
+		if pl := p.levels[i]; pl != "" && pl != ql {
+			return false // a literal cannot cover a different literal or a wildcard
+		}
+	}
+	if len(p.levels) > len(q.levels) {
+		return false
+	}
+	if !q.multi {
+		return !p.multi || p.minExtra == 0
+	}
+	return p.multi && p.minExtra <= q.minExtra
+}
+
+// overlaps reports whether some topic is matched by both p and q.
+func (p pattern) overlaps(q pattern) bool {
+	for i := range min(len(p.levels), len(q.levels)) {
+		if a, b := p.levels[i], q.levels[i]; a != "" && b != "" && a != b {
+			return false
+		}
+	}
+	lo := max(len(p.levels)+p.minExtra, len(q.levels)+q.minExtra)
+	return lo <= min(p.maxLevels(), q.maxLevels())
+}
@@ -12,7 +12,9 @@ func NewPrintSubscriber(name string) *PrintSubscriber {
 	return &PrintSubscriber{Name: name}
 }
 
-// OnMessage is called by Topic.Publish (Observer callback).
-func (ps *PrintSubscriber) OnMessage(msg *Message) {
-	fmt.Printf("[%s] received: %s\n", ps.Name, msg.Content)
+// OnMessage is called by the subscription's delivery goroutine
+// (Observer callback). Printing never fails, so it always acks.
+func (ps *PrintSubscriber) OnMessage(msg *Message) error {
+	fmt.Printf("[%s] received: %s\n", ps.Name, msg)
+	return nil
 }
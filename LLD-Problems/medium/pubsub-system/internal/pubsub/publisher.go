@@ -1,15 +1,50 @@
 package pubsub
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
 
-// Publisher knows which Topics it may publish to.
+// ErrTopicNotRegistered is returned when a Publisher publishes to a
+// topic it has not registered.
+var ErrTopicNotRegistered = fmt.Errorf("%w: topic not registered with publisher", ErrPermissionDenied)
+
+// Publisher knows which Topics it may publish to, and optionally acts
+// as a Principal checked by an Authorizer.
+//
+// An idempotent Publisher (ProducerID set) numbers its messages per
+// topic, so a topic with a dedup window (Topic.SetDedupWindow) drops a
+// retried publish even after its message ID has left the window.
 type Publisher struct {
-	topics map[*Topic]struct{}
+	Principal  Principal
+	ProducerID string
+	authz      Authorizer
+	topics     map[*Topic]struct{}
+
+	mu   sync.Mutex        // serializes idempotent publishes
+	seqs map[*Topic]uint64 // last sequence number published per topic
 }
 
 // NewPublisher constructs a Publisher (Factory Pattern).
 func NewPublisher() *Publisher {
-	return &Publisher{topics: make(map[*Topic]struct{})}
+	return &Publisher{topics: make(map[*Topic]struct{}), seqs: make(map[*Topic]uint64)}
+}
+
+// NewAuthorizedPublisher constructs a Publisher whose publishes are
+// also checked against authz as principal.
+func NewAuthorizedPublisher(principal Principal, authz Authorizer) *Publisher {
+	p := NewPublisher()
+	p.Principal, p.authz = principal, authz
+	return p
+}
+
+// NewIdempotentPublisher constructs a Publisher with a fresh random
+// ProducerID.
+func NewIdempotentPublisher() *Publisher {
+	p := NewPublisher()
+	p.ProducerID = newMessageID()
+	return p
 }
 
 // RegisterTopic grants this publisher the right to publish to topic.
@@ -17,11 +52,49 @@ func (p *Publisher) RegisterTopic(t *Topic) {
 	p.topics[t] = struct{}{}
 }
 
-// Publish sends a message to a Topic if registered.
-func (p *Publisher) Publish(t *Topic, msg *Message) {
+// Publish sends a message to a Topic if registered (and authorized).
+// An idempotent Publisher stamps it with ProducerIDHeader and the next
+// ProducerSeqHeader; publishing the same *Message to the same topic
+// again keeps its number (and its ID), so the topic can recognise the
+// retry.
+func (p *Publisher) Publish(t *Topic, msg *Message) error {
+	if err := p.check(t); err != nil {
+		return err
+	}
+	if p.ProducerID == "" {
+		return t.Publish(msg)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	seq := p.sequence(t, msg, p.seqs[t])
+	if err := t.Publish(msg); err != nil {
+		return err
+	}
+	p.seqs[t] = max(p.seqs[t], seq)
+	return nil
+}
+
+// check verifies that p may publish to t.
+func (p *Publisher) check(t *Topic) error {
 	if _, ok := p.topics[t]; !ok {
-		fmt.Printf("Publisher not registered for topic %s\n", t.Name)
-		return
+		return fmt.Errorf("%w: %s", ErrTopicNotRegistered, t.Name)
+	}
+	if p.authz != nil {
+		return p.authz.Authorize(p.Principal, ActionPublish, t.Name)
+	}
+	return nil
+}
+
+// sequence numbers msg for t with the number after last, unless it
+// already carries one of ours from an earlier attempt on t (a retry).
+// It returns the number used. Caller holds p.mu.
+func (p *Publisher) sequence(t *Topic, msg *Message, last uint64) uint64 {
+	retry := msg.Topic == "" || msg.Topic == t.Name // not yet published elsewhere
+	if pid, seq, ok := producerSeq(msg); ok && pid == p.ProducerID && retry {
+		return seq
 	}
-	t.Publish(msg)
+	seq := last + 1
+	msg.SetHeader(ProducerIDHeader, p.ProducerID)
+	msg.SetHeader(ProducerSeqHeader, strconv.FormatUint(seq, 10))
+	return seq
 }
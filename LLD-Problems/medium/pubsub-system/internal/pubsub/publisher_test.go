@@ -0,0 +1,42 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdempotentPublisherFanOut(t *testing.T) {
+	a, b := NewTopic("a"), NewTopic("b")
+	subA, subB := &countingSubscriber{}, &countingSubscriber{}
+	for topic, sub := range map[*Topic]*countingSubscriber{a: subA, b: subB} {
+		if err := topic.SetDedupWindow(time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		topic.AddSubscriber(sub)
+	}
+	p := NewIdempotentPublisher()
+	p.RegisterTopic(a)
+	p.RegisterTopic(b)
+
+	first, second := &Message{Payload: []byte("1")}, &Message{Payload: []byte("2")}
+	for _, step := range []struct {
+		topic *Topic
+		msg   *Message
+	}{{a, first}, {a, second}, {b, second}, {b, first}, {b, first}} { // the last is a retry
+		if err := p.Publish(step.topic, step.msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.Close()
+	b.Close()
+
+	if n := subA.count(); n != 2 {
+		t.Errorf("topic a delivered %d messages, want 2", n)
+	}
+	if n := subB.count(); n != 2 {
+		t.Errorf("topic b delivered %d messages, want 2", n)
+	}
+	if n := b.Duplicates(); n != 1 {
+		t.Errorf("topic b dropped %d duplicates, want 1", n)
+	}
+}
@@ -0,0 +1,93 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Request/reply headers.
+const (
+	// ReplyToHeader names the topic a request's reply goes to.
+	ReplyToHeader = "reply-to"
+	// CorrelationIDHeader on a reply carries the request's ID.
+	CorrelationIDHeader = "correlation-id"
+)
+
+// InboxPrefix is the first level of the reply inbox topics created by
+// Request. Responders need ActionPublish on InboxPrefix + ".#" (in the
+// broker's separator) when an Authorizer is set.
+const InboxPrefix = "_INBOX"
+
+// ErrNoReplyTo is returned by Reply for a message that is not a request.
+var ErrNoReplyTo = errors.New("pubsub: message has no reply-to header")
+
+// Request is RequestAs(ctx, Anonymous, topic, msg).
+func (b *Broker) Request(ctx context.Context, topic string, msg *Message) (*Message, error) {
+	return b.RequestAs(ctx, Anonymous, topic, msg)
+}
+
+// RequestAs publishes msg to topic on behalf of p and waits for the
+// first reply, or until ctx is done. It creates an ephemeral inbox
+// topic, named in msg's ReplyToHeader, and deletes it before returning,
+// so later replies fail with ErrNoSuchTopic.
+func (b *Broker) RequestAs(ctx context.Context, p Principal, topic string, msg *Message) (*Message, error) {
+	inbox := InboxPrefix + b.opts.Separator + newMessageID()
+	t, err := b.createTopic(inbox)
+	if err != nil {
+		return nil, err
+	}
+	defer b.deleteTopic(inbox)
+
+	replies := &inboxSubscriber{replies: make(chan *Message, 1)}
+	if _, err := t.Subscribe(replies, SubscriptionOptions{}); err != nil {
+		return nil, err
+	}
+	msg.SetHeader(ReplyToHeader, inbox)
+	if err := b.PublishAs(p, topic, msg); err != nil {
+		return nil, err
+	}
+	select {
+	case reply := <-replies.replies:
+		return reply, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Reply is ReplyAs(Anonymous, req, resp).
+func (b *Broker) Reply(req, resp *Message) error {
+	return b.ReplyAs(Anonymous, req, resp)
+}
+
+// ReplyAs publishes resp to req's reply inbox on behalf of p, setting
+// its CorrelationIDHeader to req.ID. The inbox is never auto-created:
+// if the requester has given up, ReplyAs returns ErrNoSuchTopic.
+func (b *Broker) ReplyAs(p Principal, req, resp *Message) error {
+	inbox := req.Header(ReplyToHeader)
+	if inbox == "" {
+		return ErrNoReplyTo
+	}
+	if err := b.authorize(p, ActionPublish, inbox); err != nil {
+		return err
+	}
+	t, ok := b.Topic(inbox)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoSuchTopic, inbox)
+	}
+	resp.SetHeader(CorrelationIDHeader, req.ID)
+	return t.Publish(resp)
+}
+
+// inboxSubscriber keeps the first reply and discards the rest.
+type inboxSubscriber struct {
+	replies chan *Message
+}
+
+func (s *inboxSubscriber) OnMessage(msg *Message) error {
+	select {
+	case s.replies <- msg:
+	default:
+	}
+	return nil
+}
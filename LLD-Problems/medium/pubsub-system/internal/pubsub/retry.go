@@ -0,0 +1,57 @@
+package pubsub
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrAckDeadlineExceeded is the nack reason recorded when a Subscriber
+// does not return within its subscription's AckDeadline.
+var ErrAckDeadlineExceeded = errors.New("pubsub: ack deadline exceeded")
+
+// RetryPolicy controls redelivery of negatively acknowledged messages.
+type RetryPolicy struct {
+	MaxAttempts    int           // total deliveries before dead-lettering; defaults to 5
+	InitialBackoff time.Duration // delay before the first redelivery; defaults to 100ms
+	MaxBackoff     time.Duration // cap on the delay; defaults to 10s
+	Multiplier     float64       // growth per attempt; defaults to 2
+}
+
+// DefaultRetryPolicy is used when SubscriptionOptions.Retry is zero.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+	}
+}
+
+// withDefaults fills unset fields from DefaultRetryPolicy.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	d := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = d.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = d.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = d.MaxBackoff
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = d.Multiplier
+	}
+	return p
+}
+
+// Backoff returns the delay before redelivery after `attempt` failed
+// deliveries (attempt >= 1): InitialBackoff * Multiplier^(attempt-1),
+// capped at MaxBackoff.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 1; i < attempt && d < float64(p.MaxBackoff); i++ {
+		d *= p.Multiplier
+	}
+	return min(time.Duration(d), p.MaxBackoff)
+}
@@ -0,0 +1,366 @@
+package pubsub
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timer wheel geometry: 10ms resolution, one revolution every ~5s.
+const (
+	wheelTick  = 10 * time.Millisecond
+	wheelSlots = 512
+)
+
+// scheduleVersion is the first byte of every stored scheduled message.
+const scheduleVersion = 1
+
+// PublishAt is PublishAtAs(Anonymous, topic, msg, at).
+func (b *Broker) PublishAt(topic string, msg *Message, at time.Time) error {
+	return b.PublishAtAs(Anonymous, topic, msg, at)
+}
+
+// PublishAfter is PublishAt(topic, msg, time.Now().Add(d)).
+func (b *Broker) PublishAfter(topic string, msg *Message, d time.Duration) error {
+	return b.PublishAtAs(Anonymous, topic, msg, time.Now().Add(d))
+}
+
+// PublishAtAs schedules msg to be published to topic at the given time
+// (within one 10ms tick), on behalf of p. Permission and the topic's
+// existence are checked now; msg.ID is set now and can be passed to
+// CancelScheduled. The message is timestamped when it is published.
+//
+// With ScheduleDir set, the message is stored before PublishAtAs
+// returns and is published at least once even across restarts (see
+// StartScheduler).
+func (b *Broker) PublishAtAs(p Principal, topic string, msg *Message, at time.Time) error {
+	if err := validateTopicName(topic, b.opts.Separator, b.opts.Wildcards); err != nil {
+		return err
+	}
+	if err := b.authorize(p, ActionPublish, topic); err != nil {
+		return err
+	}
+	if _, ok := b.Topic(topic); !ok && !b.opts.AutoCreate {
+		return fmt.Errorf("%w: %s", ErrNoSuchTopic, topic)
+	}
+	if msg.ID == "" {
+		msg.ID = newMessageID()
+	}
+	return b.sched.add(&scheduled{topic: topic, at: at, msg: msg.clone()})
+}
+
+// StartScheduler begins publishing when ScheduleDir is set; until then
+// scheduled messages, including those reloaded by OpenBroker, wait, so
+// the topics and subscriptions they are meant for can be set up first.
+// Messages that fell due while the broker was down are published
+// straight away, oldest first. Without ScheduleDir the scheduler is
+// always running and this does nothing.
+func (b *Broker) StartScheduler() { b.sched.start() }
+
+// CancelScheduled removes a scheduled message that has not been
+// published yet, reporting whether it was pending.
+func (b *Broker) CancelScheduled(id string) bool { return b.sched.cancel(id) }
+
+// Scheduled returns the number of messages waiting to be published.
+func (b *Broker) Scheduled() int { return b.sched.pending() }
+
+// scheduled is one message waiting in the wheel.
+type scheduled struct {
+	topic  string
+	at     time.Time
+	msg    *Message
+	rounds int // revolutions left before it is due
+}
+
+// scheduler is a hashed timing wheel: a ring of slots, one per tick,
+// that a hand advances through. An entry goes into the slot the hand
+// will reach when it is due, with the number of whole revolutions to
+// wait first, so adding and cancelling cost O(1) however many messages
+// are pending. The hand only turns while something is scheduled.
+type scheduler struct {
+	broker *Broker
+	dir    string // "" keeps entries in memory only
+
+	mu      sync.Mutex
+	slots   [wheelSlots][]*scheduled
+	index   map[string]int // message ID → slot
+	pos     int            // slot the hand is on
+	next    time.Time      // when the hand moves to pos+1
+	paused  bool           // durable: waiting for StartScheduler
+	running bool
+	closed  bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newScheduler creates the wheel and reloads dir, if set.
+func newScheduler(b *Broker, dir string) (*scheduler, error) {
+	s := &scheduler{broker: b, dir: dir, index: make(map[string]int), done: make(chan struct{})}
+	if dir == "" {
+		return s, nil
+	}
+	s.paused = true
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := loadScheduled(dir)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range entries {
+		s.insertLocked(e)
+	}
+	return s, nil
+}
+
+// add stores e (if durable) and puts it on the wheel.
+func (s *scheduler) add(e *scheduled) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrBrokerClosed
+	}
+	if _, ok := s.index[e.msg.ID]; ok {
+		return fmt.Errorf("pubsub: message %q is already scheduled", e.msg.ID)
+	}
+	if s.dir != "" {
+		if err := saveScheduled(s.dir, e); err != nil {
+			return err
+		}
+	}
+	s.insertLocked(e)
+	s.startLocked()
+	return nil
+}
+
+// start unpauses the wheel. Entries were slotted while the hand was
+// still, so they are slotted again from now.
+func (s *scheduler) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.paused {
+		return
+	}
+	s.paused = false
+	var all []*scheduled
+	for i := range s.slots {
+		all = append(all, s.slots[i]...)
+		s.slots[i] = nil
+	}
+	for _, e := range all {
+		s.insertLocked(e)
+	}
+	s.startLocked()
+}
+
+// startLocked starts the hand if it is idle and has work. Caller holds
+// s.mu.
+func (s *scheduler) startLocked() {
+	if s.running || s.paused || s.closed || len(s.index) == 0 {
+		return
+	}
+	s.running = true
+	s.wg.Add(1)
+	go s.run()
+}
+
+// insertLocked places e in its slot. Caller holds s.mu.
+func (s *scheduler) insertLocked(e *scheduled) {
+	if !s.running {
+		s.next = time.Now().Add(wheelTick)
+	}
+	// The hand reaches slot pos+ticks at next+(ticks-1)*tick; pick the
+	// first such time not before e.at.
+	ticks := 1
+	if late := e.at.Sub(s.next); late > 0 {
+		ticks += int((late + wheelTick - 1) / wheelTick)
+	}
+	slot := (s.pos + ticks) % wheelSlots
+	e.rounds = (ticks - 1) / wheelSlots
+	s.slots[slot] = append(s.slots[slot], e)
+	s.index[e.msg.ID] = slot
+}
+
+func (s *scheduler) cancel(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slot, ok := s.index[id]
+	if !ok {
+		return false
+	}
+	delete(s.index, id)
+	s.slots[slot] = slices.DeleteFunc(s.slots[slot], func(e *scheduled) bool { return e.msg.ID == id })
+	if s.dir != "" {
+		removeScheduled(s.dir, id)
+	}
+	return true
+}
+
+func (s *scheduler) pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.index)
+}
+
+// run moves the hand each tick and publishes what falls due, until the
+// wheel is empty or closed.
+func (s *scheduler) run() {
+	defer s.wg.Done()
+	s.mu.Lock()
+	timer := time.NewTimer(time.Until(s.next))
+	s.mu.Unlock()
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-s.done:
+			return
+		}
+		for _, e := range s.advance(time.Now()) {
+			s.fire(e)
+		}
+		s.mu.Lock()
+		if len(s.index) == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+		timer.Reset(time.Until(s.next))
+		s.mu.Unlock()
+	}
+}
+
+// advance moves the hand over every tick up to now and returns the due
+// entries in time order.
+func (s *scheduler) advance(now time.Time) []*scheduled {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []*scheduled
+	for !now.Before(s.next) {
+		s.pos = (s.pos + 1) % wheelSlots
+		s.next = s.next.Add(wheelTick)
+		kept := s.slots[s.pos][:0]
+		for _, e := range s.slots[s.pos] {
+			if e.rounds > 0 {
+				e.rounds--
+				kept = append(kept, e)
+				continue
+			}
+			delete(s.index, e.msg.ID)
+			due = append(due, e)
+		}
+		clear(s.slots[s.pos][len(kept):])
+		s.slots[s.pos] = kept
+	}
+	slices.SortStableFunc(due, func(a, b *scheduled) int { return a.at.Compare(b.at) })
+	return due
+}
+
+// fire publishes e and then forgets it. A publish that fails (the
+// topic was deleted, or the broker is closing) drops the message.
+func (s *scheduler) fire(e *scheduled) {
+	msg := e.msg.clone()
+	msg.Timestamp = time.Time{}
+	s.broker.publish(e.topic, msg)
+	if s.dir != "" {
+		removeScheduled(s.dir, e.msg.ID)
+	}
+}
+
+// close stops the hand; entries still pending stay in dir.
+func (s *scheduler) close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.done)
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Persistence: one file per pending message, named after its escaped
+// ID, holding
+//
+//	version | varint(due ns) | uvarint(len) topic | envelope
+//
+// written to a temporary file and renamed into place, and deleted once
+// the message is published or cancelled.
+
+func scheduledPath(dir, id string) string {
+	return filepath.Join(dir, url.PathEscape(id)+".msg")
+}
+
+func saveScheduled(dir string, e *scheduled) error {
+	b := []byte{scheduleVersion}
+	b = binary.AppendVarint(b, e.at.UnixNano())
+	b = appendString(b, e.topic)
+	b = append(b, encodeEnvelope(e.msg)...)
+
+	path := scheduledPath(dir, e.msg.ID)
+	if err := os.WriteFile(path+".tmp", b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func removeScheduled(dir, id string) {
+	os.Remove(scheduledPath(dir, id))
+}
+
+// loadScheduled reads every stored message in dir.
+func loadScheduled(dir string) ([]*scheduled, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []*scheduled
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".msg") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeScheduled(data)
+		if err != nil {
+			return nil, fmt.Errorf("pubsub: scheduled message %s: %w", f.Name(), err)
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func decodeScheduled(data []byte) (*scheduled, error) {
+	if len(data) == 0 || data[0] != scheduleVersion {
+		return nil, errBadEnvelope
+	}
+	at, w := binary.Varint(data[1:])
+	if w <= 0 {
+		return nil, errBadEnvelope
+	}
+	r := envelopeReader{buf: data[1+w:]}
+	topic := r.string()
+	if r.err != nil {
+		return nil, r.err
+	}
+	msg, err := decodeEnvelope(r.buf)
+	if err != nil {
+		return nil, err
+	}
+	if msg.ID == "" {
+		return nil, errors.New("missing message ID")
+	}
+	return &scheduled{topic: topic, at: time.Unix(0, at), msg: msg}, nil
+}
@@ -1,7 +1,11 @@
 package pubsub
 
 // Subscriber defines the callback invoked on message arrival.
+// Returning nil acknowledges the message; returning an error (or not
+// returning within the subscription's AckDeadline) negatively
+// acknowledges it, so it is redelivered with backoff and eventually
+// routed to the dead-letter topic.
 // (Observer Pattern)
 type Subscriber interface {
-	OnMessage(msg *Message)
+	OnMessage(msg *Message) error
 }
@@ -0,0 +1,482 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OverflowPolicy decides what Publish does when a subscription's queue
+// is full.
+type OverflowPolicy int
+
+const (
+	// Block makes the publisher wait for space (back-pressure).
+	Block OverflowPolicy = iota
+	// DropOldest discards the oldest queued message to make room.
+	DropOldest
+	// DropNewest discards the message being published.
+	DropNewest
+	// Disconnect closes the subscription and discards its queue.
+	Disconnect
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case Block:
+		return "BLOCK"
+	case DropOldest:
+		return "DROP_OLDEST"
+	case DropNewest:
+		return "DROP_NEWEST"
+	case Disconnect:
+		return "DISCONNECT"
+	default:
+		return "Unknown"
+	}
+}
+
+// ErrSlowConsumer is the reason a Disconnect subscription was closed.
+var ErrSlowConsumer = errors.New("pubsub: subscriber too slow, queue overflowed")
+
+// Headers added to messages sent to a dead-letter topic.
+const (
+	OriginalTopicHeader          = "original-topic"
+	OriginalOffsetHeader         = "original-offset"
+	DeadLetterReasonHeader       = "dead-letter-reason"
+	DeadLetterSubscriptionHeader = "dead-letter-subscription" // Subscription.ID that gave up
+)
+
+// SubscriptionOptions configures a subscription's delivery queue and
+// acknowledgement handling.
+type SubscriptionOptions struct {
+	QueueSize int // max queued messages; defaults to 128
+	Overflow  OverflowPolicy
+
+	// AckDeadline bounds how long OnMessage may run; a message whose
+	// handler has not returned by then is nacked, and the next message is
+	// delivered while the late handler keeps running. The redelivery
+	// waits until that handler returns, so one message never runs
+	// concurrently with itself. Zero waits forever.
+	AckDeadline time.Duration
+	// Retry controls redelivery of nacked messages.
+	Retry RetryPolicy
+	// DeadLetter receives messages that fail Retry.MaxAttempts times.
+	// If nil they are discarded.
+	DeadLetter *Topic
+
+	// StartAt selects where to begin reading a durable topic.
+	StartAt StartPosition
+
+	// Filter, if set, is a content filter expression (see ParseFilter);
+	// only matching messages are queued for the subscriber.
+	Filter string
+}
+
+// DefaultSubscriptionOptions is used by Topic.AddSubscriber.
+func DefaultSubscriptionOptions() SubscriptionOptions {
+	return SubscriptionOptions{QueueSize: 128, Overflow: Block, Retry: DefaultRetryPolicy()}
+}
+
+// delivery tracks one message's attempts on one subscription.
+type delivery struct {
+	msg      *Message
+	attempts int           // deliveries made so far
+	gen      uint64        // purge generation when the redelivery was scheduled
+	late     chan struct{} // closed when a timed-out attempt returns
+}
+
+// skipper is implemented by internal subscribers, such as a consumer
+// group's handlers, that must hear about messages the subscription
+// drops without delivering, to account for their offsets.
+type skipper interface {
+	skipped(msg *Message)
+}
+
+// Subscription is one Subscriber's attachment to a Topic. It owns a
+// bounded FIFO queue drained by a dedicated goroutine, so a slow
+// subscriber never delays the publisher or other subscribers (except
+// through back-pressure under the Block policy), and first deliveries
+// reach it in publish order. Nacked messages are redelivered after an
+// exponential backoff, ahead of newer queued messages.
+type Subscription struct {
+	id          string // set before the subscription is attached
+	seq         uint64 // orders Topic.Subscribe's subscriptions
+	subscriber  Subscriber
+	overflow    OverflowPolicy
+	ackDeadline time.Duration
+	retry       RetryPolicy
+	deadLetter  *Topic
+	filter      *Filter
+
+	mu        sync.Mutex
+	cond      *sync.Cond // signalled on enqueue, dequeue, retry and close
+	buf       []*Message // ring buffer of first deliveries
+	head      int
+	count     int
+	retries   []*delivery // redeliveries whose backoff has elapsed
+	scheduled int         // redeliveries still waiting on their backoff
+	inFlight  int
+	gen       uint64            // bumped by Purge
+	unread    map[*Topic]uint64 // durable topics: records not yet fed in
+	closed    bool
+	err       error
+	stopped   chan struct{} // closed when the subscription stops accepting
+	done      chan struct{} // closed when the delivery goroutine exits
+
+	dropped      uint64
+	filtered     uint64
+	acked        uint64
+	redelivered  uint64
+	deadLettered uint64
+	purged       uint64
+	latency      Histogram
+	delivered    rateMeter
+}
+
+// newSubscription validates opts and starts the delivery goroutine
+// (Factory Pattern).
+func newSubscription(s Subscriber, opts SubscriptionOptions) (*Subscription, error) {
+	var filter *Filter
+	if opts.Filter != "" {
+		var err error
+		if filter, err = ParseFilter(opts.Filter); err != nil {
+			return nil, err
+		}
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultSubscriptionOptions().QueueSize
+	}
+	sub := &Subscription{
+		subscriber:  s,
+		overflow:    opts.Overflow,
+		ackDeadline: opts.AckDeadline,
+		retry:       opts.Retry.withDefaults(),
+		deadLetter:  opts.DeadLetter,
+		filter:      filter,
+		buf:         make([]*Message, opts.QueueSize),
+		stopped:     make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	sub.cond = sync.NewCond(&sub.mu)
+	go sub.run()
+	return sub, nil
+}
+
+// enqueue adds msg according to the overflow policy. It reports
+// whether the subscription no longer accepts messages (it was closed
+// earlier, or disconnected by this call), so the caller can detach it.
+func (s *Subscription) enqueue(msg *Message) (closed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.filter != nil && !s.closed && !s.filter.Match(msg) {
+		s.filtered++
+		return false
+	}
+	for s.count == len(s.buf) && !s.closed {
+		switch s.overflow {
+		case Block:
+			s.cond.Wait()
+		case DropOldest:
+			s.pop()
+			s.dropped++
+		case DropNewest:
+			s.dropped++
+			return false
+		case Disconnect:
+			s.dropped += uint64(s.count+len(s.retries)) + 1
+			s.head, s.count, s.retries = 0, 0, nil
+			s.closeLocked(ErrSlowConsumer)
+			return true
+		}
+	}
+	if s.closed {
+		return true
+	}
+	s.buf[(s.head+s.count)%len(s.buf)] = msg
+	s.count++
+	s.cond.Broadcast()
+	return false
+}
+
+// pop removes the oldest queued message. Caller holds s.mu.
+func (s *Subscription) pop() *Message {
+	msg := s.buf[s.head]
+	s.buf[s.head] = nil
+	s.head = (s.head + 1) % len(s.buf)
+	s.count--
+	return msg
+}
+
+// next blocks for the next delivery: due redeliveries first, then the
+// queue. It returns nil once closed with nothing left to deliver.
+func (s *Subscription) next() *delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.retries) == 0 && s.count == 0 && !(s.closed && s.scheduled == 0) {
+		s.cond.Wait()
+	}
+	if len(s.retries) > 0 {
+		d := s.retries[0]
+		s.retries = s.retries[1:]
+		return d
+	}
+	if s.count > 0 {
+		d := &delivery{msg: s.pop()}
+		s.cond.Broadcast() // wake publishers blocked on a full queue
+		return d
+	}
+	return nil
+}
+
+// run delivers messages one at a time until closed and drained.
+func (s *Subscription) run() {
+	defer close(s.done)
+	for d := s.next(); d != nil; d = s.next() {
+		if d.msg.txn != nil && !d.msg.txn.wait() {
+			// published by a transaction that aborted
+			s.skip(d.msg)
+			continue
+		}
+		d.attempts++
+		s.mu.Lock()
+		s.inFlight++
+		s.mu.Unlock()
+
+		start := time.Now()
+		err := s.invoke(d)
+		s.mu.Lock()
+		s.inFlight--
+		s.latency.observe(time.Since(start))
+		if err == nil {
+			s.acked++
+		}
+		s.mu.Unlock()
+
+		if err != nil {
+			s.nack(d, err)
+			continue
+		}
+		s.delivered.mark(time.Now())
+	}
+}
+
+// invoke calls the Subscriber with a per-delivery copy of the message,
+// enforcing the ack deadline. A panicking handler counts as a nack.
+func (s *Subscription) invoke(d *delivery) error {
+	m := d.msg.clone()
+	m.DeliveryAttempt = d.attempts
+
+	call := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("pubsub: subscriber panicked: %v", r)
+			}
+		}()
+		return s.subscriber.OnMessage(m)
+	}
+	if s.ackDeadline <= 0 {
+		return call()
+	}
+
+	result := make(chan error, 1)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		result <- call()
+	}()
+	timer := time.NewTimer(s.ackDeadline)
+	defer timer.Stop()
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		// The late result is discarded; nack holds the redelivery until
+		// the handler returns.
+		d.late = finished
+		return ErrAckDeadlineExceeded
+	}
+}
+
+// nack schedules a redelivery after backoff, or dead-letters d once it
+// has used up its attempts. Dead letters are tagged with the topic they
+// came from and the last error, so they can be replayed.
+func (s *Subscription) nack(d *delivery, err error) {
+	if d.attempts >= s.retry.MaxAttempts {
+		s.mu.Lock()
+		s.deadLettered++
+		s.mu.Unlock()
+		if s.deadLetter != nil {
+			dead := d.msg.clone()
+			dead.txn = nil
+			dead.SetHeader(OriginalTopicHeader, d.msg.Topic)
+			dead.SetHeader(OriginalOffsetHeader, strconv.FormatUint(d.msg.Offset, 10))
+			dead.SetHeader(DeadLetterSubscriptionHeader, s.id)
+			dead.SetHeader(DeadLetterReasonHeader, err.Error())
+			s.deadLetter.Publish(dead)
+		}
+		return
+	}
+
+	s.mu.Lock()
+	if s.err != nil { // disconnected: discard instead of retrying
+		s.mu.Unlock()
+		return
+	}
+	s.scheduled++
+	d.gen = s.gen
+	s.mu.Unlock()
+
+	backoff := s.retry.Backoff(d.attempts)
+	requeue := func() {
+		s.mu.Lock()
+		s.scheduled--
+		purged := d.gen != s.gen
+		if s.err == nil && !purged {
+			s.retries = append(s.retries, d)
+			s.redelivered++
+		}
+		s.cond.Broadcast()
+		s.mu.Unlock()
+		if purged {
+			s.skip(d.msg)
+		}
+	}
+	if late := d.late; late != nil {
+		// the timed-out attempt is still running: back off from when it ends
+		d.late = nil
+		go func() {
+			<-late
+			time.AfterFunc(backoff, requeue)
+		}()
+		return
+	}
+	time.AfterFunc(backoff, requeue)
+}
+
+// closeLocked stops accepting messages. Caller holds s.mu.
+func (s *Subscription) closeLocked(err error) {
+	if s.closed {
+		return
+	}
+	s.closed, s.err = true, err
+	close(s.stopped)
+	s.cond.Broadcast()
+}
+
+// Close stops accepting new messages; already queued messages (and
+// pending redeliveries) are still delivered. Use Wait to block until
+// they have been.
+func (s *Subscription) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked(nil)
+}
+
+// ID names the subscription: a broker subscription's ID, or
+// "<topic>/<n>" for one made with Topic.Subscribe.
+func (s *Subscription) ID() string { return s.id }
+
+// Wait blocks until the subscription is closed and its queue drained.
+func (s *Subscription) Wait() { <-s.done }
+
+// Err returns why the subscription was closed by the topic
+// (ErrSlowConsumer), or nil.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Dropped reports how many messages were discarded by the overflow policy.
+func (s *Subscription) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Filtered reports how many messages the content filter skipped.
+func (s *Subscription) Filtered() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.filtered
+}
+
+// Acked reports how many messages the Subscriber acknowledged.
+func (s *Subscription) Acked() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.acked
+}
+
+// Redelivered reports how many redeliveries were made.
+func (s *Subscription) Redelivered() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.redelivered
+}
+
+// DeadLettered reports how many messages exhausted their attempts.
+func (s *Subscription) DeadLettered() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deadLettered
+}
+
+// Purge discards every queued message and pending redelivery and
+// returns how many there were. A message being delivered is not
+// affected, and a durable topic keeps feeding newer records. A consumer
+// group counts the discarded messages as handled.
+func (s *Subscription) Purge() int {
+	s.mu.Lock()
+	n := s.count + len(s.retries) + s.scheduled
+	discarded := make([]*Message, 0, s.count+len(s.retries))
+	for s.count > 0 {
+		discarded = append(discarded, s.pop())
+	}
+	for _, d := range s.retries {
+		discarded = append(discarded, d.msg)
+	}
+	s.retries = nil
+	s.gen++ // scheduled redeliveries are dropped when their timers fire
+	s.purged += uint64(n)
+	s.cond.Broadcast() // wake publishers blocked on a full queue
+	s.mu.Unlock()
+	s.skip(discarded...)
+	return n
+}
+
+// skip tells a skipper Subscriber about messages that will never be
+// delivered.
+func (s *Subscription) skip(msgs ...*Message) {
+	if sk, ok := s.subscriber.(skipper); ok {
+		for _, m := range msgs {
+			sk.skipped(m)
+		}
+	}
+}
+
+// setUnread records how far a durable topic's feeder is behind the end
+// of its log; 0 forgets the topic.
+func (s *Subscription) setUnread(t *Topic, n uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n == 0 {
+		delete(s.unread, t)
+		return
+	}
+	if s.unread == nil {
+		s.unread = make(map[*Topic]uint64)
+	}
+	s.unread[t] = n
+}
+
+// Pending reports how many messages are queued for delivery.
+func (s *Subscription) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count + len(s.retries) + s.scheduled
+}
@@ -0,0 +1,64 @@
+package pubsub
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowFirstSubscriber blocks on its first delivery past the ack deadline
+// and records how many deliveries ever ran at once.
+type slowFirstSubscriber struct {
+	mu       sync.Mutex
+	running  int
+	peak     int
+	attempts []int
+	done     chan struct{}
+}
+
+func (s *slowFirstSubscriber) OnMessage(msg *Message) error {
+	s.mu.Lock()
+	s.running++
+	s.peak = max(s.peak, s.running)
+	s.attempts = append(s.attempts, msg.DeliveryAttempt)
+	s.mu.Unlock()
+	if msg.DeliveryAttempt == 1 {
+		time.Sleep(100 * time.Millisecond)
+	}
+	s.mu.Lock()
+	s.running--
+	s.mu.Unlock()
+	if msg.DeliveryAttempt > 1 {
+		close(s.done)
+	}
+	return nil
+}
+
+func TestAckDeadlineRedeliveryWaitsForLateHandler(t *testing.T) {
+	topic := NewTopic("jobs")
+	sub := &slowFirstSubscriber{done: make(chan struct{})}
+	opts := DefaultSubscriptionOptions()
+	opts.AckDeadline = 10 * time.Millisecond
+	opts.Retry = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	if _, err := topic.Subscribe(sub, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := topic.Publish(&Message{Payload: []byte("x")}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-sub.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not redelivered")
+	}
+	topic.Close()
+
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.peak != 1 {
+		t.Errorf("peak concurrent deliveries = %d, want 1", sub.peak)
+	}
+	if len(sub.attempts) != 2 || sub.attempts[1] != 2 {
+		t.Errorf("attempts = %v, want [1 2]", sub.attempts)
+	}
+}
@@ -1,43 +1,244 @@
 package pubsub
 
-import "sync"
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
 
-// Topic holds a set of subscribers and delivers messages to them.
+	"pubsub-system/internal/commitlog"
+)
+
+// Topic holds a set of subscriptions and delivers messages to them.
 // Thread-safe via RWMutex.
 type Topic struct {
-	Name        string
-	subscribers map[Subscriber]struct{}
-	mu          sync.RWMutex
+	Name          string
+	subscriptions map[Subscriber]*Subscription
+	mu            sync.RWMutex
+	subSeq        uint64 // numbers the IDs of Subscribe's subscriptions
+
+	pubMu      sync.Mutex           // orders publishes: offsets match delivery order
+	nextOffset uint64               // in-memory topics only
+	log        *commitlog.Log       // nil for in-memory topics
+	partition  int                  // index within a PartitionedTopic
+	dedup      *dedupWindow         // nil unless SetDedupWindow
+	txns       map[string]*txnLatch // durable: open transactions, guarded by mu
+	aborted    map[string]struct{}  // durable: transactions that never committed, guarded by mu
+	published  rateMeter
+
+	feeders   sync.WaitGroup // durable log readers, one per subscription
+	closing   chan struct{}  // closed by Close: feeders stop at the log end
+	closeOnce sync.Once
 }
 
-// NewTopic constructs a Topic (Factory Pattern).
+// NewTopic constructs an in-memory Topic (Factory Pattern).
 func NewTopic(name string) *Topic {
 	return &Topic{
-		Name:        name,
-		subscribers: make(map[Subscriber]struct{}),
+		Name:          name,
+		subscriptions: make(map[Subscriber]*Subscription),
+		closing:       make(chan struct{}),
 	}
 }
 
-// AddSubscriber registers a Subscriber (Observer Pattern).
+// Durable reports whether the topic is backed by a log on disk.
+func (t *Topic) Durable() bool { return t.log != nil }
+
+// AddSubscriber registers a Subscriber with default queue options
+// (Observer Pattern).
 func (t *Topic) AddSubscriber(s Subscriber) {
+	t.Subscribe(s, DefaultSubscriptionOptions()) // default options are valid
+}
+
+// Subscribe registers a Subscriber with its own bounded queue and
+// delivery goroutine. Subscribing twice returns the existing
+// Subscription. It fails if opts.Filter does not parse.
+// On a durable topic the subscription first replays history from
+// opts.StartAt, then follows new messages.
+func (t *Topic) Subscribe(s Subscriber, opts SubscriptionOptions) (*Subscription, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.subscribers[s] = struct{}{}
+	if sub, ok := t.subscriptions[s]; ok {
+		return sub, nil
+	}
+	sub, err := newSubscription(s, opts)
+	if err != nil {
+		return nil, err
+	}
+	t.subSeq++
+	sub.id, sub.seq = fmt.Sprintf("%s/%d", t.Name, t.subSeq), t.subSeq
+	t.attachLocked(sub, opts.StartAt)
+	return sub, nil
 }
 
-// RemoveSubscriber unregisters a Subscriber.
-func (t *Topic) RemoveSubscriber(s Subscriber) {
+// attach adds an existing subscription, which may also be attached to
+// other topics (see Broker).
+func (t *Topic) attach(sub *Subscription, start StartPosition) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	delete(t.subscribers, s)
+	t.attachLocked(sub, start)
+}
+
+// attachLocked registers sub and, on a durable topic, starts feeding it
+// from start. Caller holds t.mu.
+func (t *Topic) attachLocked(sub *Subscription, start StartPosition) {
+	t.subscriptions[sub.subscriber] = sub
+	if t.log != nil {
+		from, err := t.resolveStart(start)
+		if err != nil {
+			from = t.log.NextOffset()
+		}
+		t.feeders.Add(1)
+		go t.feed(sub, from)
+	}
 }
 
-// Publish sends message to all current subscribers concurrently.
-func (t *Topic) Publish(msg *Message) {
+// subscriptionByID returns the attached subscription with this ID.
+func (t *Topic) subscriptionByID(id string) (*Subscription, bool) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	for s := range t.subscribers {
-		// Optionally dispatch in separate goroutines for real async:
-		s.OnMessage(msg) // add goroutine
+	for _, sub := range t.subscriptions {
+		if sub.id == id {
+			return sub, true
+		}
+	}
+	return nil, false
+}
+
+// Subscriptions returns every attached subscription, including those
+// of broker patterns and consumer groups. Subscribe's are listed in
+// subscription order.
+func (t *Topic) Subscriptions() []*Subscription {
+	t.mu.RLock()
+	out := make([]*Subscription, 0, len(t.subscriptions))
+	for _, sub := range t.subscriptions {
+		out = append(out, sub)
+	}
+	t.mu.RUnlock()
+	slices.SortFunc(out, func(x, y *Subscription) int {
+		return cmp.Or(cmp.Compare(x.seq, y.seq), cmp.Compare(x.id, y.id))
+	})
+	return out
+}
+
+// RemoveSubscriber unregisters a Subscriber. Messages already queued
+// for it are still delivered.
+func (t *Topic) RemoveSubscriber(s Subscriber) {
+	t.mu.Lock()
+	sub, ok := t.subscriptions[s]
+	delete(t.subscriptions, s)
+	t.mu.Unlock()
+	if ok {
+		sub.Close()
+	}
+}
+
+// Publish stamps msg with an ID and Timestamp if it has none, assigns
+// it the topic's next offset and hands a copy to every
+// current subscription. Durable topics append it to the log first, and
+// subscriptions read it from there. Publish returns once the message is
+// stored or queued, not when it is delivered.
+func (t *Topic) Publish(msg *Message) error {
+	t.pubMu.Lock()
+	defer t.pubMu.Unlock()
+	_, err := t.publishLocked(msg, nil)
+	return err
+}
+
+// publishLocked publishes msg as part of txn (nil outside a
+// transaction, whose messages are recorded for deduplication only once
+// it commits). Inside a transaction an in-memory topic assigns the
+// offset but returns the copy to deliver instead of queueing it: the
+// transaction delivers it once committed, so a Block subscription can
+// keep draining while the commit holds the topic. Caller holds t.pubMu.
+func (t *Topic) publishLocked(msg *Message, txn *txnLatch) (pending *Message, err error) {
+	msg.stamp()
+	if t.dedup != nil {
+		if off, known, dup := t.dedup.check(msg, time.Now()); dup {
+			msg.Topic, msg.Partition = t.Name, t.partition
+			if known {
+				msg.Offset = off
+			}
+			return nil, nil
+		}
+	}
+	m := msg.clone()
+	if txn == nil {
+		delete(m.Headers, TxnHeader) // only records of a transaction carry it
+	}
+	if t.log != nil {
+		off, err := t.log.Append(encodeEnvelope(m), m.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		msg.Topic, msg.Offset, msg.Partition = t.Name, off, t.partition
+		if t.dedup != nil && txn == nil {
+			t.dedup.record(msg, time.Now())
+		}
+		t.published.mark(time.Now())
+		return nil, nil
+	}
+	m.Topic, m.Partition = t.Name, t.partition
+	m.Offset = t.nextOffset
+	t.nextOffset++
+	msg.Topic, msg.Offset, msg.Partition = t.Name, m.Offset, t.partition
+	if t.dedup != nil && txn == nil {
+		t.dedup.record(msg, time.Now())
+	}
+	t.published.mark(time.Now())
+	if txn != nil {
+		return m, nil
+	}
+	t.deliver(m)
+	return nil, nil
+}
+
+// deliver queues m on every current subscription. Caller holds t.pubMu.
+func (t *Topic) deliver(m *Message) {
+	t.mu.RLock()
+	subs := make([]*Subscription, 0, len(t.subscriptions))
+	for _, sub := range t.subscriptions {
+		subs = append(subs, sub)
+	}
+	t.mu.RUnlock()
+
+	// Enqueue outside t.mu so a Block subscription cannot stall
+	// Subscribe/RemoveSubscriber.
+	for _, sub := range subs {
+		if sub.enqueue(m) {
+			t.detach(sub)
+		}
+	}
+}
+
+// detach removes a subscription that closed itself or is being moved
+// off this topic, without closing it.
+func (t *Topic) detach(sub *Subscription) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.subscriptions[sub.subscriber] == sub {
+		delete(t.subscriptions, sub.subscriber)
+	}
+}
+
+// Close closes every subscription, waits until their queued messages
+// have been delivered, and closes the log of a durable topic. Durable
+// subscriptions first catch up with everything published before Close.
+func (t *Topic) Close() error {
+	t.drainFeeders()
+
+	t.mu.Lock()
+	subs := t.subscriptions
+	t.subscriptions = make(map[Subscriber]*Subscription)
+	t.mu.Unlock()
+	for _, sub := range subs {
+		sub.Close()
+	}
+	for _, sub := range subs {
+		sub.Wait()
+	}
+	if t.log != nil {
+		return t.log.Close()
 	}
+	return nil
 }
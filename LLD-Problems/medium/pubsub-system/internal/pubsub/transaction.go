@@ -0,0 +1,227 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// TxnHeader carries the ID of the transaction that published a message.
+const TxnHeader = "txn-id"
+
+// txnMarkerHeader marks the log record that ends a transaction on a
+// durable topic, with the value txnCommitted or txnAborted. Markers are
+// never delivered.
+const (
+	txnMarkerHeader = "txn-marker"
+	txnCommitted    = "commit"
+	txnAborted      = "abort"
+)
+
+// ErrTransactionDone is returned when using a committed or aborted
+// Transaction.
+var ErrTransactionDone = errors.New("pubsub: transaction already committed or aborted")
+
+// commitMu lets one transaction at a time lock several topics, so two
+// commits can never wait on each other's topics.
+var commitMu sync.Mutex
+
+// Transaction stages messages for several topics and publishes them
+// all-or-nothing. It is not safe for concurrent use.
+type Transaction struct {
+	ID string
+
+	publisher *Publisher
+	staged    []stagedMessage
+	done      bool
+}
+
+type stagedMessage struct {
+	topic *Topic
+	msg   *Message
+}
+
+// txnLatch holds back delivery of a transaction's messages until it
+// commits or aborts.
+type txnLatch struct {
+	done    chan struct{}
+	aborted bool // written before done is closed
+}
+
+func newTxnLatch() *txnLatch { return &txnLatch{done: make(chan struct{})} }
+
+// wait blocks until the transaction ends and reports whether it
+// committed.
+func (l *txnLatch) wait() bool {
+	<-l.done
+	return !l.aborted
+}
+
+func (l *txnLatch) finish(committed bool) {
+	l.aborted = !committed
+	close(l.done)
+}
+
+// skippedTxn stands in for the latch of transaction markers and of
+// records whose transaction aborted: subscriptions skip them.
+var skippedTxn = func() *txnLatch {
+	l := newTxnLatch()
+	l.finish(false)
+	return l
+}()
+
+// Begin starts a transaction on behalf of this Publisher.
+func (p *Publisher) Begin() *Transaction {
+	return &Transaction{ID: newMessageID(), publisher: p}
+}
+
+// Publish stages msg for t. Registration and authorization are checked
+// now; nothing is visible to subscribers until Commit.
+func (tx *Transaction) Publish(t *Topic, msg *Message) error {
+	if tx.done {
+		return ErrTransactionDone
+	}
+	if err := tx.publisher.check(t); err != nil {
+		return err
+	}
+	tx.staged = append(tx.staged, stagedMessage{topic: t, msg: msg})
+	return nil
+}
+
+// Commit publishes every staged message while holding all their topics,
+// so no other publish interleaves, and releases them to subscribers
+// only once all are published: no subscriber sees any of them before
+// the whole batch is in place. In-memory topics queue the messages only
+// after the last publish succeeded, so a transaction may be larger than
+// a subscription's queue. If a publish fails (e.g. a log write), the
+// messages already published are never delivered and Commit returns the
+// error.
+//
+// On a durable topic the records are followed by a commit marker, and
+// readers skip records whose transaction has none: after a restart, a
+// transaction that failed or was cut off mid-commit stays invisible. A
+// failed commit also writes abort markers where it can. Only a crash
+// while the markers of a multi-topic transaction are being written can
+// leave it committed on some of its durable topics.
+func (tx *Transaction) Commit() error {
+	if tx.done {
+		return ErrTransactionDone
+	}
+	tx.done = true
+	p := tx.publisher
+	if p.ProducerID != "" {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+	}
+
+	commitMu.Lock()
+	defer commitMu.Unlock()
+	var topics []*Topic
+	for _, s := range tx.staged {
+		if !slices.Contains(topics, s.topic) {
+			topics = append(topics, s.topic)
+			s.topic.pubMu.Lock()
+			defer s.topic.pubMu.Unlock()
+		}
+	}
+
+	latch := newTxnLatch()
+	for _, t := range topics {
+		if t.log != nil {
+			t.mu.Lock()
+			if t.txns == nil {
+				t.txns = make(map[string]*txnLatch)
+			}
+			t.txns[tx.ID] = latch
+			t.mu.Unlock()
+		}
+	}
+
+	last := make(map[*Topic]uint64)
+	var deliveries []stagedMessage
+	for _, s := range tx.staged {
+		s.msg.SetHeader(TxnHeader, tx.ID)
+		if p.ProducerID != "" {
+			prev, ok := last[s.topic]
+			if !ok {
+				prev = p.seqs[s.topic]
+			}
+			last[s.topic] = max(prev, p.sequence(s.topic, s.msg, prev))
+		}
+		pending, err := s.topic.publishLocked(s.msg, latch)
+		if err != nil {
+			tx.abortLocked(topics, latch)
+			return fmt.Errorf("pubsub: transaction %s aborted: %w", tx.ID, err)
+		}
+		if pending != nil {
+			deliveries = append(deliveries, stagedMessage{topic: s.topic, msg: pending})
+		}
+	}
+	for _, t := range topics {
+		if t.log == nil {
+			continue
+		}
+		if err := t.appendTxnMarker(tx.ID, txnCommitted); err != nil {
+			tx.abortLocked(topics, latch)
+			return fmt.Errorf("pubsub: transaction %s aborted: %w", tx.ID, err)
+		}
+	}
+	latch.finish(true)
+	for _, d := range deliveries { // topics still held: stays in offset order
+		d.topic.deliver(d.msg)
+	}
+
+	for _, s := range tx.staged {
+		if s.topic.dedup != nil {
+			s.topic.dedup.record(s.msg, time.Now())
+		}
+	}
+	for t, seq := range last {
+		p.seqs[t] = max(p.seqs[t], seq)
+	}
+	for _, t := range topics {
+		if t.log != nil {
+			t.mu.Lock()
+			delete(t.txns, tx.ID)
+			t.mu.Unlock()
+		}
+	}
+	return nil
+}
+
+// abortLocked releases a failed commit: subscriptions skip its records,
+// and each durable topic records it as aborted and gets an abort marker
+// if its log still takes one. Caller holds the topics' pubMu.
+func (tx *Transaction) abortLocked(topics []*Topic, latch *txnLatch) {
+	latch.finish(false)
+	for _, t := range topics {
+		if t.log == nil {
+			continue
+		}
+		t.mu.Lock()
+		if t.aborted == nil {
+			t.aborted = make(map[string]struct{})
+		}
+		t.aborted[tx.ID] = struct{}{}
+		delete(t.txns, tx.ID)
+		t.mu.Unlock()
+		t.appendTxnMarker(tx.ID, txnAborted) // best effort: no marker reads as aborted too
+	}
+}
+
+// appendTxnMarker writes the record that ends transaction id on t's
+// log. Caller holds t.pubMu.
+func (t *Topic) appendTxnMarker(id, outcome string) error {
+	m := &Message{Headers: map[string]string{TxnHeader: id, txnMarkerHeader: outcome}}
+	m.stamp()
+	_, err := t.log.Append(encodeEnvelope(m), m.Timestamp)
+	return err
+}
+
+// Abort discards the staged messages.
+func (tx *Transaction) Abort() {
+	tx.done = true
+	tx.staged = nil
+}
@@ -0,0 +1,159 @@
+package pubsub
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"pubsub-system/internal/commitlog"
+)
+
+// countingSubscriber acks every message and counts them.
+type countingSubscriber struct {
+	mu   sync.Mutex
+	msgs []*Message
+}
+
+func (c *countingSubscriber) OnMessage(msg *Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.msgs = append(c.msgs, msg)
+	return nil
+}
+
+func (c *countingSubscriber) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.msgs)
+}
+
+func TestCommitLargerThanQueue(t *testing.T) {
+	topic := NewTopic("orders")
+	sub := &countingSubscriber{}
+	opts := DefaultSubscriptionOptions()
+	opts.QueueSize = 4
+	if _, err := topic.Subscribe(sub, opts); err != nil {
+		t.Fatal(err)
+	}
+	p := NewPublisher()
+	p.RegisterTopic(topic)
+
+	tx := p.Begin()
+	for range 10 {
+		if err := tx.Publish(topic, NewMessage("item")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	done := make(chan error, 1)
+	go func() { done <- tx.Commit() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Commit deadlocked on a transaction larger than the queue")
+	}
+
+	// the topic is usable afterwards, and order is kept
+	if err := p.Publish(topic, NewMessage("after")); err != nil {
+		t.Fatal(err)
+	}
+	topic.Close()
+	if n := sub.count(); n != 11 {
+		t.Fatalf("delivered %d messages, want 11", n)
+	}
+	for i, m := range sub.msgs {
+		if m.Offset != uint64(i) {
+			t.Fatalf("message %d has offset %d", i, m.Offset)
+		}
+	}
+}
+
+func TestAbortedTransactionIsNotDelivered(t *testing.T) {
+	topic := NewTopic("orders")
+	sub := &countingSubscriber{}
+	topic.AddSubscriber(sub)
+	p := NewPublisher()
+	p.RegisterTopic(topic)
+
+	tx := p.Begin()
+	tx.Publish(topic, NewMessage("never"))
+	tx.Abort()
+	if err := tx.Commit(); err != ErrTransactionDone {
+		t.Fatalf("Commit after Abort = %v, want ErrTransactionDone", err)
+	}
+	topic.Close()
+	if n := sub.count(); n != 0 {
+		t.Fatalf("delivered %d messages from an aborted transaction", n)
+	}
+}
+
+// replay reopens the durable topic in dir and returns the texts of
+// every message a subscriber starting at the earliest offset receives.
+func replay(t *testing.T, dir string) []string {
+	t.Helper()
+	topic, err := NewDurableTopic("orders", dir, commitlog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := &countingSubscriber{}
+	opts := DefaultSubscriptionOptions()
+	opts.StartAt = StartEarliest()
+	if _, err := topic.Subscribe(sub, opts); err != nil {
+		t.Fatal(err)
+	}
+	topic.Close()
+	var texts []string
+	for _, m := range sub.msgs {
+		texts = append(texts, m.Text())
+	}
+	return texts
+}
+
+func TestDurableTransactionVisibleOnlyOnceCommitted(t *testing.T) {
+	dir := t.TempDir()
+	orders, err := NewDurableTopic("orders", dir, commitlog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken, err := NewDurableTopic("audit", t.TempDir(), commitlog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken.log.Close() // every append to it fails
+	p := NewPublisher()
+	p.RegisterTopic(orders)
+	p.RegisterTopic(broken)
+
+	ok := p.Begin()
+	ok.Publish(orders, NewMessage("committed"))
+	if err := ok.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	failed := p.Begin()
+	failed.Publish(orders, NewMessage("failed"))
+	failed.Publish(broken, NewMessage("failed"))
+	if err := failed.Commit(); err == nil {
+		t.Fatal("Commit to a closed log succeeded")
+	}
+	// a commit cut off before its marker, as by a crash
+	orders.pubMu.Lock()
+	orders.publishLocked(&Message{Payload: []byte("crashed"), Headers: map[string]string{TxnHeader: "crashed"}}, newTxnLatch())
+	orders.pubMu.Unlock()
+	orders.Publish(NewMessage("plain"))
+
+	orders.mu.RLock()
+	open := len(orders.txns)
+	orders.mu.RUnlock()
+	if open != 0 {
+		t.Errorf("%d transaction latches left on the topic", open)
+	}
+	orders.Close()
+	broken.Close()
+
+	got := replay(t, dir)
+	if len(got) != 2 || got[0] != "committed" || got[1] != "plain" {
+		t.Fatalf("after reopen delivered %q, want [committed plain]", got)
+	}
+}